@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bookbazaar-backend/internal/app"
+	"bookbazaar-backend/internal/catalogio"
+	"bookbazaar-backend/internal/config"
+	"bookbazaar-backend/internal/repository"
+	"bookbazaar-backend/internal/services"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+const exportUsage = "verwendung: backend export [-format csv|jsonl|onix] [-o datei]"
+
+// runExport schreibt den Katalog wie GET /api/books/export in eine Datei
+// oder nach stdout.
+func runExport(configPath string, args []string) (err error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", catalogio.FormatCSV, "csv, jsonl oder onix")
+	output := fs.String("o", "", "Zieldatei (Standard: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New(exportUsage)
+	}
+
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		return err
+	}
+	if cfg.Database.Driver != config.DriverPostgres {
+		return fmt.Errorf("export braucht database.driver %q", config.DriverPostgres)
+	}
+
+	db, err := app.OpenDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		out = f
+	}
+	buf := bufio.NewWriter(out)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	exporter := services.NewExportService(repository.NewBookRepository(db))
+	count, err := exporter.Export(ctx, buf, *format)
+	if err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "%d bücher nach %s exportiert\n", count, *output)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bookbazaar-backend/internal/app"
+	"bookbazaar-backend/internal/catalogio"
+	"bookbazaar-backend/internal/config"
+	"bookbazaar-backend/internal/repository"
+	"bookbazaar-backend/internal/services"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+const importUsage = "verwendung: backend import [-dry-run] [-chunk-size n] [-format csv|json] datei"
+
+// runImport importiert eine Datei mit denselben Regeln wie POST
+// /api/books/import direkt in die Datenbank.
+func runImport(configPath string, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "nur prüfen und berichten, nichts schreiben")
+	chunkSize := fs.Int("chunk-size", -1, "Zeilen je Transaktion, 0 = alles in einer (Standard aus import.chunkSize)")
+	format := fs.String("format", "", "csv oder json (Standard: Dateiendung)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(importUsage)
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		return err
+	}
+	if cfg.Database.Driver != config.DriverPostgres {
+		return fmt.Errorf("import braucht database.driver %q", config.DriverPostgres)
+	}
+	if *chunkSize < 0 {
+		*chunkSize = cfg.Import.ChunkSize
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rows, err := catalogio.Read(f, *format)
+	if err != nil {
+		return err
+	}
+
+	db, err := app.OpenDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	importer := services.NewImportService(repository.NewBookRepository(db))
+	report, err := importer.Import(ctx, rows, services.ImportOptions{DryRun: *dryRun, ChunkSize: *chunkSize})
+	if err != nil {
+		return err
+	}
+	if err := printImportReport(report); err != nil {
+		return err
+	}
+	if report.Failed > 0 || report.Skipped > 0 {
+		return fmt.Errorf("import mit %d fehlerhaften und %d übersprungenen zeilen", report.Failed, report.Skipped)
+	}
+	return nil
+}
+
+func printImportReport(report *services.ImportReport) error {
+	mode := "geschrieben"
+	switch {
+	case report.DryRun:
+		mode = "probelauf, nichts geschrieben"
+	case !report.Applied:
+		mode = "nichts geschrieben"
+	}
+	fmt.Printf("%d zeilen: %d neu, %d aktualisiert, %d fehlerhaft, %d übersprungen (%s)\n",
+		report.Total, report.Created, report.Updated, report.Failed, report.Skipped, mode)
+	if report.Failed == 0 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ZEILE\tCODE\tFEHLER")
+	for _, row := range report.Rows {
+		if row.Status != services.ImportError {
+			continue
+		}
+		message := row.Message
+		for _, f := range row.Fields {
+			message += "; " + f.Field + ": " + f.Message
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", row.Line, row.Code, message)
+	}
+	return w.Flush()
+}
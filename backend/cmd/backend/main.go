@@ -2,8 +2,70 @@ package main
 
 import (
 	"bookbazaar-backend/internal/app"
+	"bookbazaar-backend/internal/config"
+	"bookbazaar-backend/internal/logging"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
 )
 
+const usage = `Verwendung: backend [-config datei] [befehl]
+
+Befehle:
+  serve                   startet den HTTP-Server (Standard)
+  migrate up|down|status  verwaltet das Datenbankschema
+  import [-dry-run] datei importiert Bücher aus CSV oder JSON
+  export [-format f]      schreibt den Katalog als CSV, JSON Lines oder ONIX
+`
+
 func main() {
-	app.Run()
+	configPath := flag.String("config", "", "Pfad zu einer YAML- oder TOML-Konfigurationsdatei (alternativ BOOKBAZAAR_CONFIG)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cmd := flag.Arg(0)
+	if cmd == "" {
+		cmd = "serve"
+	}
+
+	switch cmd {
+	case "serve":
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			fatal(err)
+		}
+		logger, err := logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level)
+		if err != nil {
+			fatal(err)
+		}
+		slog.SetDefault(logger)
+
+		if err := app.Run(cfg); err != nil {
+			fatal(err)
+		}
+	case "migrate":
+		if err := runMigrate(*configPath, flag.Args()[1:]); err != nil {
+			fatal(err)
+		}
+	case "import":
+		if err := runImport(*configPath, flag.Args()[1:]); err != nil {
+			fatal(err)
+		}
+	case "export":
+		if err := runExport(*configPath, flag.Args()[1:]); err != nil {
+			fatal(err)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func fatal(err error) {
+	slog.Error("abbruch", slog.Any("error", err))
+	os.Exit(1)
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bookbazaar-backend/internal/app"
+	"bookbazaar-backend/internal/config"
+	"bookbazaar-backend/internal/migrations"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+)
+
+func runMigrate(configPath string, args []string) error {
+	if len(args) != 1 {
+		return errors.New("verwendung: backend migrate up|down|status")
+	}
+
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		return err
+	}
+
+	db, err := app.OpenDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("angewendet: %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema ist bereits aktuell")
+		}
+		return nil
+
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Println("keine migration zum zurücknehmen")
+			return nil
+		}
+		fmt.Printf("zurückgenommen: %04d_%s\n", reverted.Version, reverted.Name)
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tANGEWENDET")
+		for _, s := range statuses {
+			applied := "offen"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unbekannter migrate-befehl %q (erlaubt: up, down, status)", args[0])
+	}
+}
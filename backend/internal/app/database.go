@@ -0,0 +1,30 @@
+package app
+
+import (
+	"bookbazaar-backend/internal/config"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// OpenDB öffnet den Connection-Pool und prüft, ob die Datenbank erreichbar ist.
+func OpenDB(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("pgx", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("fehler beim Verbinden mit der Datenbank: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime.Duration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("datenbank ist nicht erreichbar: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Worker ist ein Hintergrundprozess, der läuft, bis ctx abgebrochen wird.
+type Worker interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// lifecycle hält alles, was beim Herunterfahren in fester Reihenfolge
+// beendet werden muss: erst HTTP (laufende Requests dürfen fertig werden),
+// dann die Worker, zuletzt der DB-Pool.
+type lifecycle struct {
+	server *http.Server
+	// listener ist optional; ohne wird server.Addr geöffnet
+	listener        net.Listener
+	shutdownTimeout time.Duration
+	workers         []Worker
+	// onShutdown wird als erstes aufgerufen, z.B. um /readyz auf rot zu setzen
+	onShutdown func()
+	db         *sql.DB
+}
+
+func (lc *lifecycle) run(ctx context.Context) error {
+	if lc.listener == nil {
+		ln, err := net.Listen("tcp", lc.server.Addr)
+		if err != nil {
+			if lc.db != nil {
+				lc.db.Close()
+			}
+			return fmt.Errorf("fehler beim Starten des Servers: %w", err)
+		}
+		lc.listener = ln
+	}
+
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+
+	var wg sync.WaitGroup
+	for _, w := range lc.workers {
+		wg.Add(1)
+		go func(w Worker) {
+			defer wg.Done()
+			if err := w.Run(workerCtx); err != nil && !errors.Is(err, context.Canceled) {
+				slog.Error("worker mit fehler beendet", slog.String("worker", w.Name()), slog.Any("error", err))
+			}
+		}(w)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server lauscht", slog.String("addr", lc.listener.Addr().String()))
+		if err := lc.server.Serve(lc.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		slog.Info("signal empfangen, fahre herunter")
+	case err := <-serveErr:
+		runErr = fmt.Errorf("server abgebrochen: %w", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), lc.shutdownTimeout)
+	defer cancel()
+
+	return errors.Join(runErr, lc.shutdown(shutdownCtx, cancelWorkers, &wg))
+}
+
+func (lc *lifecycle) shutdown(ctx context.Context, cancelWorkers context.CancelFunc, wg *sync.WaitGroup) error {
+	var errs []error
+
+	if lc.onShutdown != nil {
+		lc.onShutdown()
+	}
+
+	// 1. keine neuen Verbindungen, laufende Requests (z.B. BuyBooks-Transaktionen) abwarten
+	if err := lc.server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("http-server nicht sauber beendet: %w", err))
+	}
+
+	// 2. Worker stoppen und auf sie warten
+	cancelWorkers()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, errors.New("worker haben sich nicht rechtzeitig beendet"))
+	}
+
+	// 3. DB-Pool schließen, erst wenn niemand mehr Verbindungen braucht
+	if lc.db != nil {
+		if err := lc.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("db-pool nicht sauber geschlossen: %w", err))
+		}
+	}
+
+	slog.Info("server beendet")
+	return errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWorker läuft, bis sein Context abgebrochen wird, und merkt sich das.
+type blockingWorker struct {
+	stopped atomic.Bool
+}
+
+func (w *blockingWorker) Name() string { return "test" }
+
+func (w *blockingWorker) Run(ctx context.Context) error {
+	<-ctx.Done()
+	w.stopped.Store(true)
+	return ctx.Err()
+}
+
+func TestLifecycleDrainsInFlightRequests(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		// simuliert eine laufende Kauf-Transaktion
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "fertig")
+	})
+
+	worker := &blockingWorker{}
+	lc := &lifecycle{
+		server:          &http.Server{Handler: handler},
+		listener:        ln,
+		shutdownTimeout: 5 * time.Second,
+		workers:         []Worker{worker},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- lc.run(ctx) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		resCh <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel() // entspricht SIGTERM während der Request läuft
+
+	res := <-resCh
+	require.NoError(t, res.err, "laufender Request darf nicht abgebrochen werden")
+	assert.Equal(t, "fertig", res.body)
+
+	require.NoError(t, <-runErr)
+	assert.True(t, worker.stopped.Load(), "Worker muss beim Herunterfahren gestoppt werden")
+}
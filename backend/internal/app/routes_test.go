@@ -0,0 +1,114 @@
+package app
+
+import (
+	"bookbazaar-backend/internal/config"
+	"bookbazaar-backend/internal/health"
+	"bookbazaar-backend/internal/metrics"
+	"bookbazaar-backend/internal/openapi"
+	"bookbazaar-backend/internal/repository/memory"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRouter baut den echten Router auf dem In-Memory-Store.
+func testRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.Database.Driver = config.DriverMemory
+	cfg.Auth.JWTSecret = "test-secret-1234567890"
+
+	store := memory.NewStore()
+	return newRouter(&cfg, dependencies{
+		bookRepo:     store.Books(),
+		userRepo:     store.Users(),
+		authorRepo:   store.Authors(),
+		categoryRepo: store.Categories(),
+		stockRepo:    store.Stock(),
+		checker:      health.NewChecker(time.Second),
+		metrics:      metrics.New(),
+	})
+}
+
+var ginParam = regexp.MustCompile(`:(\w+)`)
+
+// TestRoutesMatchOpenAPISpec schlägt fehl, wenn eine gin-Route keinen Eintrag
+// in openapi.yaml hat oder die Spec eine Route beschreibt, die es nicht gibt.
+func TestRoutesMatchOpenAPISpec(t *testing.T) {
+	router := testRouter(t)
+
+	routes := map[string]bool{}
+	for _, r := range router.Routes() {
+		routes[r.Method+" "+ginParam.ReplaceAllString(r.Path, "{$1}")] = true
+	}
+
+	ops, err := openapi.Operations()
+	require.NoError(t, err)
+	documented := map[string]bool{}
+	for _, op := range ops {
+		documented[op] = true
+	}
+
+	for route := range routes {
+		assert.True(t, documented[route], "Route %q fehlt in internal/openapi/openapi.yaml", route)
+	}
+	for op := range documented {
+		assert.True(t, routes[op], "Spec beschreibt %q, aber es gibt keine gin-Route dafür", op)
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	router := testRouter(t)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Contains(t, doc["paths"], "/api/books/{id}")
+}
+
+func TestAnonymousCatalog(t *testing.T) {
+	router := testRouter(t)
+	get := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	t.Run("Katalog ohne Token", func(t *testing.T) {
+		resp := get("/api/books")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"items"`)
+	})
+
+	t.Run("Warenkorb braucht weiter ein Token", func(t *testing.T) {
+		resp := get("/api/books/cart")
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+}
+
+// TestCoverNotModified prüft, dass If-None-Match kein 304 für ein Cover
+// liefert, das es nicht (mehr) gibt.
+func TestCoverNotModified(t *testing.T) {
+	router := testRouter(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/covers/999/0123456789abcdef/small.jpg", nil)
+	req.Header.Set("If-None-Match", `"0123456789abcdef-small"`)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
@@ -0,0 +1,119 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kategorien für errors.Is. Die HTTP-Abbildung passiert zentral in
+// middleware.ErrorHandler, Services und Repositories kennen keine Statuscodes.
+var (
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrInsufficientBalance  = errors.New("insufficient balance")
+	ErrOutOfStock           = errors.New("out of stock")
+	ErrValidation           = errors.New("validation failed")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrPreconditionFailed   = errors.New("precondition failed")   // If-Match passt nicht
+	ErrPreconditionRequired = errors.New("precondition required") // If-Match fehlt
+	ErrTooLarge             = errors.New("too large")             // Upload über dem Limit
+	ErrUnsupportedMedia     = errors.New("unsupported media type")
+	ErrTooManyRequests      = errors.New("too many requests") // Rate-Limit überschritten
+)
+
+// FieldError beschreibt ein einzelnes ungültiges Feld bei ErrValidation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error ist ein Fachfehler mit stabilem, maschinenlesbarem Code
+// (z.B. "book_not_found"). Message ist für Menschen gedacht und darf sich ändern.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap macht sowohl die Kategorie als auch die Ursache für errors.Is/As sichtbar.
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// WithCause hängt die technische Ursache an (wird nicht an Clients ausgeliefert).
+func (e *Error) WithCause(err error) *Error {
+	e.Err = err
+	return e
+}
+
+func newError(kind error, code, format string, args []any) *Error {
+	return &Error{Kind: kind, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(code, format string, args ...any) *Error {
+	return newError(ErrNotFound, code, format, args)
+}
+
+func Conflict(code, format string, args ...any) *Error {
+	return newError(ErrConflict, code, format, args)
+}
+
+func InsufficientBalance(code, format string, args ...any) *Error {
+	return newError(ErrInsufficientBalance, code, format, args)
+}
+
+func OutOfStock(code, format string, args ...any) *Error {
+	return newError(ErrOutOfStock, code, format, args)
+}
+
+func Validation(code, format string, args ...any) *Error {
+	return newError(ErrValidation, code, format, args)
+}
+
+func Unauthorized(code, format string, args ...any) *Error {
+	return newError(ErrUnauthorized, code, format, args)
+}
+
+func Forbidden(code, format string, args ...any) *Error {
+	return newError(ErrForbidden, code, format, args)
+}
+
+func PreconditionFailed(code, format string, args ...any) *Error {
+	return newError(ErrPreconditionFailed, code, format, args)
+}
+
+func PreconditionRequired(code, format string, args ...any) *Error {
+	return newError(ErrPreconditionRequired, code, format, args)
+}
+
+func TooLarge(code, format string, args ...any) *Error {
+	return newError(ErrTooLarge, code, format, args)
+}
+
+func UnsupportedMedia(code, format string, args ...any) *Error {
+	return newError(ErrUnsupportedMedia, code, format, args)
+}
+
+func TooManyRequests(code, format string, args ...any) *Error {
+	return newError(ErrTooManyRequests, code, format, args)
+}
+
+// As liefert den *Error aus einer Fehlerkette, falls vorhanden.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
@@ -0,0 +1,41 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FromValidator übersetzt validator.ValidationErrors in einen ErrValidation
+// mit Feldliste. Andere Fehler werden unverändert zurückgegeben.
+func FromValidator(code string, err error) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	e := Validation(code, "Ungültige Eingabe")
+	for _, fe := range verrs {
+		e.Fields = append(e.Fields, FieldError{Field: fe.Field(), Message: fieldMessage(fe)})
+	}
+	if len(e.Fields) > 0 {
+		e.Message = fmt.Sprintf("Ungültige Eingabe: %s %s", e.Fields[0].Field, e.Fields[0].Message)
+	}
+	return e
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "ist erforderlich"
+	case "min":
+		return "muss mindestens " + fe.Param() + " sein (bei Text: Zeichen)"
+	case "max":
+		return "darf höchstens " + fe.Param() + " sein (bei Text: Zeichen)"
+	case "email":
+		return "ist keine gültige E-Mail-Adresse"
+	default:
+		return "ist ungültig (" + fe.Tag() + ")"
+	}
+}
@@ -0,0 +1,29 @@
+// Package blob speichert Binärdaten (z.B. Buchcover) unter einem Schlüssel.
+// Die Services kennen nur das Interface Store; welches Backend dahinter
+// steckt (lokales Dateisystem, später z.B. S3), entscheidet app.Run.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound meldet, dass es unter einem Schlüssel nichts gibt.
+var ErrNotFound = errors.New("blob nicht gefunden")
+
+// Info beschreibt ein gespeichertes Objekt.
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Store ist ein einfacher Objektspeicher. Schlüssel sind relative Pfade mit
+// "/" als Trenner ("covers/12/ab34/small.jpg"); Put überschreibt, Delete
+// eines fehlenden Schlüssels ist kein Fehler.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	Delete(ctx context.Context, key string) error
+}
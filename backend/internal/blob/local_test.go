@@ -0,0 +1,42 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	t.Run("schreiben, lesen, löschen", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "covers/1/abc/small.jpg", strings.NewReader("bild")))
+
+		r, info, err := store.Open(ctx, "covers/1/abc/small.jpg")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(r)
+		r.Close()
+		assert.Equal(t, "bild", string(data))
+		assert.Equal(t, int64(4), info.Size)
+
+		require.NoError(t, store.Delete(ctx, "covers/1/abc/small.jpg"))
+		_, _, err = store.Open(ctx, "covers/1/abc/small.jpg")
+		assert.True(t, errors.Is(err, ErrNotFound))
+		assert.NoError(t, store.Delete(ctx, "covers/1/abc/small.jpg"))
+	})
+
+	t.Run("Schlüssel bleiben im Wurzelverzeichnis", func(t *testing.T) {
+		for _, key := range []string{"../x", "covers/../../x", "/etc/passwd", "", "covers//x"} {
+			assert.Error(t, store.Put(ctx, key, strings.NewReader("x")), key)
+			_, _, err := store.Open(ctx, key)
+			assert.True(t, errors.Is(err, ErrNotFound), key)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package catalogio
+
+import (
+	"bookbazaar-backend/internal/models"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ONIX 3.0 (Reference-Tags), nur die Elemente, die der Katalog füllen kann.
+// Codes aus den EDItEUR-Codelisten stehen als Konstanten mit Listennummer.
+const (
+	onixNamespace   = "http://ns.editeur.org/onix/3.0/reference"
+	onixSender      = "BookBazaar"
+	onixCurrency    = "EUR"
+	onixRecordRef   = "bookbazaar:book:"
+	onixDateTime    = "20060102T1504Z"
+	onixProprietary = "01" // Liste 5
+	onixISBN13      = "15" // Liste 5
+	onixNotifyFull  = "03" // Liste 1: vollständiger Datensatz
+	onixSingleItem  = "00" // Liste 2
+	onixBook        = "BA" // Liste 150: Buch, Einband nicht angegeben
+	onixMainTitle   = "01" // Liste 15
+	onixTitleLevel  = "01" // Liste 149: Titel auf Produktebene
+	onixByAuthor    = "A01"
+	onixSubjectProp = "24" // Liste 27: eigenes Schema
+	onixShortDesc   = "02" // Liste 153
+	onixDescription = "03" // Liste 153
+	onixAnyAudience = "00" // Liste 154
+	onixRetailer    = "09" // Liste 93: Verkauf an Endkunden
+	onixInStock     = "21" // Liste 65
+	onixOutOfStock  = "31" // Liste 65
+	onixFixedPrice  = "04" // Liste 58: gebundener Endpreis inkl. MwSt.
+	onixRental      = "05" // Liste 167: Preis für eine befristete Ausleihe
+)
+
+type onixProduct struct {
+	XMLName            xml.Name              `xml:"Product"`
+	RecordReference    string                `xml:"RecordReference"`
+	NotificationType   string                `xml:"NotificationType"`
+	ProductIdentifiers []onixIdentifier      `xml:"ProductIdentifier"`
+	DescriptiveDetail  onixDescriptiveDetail `xml:"DescriptiveDetail"`
+	CollateralDetail   *onixCollateral       `xml:"CollateralDetail,omitempty"`
+	ProductSupply      onixProductSupply     `xml:"ProductSupply"`
+}
+
+type onixIdentifier struct {
+	ProductIDType string `xml:"ProductIDType"`
+	IDTypeName    string `xml:"IDTypeName,omitempty"`
+	IDValue       string `xml:"IDValue"`
+}
+
+type onixDescriptiveDetail struct {
+	ProductComposition string            `xml:"ProductComposition"`
+	ProductForm        string            `xml:"ProductForm"`
+	TitleDetail        onixTitleDetail   `xml:"TitleDetail"`
+	Contributors       []onixContributor `xml:"Contributor"`
+	Subjects           []onixSubject     `xml:"Subject"`
+}
+
+type onixTitleDetail struct {
+	TitleType         string `xml:"TitleType"`
+	TitleElementLevel string `xml:"TitleElement>TitleElementLevel"`
+	TitleText         string `xml:"TitleElement>TitleText"`
+}
+
+type onixContributor struct {
+	SequenceNumber  int    `xml:"SequenceNumber"`
+	ContributorRole string `xml:"ContributorRole"`
+	PersonName      string `xml:"PersonName"`
+}
+
+type onixSubject struct {
+	SubjectSchemeIdentifier string `xml:"SubjectSchemeIdentifier"`
+	SubjectSchemeName       string `xml:"SubjectSchemeName"`
+	SubjectCode             string `xml:"SubjectCode,omitempty"`
+	SubjectHeadingText      string `xml:"SubjectHeadingText"`
+}
+
+type onixCollateral struct {
+	TextContents []onixTextContent `xml:"TextContent"`
+}
+
+type onixTextContent struct {
+	TextType        string `xml:"TextType"`
+	ContentAudience string `xml:"ContentAudience"`
+	Text            string `xml:"Text"`
+}
+
+type onixProductSupply struct {
+	SupplierRole        string      `xml:"SupplyDetail>Supplier>SupplierRole"`
+	SupplierName        string      `xml:"SupplyDetail>Supplier>SupplierName"`
+	ProductAvailability string      `xml:"SupplyDetail>ProductAvailability"`
+	OnHand              int         `xml:"SupplyDetail>Stock>OnHand"`
+	Prices              []onixPrice `xml:"SupplyDetail>Price"`
+}
+
+type onixPrice struct {
+	PriceType          string `xml:"PriceType"`
+	PriceConditionType string `xml:"PriceCondition>PriceConditionType,omitempty"`
+	PriceAmount        string `xml:"PriceAmount"`
+	CurrencyCode       string `xml:"CurrencyCode"`
+}
+
+type onixWriter struct {
+	w   io.Writer
+	enc *xml.Encoder
+}
+
+func newONIXWriter(w io.Writer, sent time.Time) (*onixWriter, error) {
+	_, err := fmt.Fprintf(w, "%s<ONIXMessage release=\"3.0\" xmlns=\"%s\">\n"+
+		"  <Header>\n    <Sender>\n      <SenderName>%s</SenderName>\n    </Sender>\n    <SentDateTime>%s</SentDateTime>\n  </Header>\n",
+		xml.Header, onixNamespace, onixSender, sent.UTC().Format(onixDateTime))
+	if err != nil {
+		return nil, err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("  ", "  ")
+	return &onixWriter{w: w, enc: enc}, nil
+}
+
+func (o *onixWriter) Write(book *models.Book) error {
+	p := onixProduct{
+		RecordReference:  onixRecordRef + strconv.Itoa(book.ID),
+		NotificationType: onixNotifyFull,
+		ProductIdentifiers: []onixIdentifier{
+			{ProductIDType: onixProprietary, IDTypeName: onixSender, IDValue: strconv.Itoa(book.ID)},
+		},
+		DescriptiveDetail: onixDescriptiveDetail{
+			ProductComposition: onixSingleItem,
+			ProductForm:        onixBook,
+			TitleDetail:        onixTitleDetail{TitleType: onixMainTitle, TitleElementLevel: onixTitleLevel, TitleText: book.Name},
+		},
+		ProductSupply: onixProductSupply{
+			SupplierRole:        onixRetailer,
+			SupplierName:        onixSender,
+			ProductAvailability: onixInStock,
+			OnHand:              book.Quantity,
+			Prices:              []onixPrice{{PriceType: onixFixedPrice, PriceAmount: formatPrice(book.Price), CurrencyCode: onixCurrency}},
+		},
+	}
+	if book.ISBN13 != "" {
+		p.ProductIdentifiers = append(p.ProductIdentifiers, onixIdentifier{ProductIDType: onixISBN13, IDValue: book.ISBN13})
+	}
+
+	authors := book.Authors
+	if len(authors) == 0 && book.Author != "" {
+		authors = []models.AuthorRef{{Name: book.Author}}
+	}
+	for i, a := range authors {
+		p.DescriptiveDetail.Contributors = append(p.DescriptiveDetail.Contributors,
+			onixContributor{SequenceNumber: i + 1, ContributorRole: onixByAuthor, PersonName: a.Name})
+	}
+	for _, c := range book.Categories {
+		p.DescriptiveDetail.Subjects = append(p.DescriptiveDetail.Subjects, onixSubject{
+			SubjectSchemeIdentifier: onixSubjectProp, SubjectSchemeName: onixSender,
+			SubjectCode: strconv.Itoa(c.ID), SubjectHeadingText: c.Name,
+		})
+	}
+	if len(book.Categories) == 0 && book.Genre != "" {
+		p.DescriptiveDetail.Subjects = []onixSubject{{SubjectSchemeIdentifier: onixSubjectProp, SubjectSchemeName: onixSender, SubjectHeadingText: book.Genre}}
+	}
+
+	var texts []onixTextContent
+	if book.Description != "" {
+		texts = append(texts, onixTextContent{TextType: onixShortDesc, ContentAudience: onixAnyAudience, Text: book.Description})
+	}
+	if book.Descriptionlong != "" {
+		texts = append(texts, onixTextContent{TextType: onixDescription, ContentAudience: onixAnyAudience, Text: book.Descriptionlong})
+	}
+	if texts != nil {
+		p.CollateralDetail = &onixCollateral{TextContents: texts}
+	}
+
+	if book.Quantity < 1 {
+		p.ProductSupply.ProductAvailability = onixOutOfStock
+	}
+	if book.BorrowPrice > 0 {
+		p.ProductSupply.Prices = append(p.ProductSupply.Prices, onixPrice{
+			PriceType: onixFixedPrice, PriceConditionType: onixRental, PriceAmount: formatPrice(book.BorrowPrice), CurrencyCode: onixCurrency,
+		})
+	}
+	return o.enc.Encode(p)
+}
+
+func (o *onixWriter) Close() error {
+	if err := o.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(o.w, "\n</ONIXMessage>\n")
+	return err
+}
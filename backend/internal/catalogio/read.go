@@ -0,0 +1,205 @@
+// Package catalogio liest und schreibt den Katalog in Austauschformaten:
+// CSV und JSON für den Import, CSV, JSON Lines und ONIX 3.0 für den Export.
+// Geprüft wird hier nur, ob sich eine Zeile lesen lässt; die fachlichen
+// Regeln wendet der Import-Service an.
+package catalogio
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Formate für Read.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Row ist ein gelesener Datensatz. Line ist die Zeile in der CSV-Datei
+// (Kopfzeile = 1) bzw. die Position im JSON-Array ab 1. Ist Err gesetzt,
+// ließ sich die Zeile nicht lesen und Book ist unvollständig.
+type Row struct {
+	Line int
+	Book models.Book
+	Err  error
+}
+
+// Spalten der CSV-Datei, ohne Groß-/Kleinschreibung. Mehrere Autoren oder
+// Kategorie-IDs stehen durch ";" getrennt in einer Zelle, isbn nimmt ISBN-10
+// und ISBN-13.
+var csvColumns = []string{"isbn", "name", "author", "price", "borrowprice", "quantity", "genre", "categories", "description", "descriptionlong"}
+
+// listSeparator trennt mehrere Werte in einer Zelle.
+const listSeparator = ";"
+
+// Read liest alle Datensätze. Ein Fehler kommt nur, wenn die Datei als Ganzes
+// unbrauchbar ist (z.B. fehlende Kopfzeile oder kein JSON-Array).
+func Read(r io.Reader, format string) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatJSON:
+		return readJSON(r)
+	}
+	return nil, apperr.Validation("invalid_import_format", "Unbekanntes Importformat %q (erlaubt: csv, json)", format)
+}
+
+func invalidImport(format string, args ...any) error {
+	return apperr.Validation("invalid_import", format, args...)
+}
+
+func readCSV(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff")) // BOM aus Excel
+
+	cr := csv.NewReader(bytes.NewReader(data))
+	// Tabellenprogramme mit deutschem Gebietsschema trennen mit Semikolon
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	head, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, invalidImport("Die Datei ist leer")
+	}
+	if err != nil {
+		return nil, invalidImport("Kopfzeile nicht lesbar: %v", err)
+	}
+	index := map[string]int{}
+	for i, name := range head {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return nil, invalidImport("Unbekannte Spalte %q (erlaubt: %s)", name, strings.Join(csvColumns, ", "))
+		}
+		if _, dup := index[name]; dup {
+			return nil, invalidImport("Spalte %q kommt doppelt vor", name)
+		}
+		index[name] = i
+	}
+	for _, required := range []string{"name", "author"} {
+		if _, ok := index[required]; !ok {
+			return nil, invalidImport("Spalte %q fehlt", required)
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, invalidImport("Datei nicht lesbar: %v", err)
+		}
+		line, _ := cr.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue // Leerzeile
+		}
+		row := Row{Line: line}
+		row.Book, row.Err = csvBook(record, index)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvBook baut aus einer CSV-Zeile ein Buch; Zahlen dürfen ein Dezimalkomma haben.
+func csvBook(record []string, index map[string]int) (models.Book, error) {
+	cell := func(column string) string {
+		i, ok := index[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	var book models.Book
+	var fields []apperr.FieldError
+	number := func(column string, dst *float64) {
+		if v := cell(column); v != "" {
+			f, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+			if err != nil {
+				fields = append(fields, apperr.FieldError{Field: column, Message: "'" + v + "' ist keine Zahl"})
+			}
+			*dst = f
+		}
+	}
+	integer := func(column string, v string) (int, bool) {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{Field: column, Message: "'" + v + "' ist keine ganze Zahl"})
+		}
+		return n, err == nil
+	}
+
+	book.ISBN13 = cell("isbn")
+	book.Name = cell("name")
+	book.Genre = cell("genre")
+	book.Description = cell("description")
+	book.Descriptionlong = cell("descriptionlong")
+	number("price", &book.Price)
+	number("borrowprice", &book.BorrowPrice)
+	if v := cell("quantity"); v != "" {
+		book.Quantity, _ = integer("quantity", v)
+	}
+
+	authors := splitCell(cell("author"))
+	book.Author = strings.Join(authors, ", ")
+	if len(authors) > 1 {
+		for _, name := range authors {
+			book.Authors = append(book.Authors, models.AuthorRef{Name: name})
+		}
+	}
+	for _, v := range splitCell(cell("categories")) {
+		if id, ok := integer("categories", v); ok {
+			book.Categories = append(book.Categories, models.CategoryRef{ID: id})
+		}
+	}
+
+	if len(fields) > 0 {
+		e := apperr.Validation("invalid_row", "Zeile nicht lesbar: %s %s", fields[0].Field, fields[0].Message)
+		e.Fields = fields
+		return book, e
+	}
+	return book, nil
+}
+
+func splitCell(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, listSeparator) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// readJSON liest ein Array von Büchern im Format von POST /api/books.
+func readJSON(r io.Reader) ([]Row, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, invalidImport("Erwartet wird ein JSON-Array von Büchern: %v", err)
+	}
+	rows := make([]Row, len(raw))
+	for i, msg := range raw {
+		rows[i].Line = i + 1
+		dec := json.NewDecoder(bytes.NewReader(msg))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rows[i].Book); err != nil {
+			rows[i].Err = apperr.Validation("invalid_row", "Eintrag nicht lesbar: %v", err)
+		}
+	}
+	return rows, nil
+}
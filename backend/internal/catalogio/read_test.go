@@ -0,0 +1,73 @@
+package catalogio
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead(t *testing.T) {
+	t.Run("CSV mit Semikolon und Dezimalkomma", func(t *testing.T) {
+		data := "\ufeffName;Author;Price;Quantity;Categories\n" +
+			`Ein gutes Omen;"Terry Pratchett; Neil Gaiman";12,5;3;"4;7"` + "\n" +
+			"\n" +
+			"Der Hobbit;J.R.R. Tolkien;9.99;;\n"
+
+		rows, err := Read(strings.NewReader(data), FormatCSV)
+
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		require.NoError(t, rows[0].Err)
+		assert.Equal(t, 2, rows[0].Line)
+		assert.Equal(t, "Terry Pratchett, Neil Gaiman", rows[0].Book.Author)
+		assert.Equal(t, []models.AuthorRef{{Name: "Terry Pratchett"}, {Name: "Neil Gaiman"}}, rows[0].Book.Authors)
+		assert.Equal(t, 12.5, rows[0].Book.Price)
+		assert.Equal(t, 3, rows[0].Book.Quantity)
+		assert.Equal(t, []models.CategoryRef{{ID: 4}, {ID: 7}}, rows[0].Book.Categories)
+		assert.Equal(t, 4, rows[1].Line)
+		assert.Empty(t, rows[1].Book.Authors)
+	})
+
+	t.Run("Zeile mit ungültiger Zahl", func(t *testing.T) {
+		rows, err := Read(strings.NewReader("name,author,price\nDer Hobbit,J.R.R. Tolkien,zehn\n"), FormatCSV)
+
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		var ae *apperr.Error
+		require.True(t, errors.As(rows[0].Err, &ae))
+		assert.Equal(t, "invalid_row", ae.Code)
+		assert.Equal(t, "price", ae.Fields[0].Field)
+	})
+
+	t.Run("unbekannte oder fehlende Spalte", func(t *testing.T) {
+		_, err := Read(strings.NewReader("name,author,verlag\n"), FormatCSV)
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+
+		_, err = Read(strings.NewReader("name,price\n"), FormatCSV)
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+
+	t.Run("JSON-Array", func(t *testing.T) {
+		data := `[{"name":"Der Hobbit","author":"J.R.R. Tolkien","quantity":2},{"name":"Momo","verlag":"Thienemann"}]`
+
+		rows, err := Read(strings.NewReader(data), FormatJSON)
+
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.NoError(t, rows[0].Err)
+		assert.Equal(t, 2, rows[0].Book.Quantity)
+		assert.Equal(t, 2, rows[1].Line)
+		assert.True(t, errors.Is(rows[1].Err, apperr.ErrValidation))
+	})
+
+	t.Run("unbekanntes Format", func(t *testing.T) {
+		_, err := Read(strings.NewReader(""), "xml")
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+}
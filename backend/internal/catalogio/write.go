@@ -0,0 +1,105 @@
+package catalogio
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exportformate zusätzlich zu FormatCSV.
+const (
+	FormatJSONLines = "jsonl"
+	FormatONIX      = "onix"
+)
+
+// ContentTypes der Exportformate für die HTTP-Antwort.
+var ContentTypes = map[string]string{
+	FormatCSV:       "text/csv; charset=utf-8",
+	FormatJSONLines: "application/x-ndjson",
+	FormatONIX:      "application/xml",
+}
+
+// Writer schreibt Bücher einzeln, ohne den Katalog im Speicher zu sammeln.
+// Close schreibt den Abschluss des Formats, schließt aber nicht den
+// darunterliegenden io.Writer.
+type Writer interface {
+	Write(book *models.Book) error
+	Close() error
+}
+
+// NewWriter liefert einen Writer für FormatCSV, FormatJSONLines oder FormatONIX.
+// CSV hat dieselben Spalten wie der Import, so dass sich ein Export wieder
+// einlesen lässt.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw}, nil
+	case FormatJSONLines:
+		return &jsonLinesWriter{enc: json.NewEncoder(w)}, nil
+	case FormatONIX:
+		return newONIXWriter(w, time.Now())
+	}
+	return nil, apperr.Validation("invalid_export_format", "Unbekanntes Exportformat %q (erlaubt: csv, jsonl, onix)", format)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(book *models.Book) error {
+	authors := []string{book.Author}
+	if len(book.Authors) > 0 {
+		authors = authors[:0]
+		for _, a := range book.Authors {
+			authors = append(authors, a.Name)
+		}
+	}
+	categories := make([]string, len(book.Categories))
+	for i, c := range book.Categories {
+		categories[i] = strconv.Itoa(c.ID)
+	}
+	// Reihenfolge wie csvColumns
+	return c.w.Write([]string{
+		book.ISBN13,
+		book.Name,
+		strings.Join(authors, listSeparator+" "),
+		formatPrice(book.Price),
+		formatPrice(book.BorrowPrice),
+		strconv.Itoa(book.Quantity),
+		book.Genre,
+		strings.Join(categories, listSeparator),
+		book.Description,
+		book.Descriptionlong,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func formatPrice(p float64) string {
+	return strconv.FormatFloat(p, 'f', 2, 64)
+}
+
+// jsonLinesWriter schreibt ein Buch je Zeile im Format der API.
+type jsonLinesWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonLinesWriter) Write(book *models.Book) error {
+	return j.enc.Encode(book)
+}
+
+func (j *jsonLinesWriter) Close() error {
+	return nil
+}
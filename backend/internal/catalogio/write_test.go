@@ -0,0 +1,115 @@
+package catalogio
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	omen := &models.Book{
+		ID: 7, Name: "Ein gutes Omen", Author: "Terry Pratchett, Neil Gaiman", ISBN13: "9783492281553",
+		Authors:    []models.AuthorRef{{ID: 1, Name: "Terry Pratchett"}, {ID: 2, Name: "Neil Gaiman"}},
+		Categories: []models.CategoryRef{{ID: 4, Name: "Fantasy"}}, Genre: "Fantasy",
+		Price: 12.5, BorrowPrice: 1.5, Quantity: 3, Description: "Das Ende naht",
+	}
+	write := func(t *testing.T, format string, books ...*models.Book) string {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, format)
+		require.NoError(t, err)
+		for _, b := range books {
+			require.NoError(t, w.Write(b))
+		}
+		require.NoError(t, w.Close())
+		return buf.String()
+	}
+
+	t.Run("CSV lässt sich wieder importieren", func(t *testing.T) {
+		data := write(t, FormatCSV, omen)
+
+		rows, err := Read(strings.NewReader(data), FormatCSV)
+
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		require.NoError(t, rows[0].Err)
+		book := rows[0].Book
+		assert.Equal(t, omen.ISBN13, book.ISBN13)
+		assert.Equal(t, omen.Author, book.Author)
+		assert.Equal(t, []models.AuthorRef{{Name: "Terry Pratchett"}, {Name: "Neil Gaiman"}}, book.Authors)
+		assert.Equal(t, []models.CategoryRef{{ID: 4}}, book.Categories)
+		assert.Equal(t, 12.5, book.Price)
+		assert.Equal(t, 1.5, book.BorrowPrice)
+		assert.Equal(t, 3, book.Quantity)
+	})
+
+	t.Run("JSON Lines ein Buch je Zeile", func(t *testing.T) {
+		data := write(t, FormatJSONLines, omen, &models.Book{ID: 8, Name: "Momo", Author: "Michael Ende"})
+
+		lines := strings.Split(strings.TrimSpace(data), "\n")
+		require.Len(t, lines, 2)
+		var book models.Book
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &book))
+		assert.Equal(t, 3, book.Quantity)
+		assert.Equal(t, 1.5, book.BorrowPrice)
+	})
+
+	t.Run("ONIX", func(t *testing.T) {
+		data := write(t, FormatONIX, omen, &models.Book{ID: 8, Name: "Momo", Author: "Michael Ende"})
+
+		var msg struct {
+			Release  string `xml:"release,attr"`
+			Products []struct {
+				RecordReference string `xml:"RecordReference"`
+				IDs             []struct {
+					Type  string `xml:"ProductIDType"`
+					Value string `xml:"IDValue"`
+				} `xml:"ProductIdentifier"`
+				Contributors []string `xml:"DescriptiveDetail>Contributor>PersonName"`
+				Availability string   `xml:"ProductSupply>SupplyDetail>ProductAvailability"`
+				OnHand       int      `xml:"ProductSupply>SupplyDetail>Stock>OnHand"`
+				Prices       []struct {
+					Amount    string `xml:"PriceAmount"`
+					Condition string `xml:"PriceCondition>PriceConditionType"`
+				} `xml:"ProductSupply>SupplyDetail>Price"`
+			} `xml:"Product"`
+		}
+		require.NoError(t, xml.Unmarshal([]byte(data), &msg))
+		assert.Equal(t, "3.0", msg.Release)
+		require.Len(t, msg.Products, 2)
+		p := msg.Products[0]
+		assert.Equal(t, "bookbazaar:book:7", p.RecordReference)
+		require.Len(t, p.IDs, 2)
+		assert.Equal(t, "9783492281553", p.IDs[1].Value)
+		assert.Equal(t, []string{"Terry Pratchett", "Neil Gaiman"}, p.Contributors)
+		assert.Equal(t, onixInStock, p.Availability)
+		assert.Equal(t, 3, p.OnHand)
+		require.Len(t, p.Prices, 2)
+		assert.Equal(t, "12.50", p.Prices[0].Amount)
+		assert.Equal(t, onixRental, p.Prices[1].Condition)
+		assert.Equal(t, onixOutOfStock, msg.Products[1].Availability)
+		assert.Len(t, msg.Products[1].Prices, 1)
+	})
+
+	t.Run("ONIX-Kopf mit Sendezeit", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := newONIXWriter(&buf, time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC))
+
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "<SentDateTime>20260301T0930Z</SentDateTime>")
+	})
+
+	t.Run("unbekanntes Format", func(t *testing.T) {
+		_, err := NewWriter(&bytes.Buffer{}, "xlsx")
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+}
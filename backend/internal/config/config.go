@@ -0,0 +1,456 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config bündelt alle Einstellungen, die sich zwischen den Umgebungen
+// (dev, staging, prod) unterscheiden. Werte kommen in dieser Reihenfolge
+// zustande: Defaults -> optionale YAML/TOML-Datei -> Umgebungsvariablen.
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth"`
+	CORS     CORSConfig     `yaml:"cors" toml:"cors"`
+	Workers  WorkersConfig  `yaml:"workers" toml:"workers"`
+	Health   HealthConfig   `yaml:"health" toml:"health"`
+	Log      LogConfig      `yaml:"log" toml:"log"`
+	Covers   CoversConfig   `yaml:"covers" toml:"covers"`
+	Import   ImportConfig   `yaml:"import" toml:"import"`
+	Public   PublicConfig   `yaml:"public" toml:"public"`
+}
+
+type ServerConfig struct {
+	// ListenAddr im Format host:port, z.B. ":8080"
+	ListenAddr        string   `yaml:"listenAddr" toml:"listenAddr"`
+	ReadHeaderTimeout Duration `yaml:"readHeaderTimeout" toml:"readHeaderTimeout"`
+	IdleTimeout       Duration `yaml:"idleTimeout" toml:"idleTimeout"`
+	// ShutdownTimeout begrenzt, wie lange laufende Requests beim Herunterfahren
+	// noch fertig werden dürfen (sollte unter dem terminationGracePeriod liegen).
+	ShutdownTimeout Duration `yaml:"shutdownTimeout" toml:"shutdownTimeout"`
+	// RequestTimeout ist die Deadline für API-Requests inklusive aller
+	// SQL-Abfragen. 0 schaltet sie ab.
+	RequestTimeout Duration `yaml:"requestTimeout" toml:"requestTimeout"`
+	// ExportTimeout ersetzt RequestTimeout für den Katalogexport, der den
+	// ganzen Katalog streamt. 0 schaltet sie ab.
+	ExportTimeout Duration `yaml:"exportTimeout" toml:"exportTimeout"`
+	// TrustedProxies sind IPs/CIDRs, deren X-Forwarded-For als Client-IP
+	// gilt. Leer = keinem Proxy trauen; hinter einem Ingress muss er hier
+	// stehen, sonst teilen sich alle anonymen Besucher ein Rate-Limit.
+	TrustedProxies []string `yaml:"trustedProxies" toml:"trustedProxies"`
+}
+
+// Speicher-Backends für DatabaseConfig.Driver
+const (
+	DriverPostgres = "postgres"
+	// DriverMemory hält alles im Prozess – nur für Tests und lokale Entwicklung.
+	DriverMemory = "memory"
+)
+
+type DatabaseConfig struct {
+	Driver          string   `yaml:"driver" toml:"driver"`
+	DSN             string   `yaml:"dsn" toml:"dsn"`
+	MaxOpenConns    int      `yaml:"maxOpenConns" toml:"maxOpenConns"`
+	MaxIdleConns    int      `yaml:"maxIdleConns" toml:"maxIdleConns"`
+	ConnMaxLifetime Duration `yaml:"connMaxLifetime" toml:"connMaxLifetime"`
+}
+
+type AuthConfig struct {
+	JWTSecret    string `yaml:"jwtSecret" toml:"jwtSecret"`
+	CookieDomain string `yaml:"cookieDomain" toml:"cookieDomain"`
+	CookieSecure bool   `yaml:"cookieSecure" toml:"cookieSecure"`
+}
+
+type CORSConfig struct {
+	AllowOrigins []string `yaml:"allowOrigins" toml:"allowOrigins"`
+}
+
+type HealthConfig struct {
+	CheckTimeout Duration `yaml:"checkTimeout" toml:"checkTimeout"`
+	// PoolSaturationThreshold (0..1): ab diesem Anteil belegter Verbindungen
+	// plus wartenden Anfragen meldet /readyz "nicht bereit".
+	PoolSaturationThreshold float64 `yaml:"poolSaturationThreshold" toml:"poolSaturationThreshold"`
+}
+
+type LogConfig struct {
+	// Format "json" für Produktion (Log-Aggregation), "text" für die Konsole.
+	Format string `yaml:"format" toml:"format"`
+	// Level "debug", "info", "warn" oder "error".
+	Level string `yaml:"level" toml:"level"`
+}
+
+type CoversConfig struct {
+	// Dir ist das Wurzelverzeichnis des lokalen Blob-Speichers.
+	Dir string `yaml:"dir" toml:"dir"`
+	// MaxUploadBytes begrenzt die Größe eines hochgeladenen Covers.
+	MaxUploadBytes int `yaml:"maxUploadBytes" toml:"maxUploadBytes"`
+}
+
+type ImportConfig struct {
+	// MaxUploadBytes begrenzt die Größe einer Importdatei über die API.
+	MaxUploadBytes int `yaml:"maxUploadBytes" toml:"maxUploadBytes"`
+	// ChunkSize ist die Anzahl Zeilen je Transaktion; 0 schreibt alles in einer.
+	ChunkSize int `yaml:"chunkSize" toml:"chunkSize"`
+}
+
+// PublicConfig regelt das Stöbern im Katalog ohne Anmeldung.
+type PublicConfig struct {
+	// RequestsPerMinute je Client-IP für Katalogabfragen ohne Token. 0
+	// schaltet den anonymen Zugriff ab; angemeldete User sind nicht begrenzt.
+	RequestsPerMinute int `yaml:"requestsPerMinute" toml:"requestsPerMinute"`
+	// Burst ist die Anzahl Anfragen, die ein Client auf einmal stellen darf.
+	Burst int `yaml:"burst" toml:"burst"`
+}
+
+type WorkersConfig struct {
+	CartSweepInterval Duration `yaml:"cartSweepInterval" toml:"cartSweepInterval"`
+}
+
+// EnvConfigFile zeigt auf eine optionale Konfigurationsdatei (.yaml, .yml oder .toml).
+const EnvConfigFile = "BOOKBAZAAR_CONFIG"
+
+// minSecretLength verhindert, dass in Produktion ein trivialer JWT-Secret landet.
+const minSecretLength = 16
+
+// Default liefert die Werte für die lokale Entwicklung. DSN und JWT-Secret
+// haben bewusst keinen Default und müssen immer gesetzt werden.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			ListenAddr:        ":8080",
+			ReadHeaderTimeout: Duration{10 * time.Second},
+			IdleTimeout:       Duration{2 * time.Minute},
+			ShutdownTimeout:   Duration{20 * time.Second},
+			RequestTimeout:    Duration{10 * time.Second},
+			ExportTimeout:     Duration{5 * time.Minute},
+		},
+		Database: DatabaseConfig{
+			Driver:          DriverPostgres,
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: Duration{30 * time.Minute},
+		},
+		Auth: AuthConfig{
+			CookieDomain: "localhost",
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"http://localhost:5173"},
+		},
+		Workers: WorkersConfig{
+			CartSweepInterval: Duration{time.Minute},
+		},
+		Health: HealthConfig{
+			CheckTimeout:            Duration{2 * time.Second},
+			PoolSaturationThreshold: 0.9,
+		},
+		Log: LogConfig{
+			Format: "text",
+			Level:  "info",
+		},
+		Covers: CoversConfig{
+			Dir:            "data/covers",
+			MaxUploadBytes: 5 << 20,
+		},
+		Import: ImportConfig{
+			MaxUploadBytes: 20 << 20,
+			ChunkSize:      500,
+		},
+		Public: PublicConfig{
+			RequestsPerMinute: 60,
+			Burst:             20,
+		},
+	}
+}
+
+// Load baut die Konfiguration auf und validiert sie vollständig. Ist path leer,
+// wird BOOKBAZAAR_CONFIG ausgewertet; ist auch diese leer, werden nur Defaults
+// und Env-Variablen genutzt.
+func Load(path string) (*Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Read wie Load, aber ohne Validierung. Für Subcommands, die nur einen Teil
+// der Konfiguration brauchen (z.B. migrate nur die Datenbank).
+func Read(path string) (*Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv(EnvConfigFile)
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(&cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: datei %q kann nicht gelesen werden: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("config: unbekanntes dateiformat %q (erlaubt: .yaml, .yml, .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("config: datei %q ist ungültig: %w", path, err)
+	}
+	return nil
+}
+
+type lookupFunc func(key string) (string, bool)
+
+// applyEnv überschreibt einzelne Werte mit BOOKBAZAAR_* Variablen.
+func applyEnv(cfg *Config, lookup lookupFunc) error {
+	str := func(key string, dst *string) {
+		if v, ok := lookup(key); ok {
+			*dst = v
+		}
+	}
+	var errs []error
+	integer := func(key string, dst *int) {
+		if v, ok := lookup(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q ist keine ganze Zahl", key, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	boolean := func(key string, dst *bool) {
+		if v, ok := lookup(key); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q ist kein bool", key, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	duration := func(key string, dst *Duration) {
+		if v, ok := lookup(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q ist keine Dauer (z.B. 30s, 5m)", key, v))
+				return
+			}
+			dst.Duration = d
+		}
+	}
+	list := func(key string, dst *[]string) {
+		if v, ok := lookup(key); ok {
+			*dst = splitList(v)
+		}
+	}
+
+	str("BOOKBAZAAR_LISTEN_ADDR", &cfg.Server.ListenAddr)
+	duration("BOOKBAZAAR_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+	duration("BOOKBAZAAR_REQUEST_TIMEOUT", &cfg.Server.RequestTimeout)
+	duration("BOOKBAZAAR_EXPORT_TIMEOUT", &cfg.Server.ExportTimeout)
+	list("BOOKBAZAAR_TRUSTED_PROXIES", &cfg.Server.TrustedProxies)
+
+	str("BOOKBAZAAR_DB_DRIVER", &cfg.Database.Driver)
+	str("BOOKBAZAAR_DB_DSN", &cfg.Database.DSN)
+	integer("BOOKBAZAAR_DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	integer("BOOKBAZAAR_DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	duration("BOOKBAZAAR_DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+
+	str("BOOKBAZAAR_JWT_SECRET", &cfg.Auth.JWTSecret)
+	str("BOOKBAZAAR_COOKIE_DOMAIN", &cfg.Auth.CookieDomain)
+	boolean("BOOKBAZAAR_COOKIE_SECURE", &cfg.Auth.CookieSecure)
+
+	list("BOOKBAZAAR_CORS_ORIGINS", &cfg.CORS.AllowOrigins)
+
+	duration("BOOKBAZAAR_CART_SWEEP_INTERVAL", &cfg.Workers.CartSweepInterval)
+
+	str("BOOKBAZAAR_LOG_FORMAT", &cfg.Log.Format)
+	str("BOOKBAZAAR_LOG_LEVEL", &cfg.Log.Level)
+
+	str("BOOKBAZAAR_COVERS_DIR", &cfg.Covers.Dir)
+	integer("BOOKBAZAAR_COVERS_MAX_UPLOAD_BYTES", &cfg.Covers.MaxUploadBytes)
+	integer("BOOKBAZAAR_IMPORT_MAX_UPLOAD_BYTES", &cfg.Import.MaxUploadBytes)
+	integer("BOOKBAZAAR_IMPORT_CHUNK_SIZE", &cfg.Import.ChunkSize)
+
+	integer("BOOKBAZAAR_PUBLIC_REQUESTS_PER_MINUTE", &cfg.Public.RequestsPerMinute)
+	integer("BOOKBAZAAR_PUBLIC_BURST", &cfg.Public.Burst)
+
+	return errors.Join(errs...)
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// Validate sammelt alle Fehler, damit beim Start alles auf einmal sichtbar ist.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.ListenAddr == "" {
+		errs = append(errs, errors.New("server.listenAddr (BOOKBAZAAR_LISTEN_ADDR) darf nicht leer sein"))
+	}
+	if c.Server.ShutdownTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("server.shutdownTimeout (BOOKBAZAAR_SHUTDOWN_TIMEOUT) muss größer 0 sein"))
+	}
+	if c.Server.RequestTimeout.Duration < 0 {
+		errs = append(errs, errors.New("server.requestTimeout (BOOKBAZAAR_REQUEST_TIMEOUT) darf nicht negativ sein"))
+	}
+	if c.Server.ExportTimeout.Duration < 0 {
+		errs = append(errs, errors.New("server.exportTimeout (BOOKBAZAAR_EXPORT_TIMEOUT) darf nicht negativ sein"))
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("server.trustedProxies: %q ist keine IP oder CIDR", proxy))
+		}
+	}
+
+	errs = append(errs, c.Database.validate()...)
+
+	if len(c.Auth.JWTSecret) < minSecretLength {
+		errs = append(errs, fmt.Errorf("auth.jwtSecret (BOOKBAZAAR_JWT_SECRET) muss mindestens %d Zeichen haben", minSecretLength))
+	}
+
+	if len(c.CORS.AllowOrigins) == 0 {
+		errs = append(errs, errors.New("cors.allowOrigins (BOOKBAZAAR_CORS_ORIGINS) braucht mindestens einen Eintrag"))
+	}
+	for _, origin := range c.CORS.AllowOrigins {
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("cors.allowOrigins: %q ist keine gültige Origin", origin))
+		}
+	}
+
+	if c.Workers.CartSweepInterval.Duration <= 0 {
+		errs = append(errs, errors.New("workers.cartSweepInterval (BOOKBAZAAR_CART_SWEEP_INTERVAL) muss größer 0 sein"))
+	}
+
+	if c.Health.CheckTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("health.checkTimeout muss größer 0 sein"))
+	}
+	if c.Health.PoolSaturationThreshold <= 0 || c.Health.PoolSaturationThreshold > 1 {
+		errs = append(errs, errors.New("health.poolSaturationThreshold muss zwischen 0 und 1 liegen"))
+	}
+
+	if c.Covers.Dir == "" {
+		errs = append(errs, errors.New("covers.dir (BOOKBAZAAR_COVERS_DIR) darf nicht leer sein"))
+	}
+	if c.Covers.MaxUploadBytes < 1 {
+		errs = append(errs, errors.New("covers.maxUploadBytes (BOOKBAZAAR_COVERS_MAX_UPLOAD_BYTES) muss größer 0 sein"))
+	}
+	if c.Import.MaxUploadBytes < 1 {
+		errs = append(errs, errors.New("import.maxUploadBytes (BOOKBAZAAR_IMPORT_MAX_UPLOAD_BYTES) muss größer 0 sein"))
+	}
+	if c.Import.ChunkSize < 0 {
+		errs = append(errs, errors.New("import.chunkSize (BOOKBAZAAR_IMPORT_CHUNK_SIZE) darf nicht negativ sein"))
+	}
+	if c.Public.RequestsPerMinute < 0 {
+		errs = append(errs, errors.New("public.requestsPerMinute (BOOKBAZAAR_PUBLIC_REQUESTS_PER_MINUTE) darf nicht negativ sein"))
+	}
+	if c.Public.RequestsPerMinute > 0 && c.Public.Burst < 1 {
+		errs = append(errs, errors.New("public.burst (BOOKBAZAAR_PUBLIC_BURST) muss mindestens 1 sein"))
+	}
+
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("log.format (BOOKBAZAAR_LOG_FORMAT): %q unbekannt (erlaubt: json, text)", c.Log.Format))
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level (BOOKBAZAAR_LOG_LEVEL): %q unbekannt (erlaubt: debug, info, warn, error)", c.Log.Level))
+	}
+
+	return joinErrors(errs)
+}
+
+// Validate prüft nur den Datenbank-Teil.
+func (c DatabaseConfig) Validate() error {
+	return joinErrors(c.validate())
+}
+
+func joinErrors(errs []error) error {
+	if len(errs) > 0 {
+		return fmt.Errorf("ungültige Konfiguration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (c DatabaseConfig) validate() []error {
+	var errs []error
+
+	switch c.Driver {
+	case DriverMemory:
+		return nil
+	case DriverPostgres:
+	default:
+		return append(errs, fmt.Errorf("database.driver (BOOKBAZAAR_DB_DRIVER): %q unbekannt (erlaubt: %s, %s)", c.Driver, DriverPostgres, DriverMemory))
+	}
+
+	if c.DSN == "" {
+		errs = append(errs, errors.New("database.dsn (BOOKBAZAAR_DB_DSN) fehlt"))
+	} else if _, err := pgx.ParseConfig(c.DSN); err != nil {
+		// ohne err: die Meldung kann Teile der DSN samt Passwort enthalten
+		errs = append(errs, errors.New("database.dsn (BOOKBAZAAR_DB_DSN) muss eine postgres:// URL oder key=value-Angabe wie \"host=db user=shop\" sein"))
+	}
+	if c.MaxOpenConns < 1 {
+		errs = append(errs, errors.New("database.maxOpenConns muss mindestens 1 sein"))
+	}
+	if c.MaxIdleConns < 0 || c.MaxIdleConns > c.MaxOpenConns {
+		errs = append(errs, errors.New("database.maxIdleConns muss zwischen 0 und maxOpenConns liegen"))
+	}
+	return errs
+}
+
+// Duration erlaubt Angaben wie "30s" oder "5m" in YAML, TOML und Env-Variablen.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
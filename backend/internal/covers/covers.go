@@ -0,0 +1,148 @@
+// Package covers prüft hochgeladene Buchcover und erzeugt daraus die
+// ausgelieferten Größen. Alle Größen werden als JPEG neu kodiert; damit
+// landen keine Metadaten (EXIF, GPS) und keine Fremdformate im Speicher.
+package covers
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // registriert den PNG-Decoder für image.Decode
+	"net/http"
+	"slices"
+)
+
+// Size ist eine ausgelieferte Größe; Bilder werden auf höchstens Width Pixel
+// Breite verkleinert, nie vergrößert.
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Sizes sind alle erzeugten Größen. DefaultSize steckt in Book.CoverURL.
+var Sizes = []Size{
+	{"small", 160},
+	{"medium", 400},
+	{"large", 1200},
+}
+
+const DefaultSize = "medium"
+
+// AllowedTypes sind die akzeptierten Formate, erkannt am Inhalt, nicht an
+// Dateiname oder Content-Type des Uploads.
+var AllowedTypes = []string{"image/jpeg", "image/png"}
+
+// MaxPixels begrenzt die Bildfläche, damit ein kleines, stark komprimiertes
+// Bild beim Dekodieren nicht den Speicher sprengt.
+const MaxPixels = 40_000_000
+
+const jpegQuality = 85
+
+// Cover ist ein verarbeitetes Cover: der Hash des Originals (Teil der URL)
+// und die kodierten Größen nach Size.Name.
+type Cover struct {
+	Hash   string
+	Images map[string][]byte
+}
+
+// ValidSize meldet, ob name eine der erzeugten Größen ist.
+func ValidSize(name string) bool {
+	return slices.ContainsFunc(Sizes, func(s Size) bool { return s.Name == name })
+}
+
+// Key ist der Blob-Schlüssel einer Größe.
+func Key(bookID int, hash, size string) string {
+	return fmt.Sprintf("covers/%d/%s/%s.jpg", bookID, hash, size)
+}
+
+// URL ist die öffentliche Adresse einer Größe. Der Hash im Pfad ändert sich
+// mit jedem neuen Cover, deshalb dürfen Clients die Antwort unbegrenzt cachen.
+func URL(bookID int, hash, size string) string {
+	return fmt.Sprintf("/api/covers/%d/%s/%s.jpg", bookID, hash, size)
+}
+
+// Process prüft die hochgeladenen Bytes und erzeugt alle Größen.
+func Process(data []byte) (*Cover, error) {
+	if ct := http.DetectContentType(data); !slices.Contains(AllowedTypes, ct) {
+		return nil, apperr.UnsupportedMedia("unsupported_cover_type", "Cover muss JPEG oder PNG sein, erkannt wurde %s", ct)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperr.Validation("invalid_cover", "Cover kann nicht gelesen werden").WithCause(err)
+	}
+	if cfg.Width < 1 || cfg.Height < 1 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, apperr.Validation("invalid_cover", "Cover hat ungültige Abmessungen %dx%d", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperr.Validation("invalid_cover", "Cover kann nicht gelesen werden").WithCause(err)
+	}
+	src := flatten(img)
+
+	sum := sha256.Sum256(data)
+	cover := &Cover{Hash: hex.EncodeToString(sum[:8]), Images: map[string][]byte{}}
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, fit(src, size.Width), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		cover.Images[size.Name] = buf.Bytes()
+	}
+	return cover, nil
+}
+
+// flatten zeichnet das Bild auf weißen Grund, weil JPEG keine Transparenz kennt.
+func flatten(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// fit verkleinert src auf höchstens width Pixel Breite.
+func fit(src *image.RGBA, width int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= width {
+		return src
+	}
+	return scale(src, width, max(1, h*width/w))
+}
+
+// scale verkleinert per Flächenmittel: jedes Zielpixel ist der Durchschnitt
+// der Quellpixel, die es überdeckt. Für reines Verkleinern scharf genug und
+// ohne Abhängigkeit auf golang.org/x/image.
+func scale(src *image.RGBA, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+
+			var r, g, b, n uint32
+			for sy := y0; sy < y1; sy++ {
+				off := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[off])
+					g += uint32(src.Pix[off+1])
+					b += uint32(src.Pix[off+2])
+					off += 4
+					n++
+				}
+			}
+			d := dst.PixOffset(x, y)
+			dst.Pix[d], dst.Pix[d+1], dst.Pix[d+2], dst.Pix[d+3] = uint8(r/n), uint8(g/n), uint8(b/n), 0xff
+		}
+	}
+	return dst
+}
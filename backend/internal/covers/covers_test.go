@@ -0,0 +1,86 @@
+package covers
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("erzeugt alle Größen als JPEG", func(t *testing.T) {
+		cover, err := Process(pngBytes(t, 800, 1200))
+		require.NoError(t, err)
+		assert.Len(t, cover.Hash, 16)
+
+		want := map[string][2]int{"small": {160, 240}, "medium": {400, 600}, "large": {800, 1200}}
+		for name, dim := range want {
+			img, err := jpeg.Decode(bytes.NewReader(cover.Images[name]))
+			require.NoError(t, err, name)
+			assert.Equal(t, dim[0], img.Bounds().Dx(), name)
+			assert.Equal(t, dim[1], img.Bounds().Dy(), name)
+		}
+	})
+
+	t.Run("Transparenz wird weiß", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 10, 10))))
+
+		cover, err := Process(buf.Bytes())
+		require.NoError(t, err)
+		img, err := jpeg.Decode(bytes.NewReader(cover.Images["small"]))
+		require.NoError(t, err)
+		r, g, b, _ := img.At(5, 5).RGBA()
+		assert.Greater(t, min(r, g, b), uint32(0xf000))
+	})
+
+	t.Run("nur JPEG und PNG", func(t *testing.T) {
+		_, err := Process([]byte("GIF89a......"))
+		assert.True(t, errors.Is(err, apperr.ErrUnsupportedMedia))
+
+		_, err = Process([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+		assert.True(t, errors.Is(err, apperr.ErrUnsupportedMedia))
+	})
+
+	t.Run("kaputtes Bild", func(t *testing.T) {
+		data := pngBytes(t, 10, 10)
+		_, err := Process(data[:40])
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+}
+
+func TestScale(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	// linke Hälfte schwarz, rechte weiß
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if x >= 2 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	dst := scale(src, 2, 1)
+
+	assert.Equal(t, color.RGBA{0, 0, 0, 0xff}, dst.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{0xff, 0xff, 0xff, 0xff}, dst.RGBAAt(1, 0))
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/config"
+	"bookbazaar-backend/internal/logging"
 	"bookbazaar-backend/internal/services"
 	"crypto/rand"
 	"encoding/base64"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -12,12 +17,19 @@ import (
 )
 
 type AuthController struct {
-	Service services.UserService
-	Secret  string
+	Service      services.UserService
+	Secret       string
+	CookieDomain string
+	CookieSecure bool
 }
 
-func NewAuthController(service services.UserService, secret string) *AuthController {
-	return &AuthController{Service: service, Secret: secret}
+func NewAuthController(service services.UserService, cfg config.AuthConfig) *AuthController {
+	return &AuthController{
+		Service:      service,
+		Secret:       cfg.JWTSecret,
+		CookieDomain: cfg.CookieDomain,
+		CookieSecure: cfg.CookieSecure,
+	}
 }
 
 type TokenResponse struct {
@@ -52,46 +64,46 @@ func generateRandomToken(length int) (string, error) {
 func (a *AuthController) Login(ctx *gin.Context) {
 	var req LoginRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungültige Daten"})
+		ctx.Error(invalidBody(err))
 		return
 	}
 
-	// user, err := a.Service.ValidateUser(req.Username, req.Password)
+	// user, err := a.Service.ValidateUser(ctx.Request.Context(), req.Username, req.Password)
 	// if err != nil {
 	// 	ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Benutzername oder Passwort falsch"})
 	// 	return
 	// }
 
-	user, err := a.Service.ValidateUserCredentials(req.Username, req.Password)
+	user, err := a.Service.ValidateUserCredentials(ctx.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Benutzername oder Passwort falsch"})
+		ctx.Error(err)
 		return
 	}
 
-	println(user)
+	slog.InfoContext(ctx.Request.Context(), "login erfolgreich", slog.Int(logging.KeyUserID, user.ID))
 
 	accessToken, err := a.createAccessToken(user.ID, user.Role)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Fehler beim Erstellen des Access-Tokens"})
+		ctx.Error(fmt.Errorf("fehler beim Erstellen des Access-Tokens: %w", err))
 		return
 	}
 
 	refreshToken, err := generateRandomToken(64)
 
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Fehler beim Erstellen des Refresh-Tokens"})
+		ctx.Error(fmt.Errorf("fehler beim Erstellen des Refresh-Tokens: %w", err))
 		return
 	}
 
-	err = a.Service.StoreRefreshToken(user.ID, refreshToken)
+	err = a.Service.StoreRefreshToken(ctx.Request.Context(), user.ID, refreshToken)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": "Fehler speichern des Refresh-Tokens"})
+		ctx.Error(fmt.Errorf("fehler beim Speichern des Refresh-Tokens: %w", err))
 		return
 	}
 
 	maxAge := 30 * 24 * 60 * 60
 
-	ctx.SetCookie("refresh_token", refreshToken, maxAge, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", refreshToken, maxAge, "/", a.CookieDomain, a.CookieSecure, true)
 
 	ctx.JSON(http.StatusOK, TokenResponse{
 		AcccessToken: accessToken,
@@ -110,28 +122,28 @@ func (a *AuthController) Refresh(ctx *gin.Context) {
 			RefreshToken string `json:"refresh_token"`
 		}
 		if err := ctx.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token fehlt"})
+			ctx.Error(apperr.Validation("missing_refresh_token", "Refresh token fehlt"))
 			return
 		}
 		token = req.RefreshToken
 	}
 
-	userID, err := a.Service.ValidateRefreshToken(token)
+	userID, err := a.Service.ValidateRefreshToken(ctx.Request.Context(), token)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh Token ungültig oder abgelaufen"})
+		ctx.Error(err)
 		return
 	}
 
 	// Rolle ggf. aus DB holen
-	user, err := a.Service.GetUserByUserId(userID)
+	user, err := a.Service.GetUserByUserId(ctx.Request.Context(), userID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "User nicht gefunden"})
+		ctx.Error(err)
 		return
 	}
 
 	accessToken, err := a.createAccessToken(user.ID, user.Role)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Access Token konnte nicht erstellt werden"})
+		ctx.Error(fmt.Errorf("access Token konnte nicht erstellt werden: %w", err))
 		return
 	}
 
@@ -141,17 +153,17 @@ func (a *AuthController) Refresh(ctx *gin.Context) {
 func (a *AuthController) Logout(ctx *gin.Context) {
 	refreshToken, err := ctx.Cookie("refresh_token")
 	if err != nil || refreshToken == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token fehlt"})
+		ctx.Error(apperr.Validation("missing_refresh_token", "Refresh token fehlt"))
 		return
 	}
 
-	if err := a.Service.RevokeRefreshToken(refreshToken); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Logout fehlgeschlagen"})
+	if err := a.Service.RevokeRefreshToken(ctx.Request.Context(), refreshToken); err != nil {
+		ctx.Error(fmt.Errorf("logout fehlgeschlagen: %w", err))
 		return
 	}
 
 	// Cookie löschen
-	ctx.SetCookie("refresh_token", "", -1, "/", "localhost", false, true)
+	ctx.SetCookie("refresh_token", "", -1, "/", a.CookieDomain, a.CookieSecure, true)
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Erfolgreich ausgeloggt"})
 }
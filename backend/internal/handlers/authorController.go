@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"bookbazaar-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthorListQuery sind die Query-Parameter von GET /api/authors.
+type AuthorListQuery struct {
+	Name   string `form:"name"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+// MergeAuthorsRequest ist der Body von POST /api/authors/:id/merge.
+type MergeAuthorsRequest struct {
+	DuplicateIDs []int `json:"duplicateIds"`
+}
+
+type AuthorController struct {
+	Service services.AuthorService
+}
+
+func NewAuthorController(s services.AuthorService) *AuthorController {
+	return &AuthorController{Service: s}
+}
+
+func (c *AuthorController) GetAuthors(ctx *gin.Context) {
+	var q AuthorListQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.Error(apperr.Validation("invalid_query", "Ungültige Query-Parameter").WithCause(err))
+		return
+	}
+
+	page, err := c.Service.ListAuthors(ctx.Request.Context(), repository.AuthorQuery(q))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, page)
+}
+
+// GetAuthor liefert den Autor mit seinen Büchern.
+func (c *AuthorController) GetAuthor(ctx *gin.Context) {
+	id, err := authorIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	author, err := c.Service.GetAuthor(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if anonymous(ctx) {
+		ctx.JSON(200, PublicAuthorDetail{Author: author.Author, Books: publicBooks(author.Books)})
+		return
+	}
+	ctx.JSON(200, author)
+}
+
+func (c *AuthorController) AddAuthor(ctx *gin.Context) {
+	var author models.Author
+	if err := ctx.ShouldBindJSON(&author); err != nil {
+		ctx.Error(invalidBody(err))
+		return
+	}
+
+	created, err := c.Service.CreateAuthor(ctx.Request.Context(), &author)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, created)
+}
+
+func (c *AuthorController) UpdateAuthor(ctx *gin.Context) {
+	id, err := authorIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	var author models.Author
+	if err := ctx.ShouldBindJSON(&author); err != nil {
+		ctx.Error(invalidBody(err))
+		return
+	}
+
+	updated, err := c.Service.UpdateAuthor(ctx.Request.Context(), id, author)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, updated)
+}
+
+func (c *AuthorController) DeleteAuthor(ctx *gin.Context) {
+	id, err := authorIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if err := c.Service.DeleteAuthor(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "Autor gelöscht"})
+}
+
+// MergeAuthors führt die Dubletten aus dem Body in den Autor :id zusammen.
+func (c *AuthorController) MergeAuthors(ctx *gin.Context) {
+	id, err := authorIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	var req MergeAuthorsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidBody(err))
+		return
+	}
+
+	author, err := c.Service.MergeAuthors(ctx.Request.Context(), id, req.DuplicateIDs)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, author)
+}
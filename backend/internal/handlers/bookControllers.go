@@ -1,15 +1,61 @@
 package handlers
 
 import (
+	"bookbazaar-backend/internal/apperr"
 	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
 	"bookbazaar-backend/internal/services"
-	"log"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// BuyBooksRequest ist der Body von POST /api/books/buyBooks.
+type BuyBooksRequest struct {
+	Purchases []services.Purchase `json:"purchases"`
+}
+
+// BorrowBookRequest ist der Body von POST /api/books/:id/borrowBook.
+type BorrowBookRequest struct {
+	Days int `json:"days"`
+}
+
+// BookListQuery sind die Query-Parameter von GET /api/books.
+type BookListQuery struct {
+	Genre      string   `form:"genre"`
+	Category   int      `form:"category"`
+	Author     string   `form:"author"`
+	MinPrice   *float64 `form:"minPrice"`
+	MaxPrice   *float64 `form:"maxPrice"`
+	InStock    bool     `form:"inStock"`
+	Borrowable bool     `form:"borrowable"`
+	Sort       string   `form:"sort"`
+	Limit      int      `form:"limit"`
+	Offset     int      `form:"offset"`
+	Cursor     string   `form:"cursor"`
+}
+
+// BookSearchQuery sind die Query-Parameter von GET /api/books/search.
+type BookSearchQuery struct {
+	Text   string `form:"q"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+// BookSuggestQuery sind die Query-Parameter von GET /api/books/suggest.
+type BookSuggestQuery struct {
+	Prefix string `form:"prefix"`
+	Limit  int    `form:"limit"`
+}
+
+func (q BookListQuery) query() repository.BookQuery {
+	return repository.BookQuery{
+		Genre: q.Genre, Category: q.Category, Author: q.Author, MinPrice: q.MinPrice, MaxPrice: q.MaxPrice,
+		InStock: q.InStock, Borrowable: q.Borrowable,
+		Sort: q.Sort, Limit: q.Limit, Offset: q.Offset, Cursor: q.Cursor,
+	}
+}
+
 type BookController struct {
 	Service services.BookService
 }
@@ -19,117 +65,268 @@ func NewBookController(s services.BookService) *BookController {
 }
 
 func (c *BookController) GetBooks(ctx *gin.Context) {
-	books, err := c.Service.GetAll()
+	var q BookListQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.Error(apperr.Validation("invalid_query", "Ungültige Query-Parameter").WithCause(err))
+		return
+	}
 
+	page, err := c.Service.ListBooks(ctx.Request.Context(), q.query())
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
-	ctx.JSON(200, books)
+	if anonymous(ctx) {
+		ctx.JSON(200, publicBookPage(page))
+		return
+	}
+	ctx.JSON(200, page)
+}
+
+// GetArchivedBooks listet archivierte Bücher (Admin) mit denselben Filtern.
+func (c *BookController) GetArchivedBooks(ctx *gin.Context) {
+	var q BookListQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.Error(apperr.Validation("invalid_query", "Ungültige Query-Parameter").WithCause(err))
+		return
+	}
+
+	query := q.query()
+	query.Archived = true
+	page, err := c.Service.ListBooks(ctx.Request.Context(), query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, page)
+}
+
+func (c *BookController) SearchBooks(ctx *gin.Context) {
+	var q BookSearchQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.Error(apperr.Validation("invalid_query", "Ungültige Query-Parameter").WithCause(err))
+		return
+	}
+
+	page, err := c.Service.SearchBooks(ctx.Request.Context(), repository.SearchQuery(q))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if anonymous(ctx) {
+		ctx.JSON(200, publicSearchPage(page))
+		return
+	}
+	ctx.JSON(200, page)
+}
+
+func (c *BookController) SuggestBooks(ctx *gin.Context) {
+	var q BookSuggestQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.Error(apperr.Validation("invalid_query", "Ungültige Query-Parameter").WithCause(err))
+		return
+	}
+
+	suggestions, err := c.Service.SuggestBooks(ctx.Request.Context(), q.Prefix, q.Limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	// Vorschläge ändern sich selten; kurzes Caching entlastet die Tipp-Anfragen
+	ctx.Header("Cache-Control", "private, max-age=60")
+	ctx.JSON(200, suggestions)
 }
 
 func (c *BookController) AddBooks(ctx *gin.Context) {
 	var book models.Book
-	if err := ctx.BindJSON(&book); err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	if err := ctx.ShouldBindJSON(&book); err != nil {
+		ctx.Error(invalidBody(err))
 		return
 	}
 
-	createdBook, err := c.Service.Create(&book)
+	createdBook, err := c.Service.Create(ctx.Request.Context(), &book)
 	if err != nil {
-		log.Println("Error aus Service:", err.Error())
-		if strings.Contains(err.Error(), "existiert bereits") {
-			ctx.JSON(409, gin.H{"message": err.Error()})
-			return
-		}
-		ctx.JSON(500, gin.H{"message": err.Error()})
+		ctx.Error(err)
 		return
 	}
+	setETag(ctx, createdBook)
 	ctx.JSON(200, createdBook)
 }
 
-func (c *BookController) DeleteBooks(ctx *gin.Context) {
-	idParam := ctx.Param("id")
+// UpdateBook ersetzt die Katalogfelder (PUT). Der Bestand bleibt unverändert.
+func (c *BookController) UpdateBook(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	var book models.Book
+	if err := ctx.ShouldBindJSON(&book); err != nil {
+		ctx.Error(invalidBody(err))
+		return
+	}
 
-	id, err := strconv.Atoi(idParam)
+	updated, err := c.Service.UpdateBook(ctx.Request.Context(), id, book, version)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	setETag(ctx, updated)
+	ctx.JSON(200, updated)
+}
+
+// PatchBook ändert nur die mitgeschickten Felder.
+func (c *BookController) PatchBook(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	var patch services.BookPatch
+	if err := ctx.ShouldBindJSON(&patch); err != nil {
+		ctx.Error(invalidBody(err))
+		return
+	}
 
+	updated, err := c.Service.PatchBook(ctx.Request.Context(), id, patch, version)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungültige ID"})
+		ctx.Error(err)
 		return
 	}
-	log.Println("Controller.Delete wurde aufgerufen")
-	err = c.Service.Delete(id)
+	setETag(ctx, updated)
+	ctx.JSON(200, updated)
+}
 
+func (c *BookController) DeleteBooks(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
 	if err != nil {
-		ctx.JSON(404, gin.H{"error": err.Error()})
+		ctx.Error(err)
+		return
+	}
+	if err := c.Service.Archive(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(200, gin.H{"message": "Buch erfolgreich gelöscht"})
+	ctx.JSON(200, gin.H{"message": "Buch archiviert"})
 }
 
-func (c *BookController) BuyBook(ctx *gin.Context) {
-	bookId, _ := strconv.Atoi(ctx.Param("id"))
-	log.Println(bookId)
+func (c *BookController) RestoreBook(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 
-	// userID wird in der Middleware gesetzt
-	userID, exists := ctx.Get("userId")
+	book, err := c.Service.Restore(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	setETag(ctx, book)
+	ctx.JSON(200, book)
+}
 
-	if !exists {
-		ctx.JSON(401, gin.H{"error": "UserID nicht gefunden"})
+// GetBook liefert die Detailseite eines Buchs mit Verfügbarkeit und dem
+// Stand des angemeldeten Users (Favorit, Warenkorb, ausgeliehen, gekauft).
+// Besucher ohne Token bekommen nur das PublicBook.
+func (c *BookController) GetBook(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if anonymous(ctx) {
+		detail, err := c.Service.GetBook(ctx.Request.Context(), id, 0)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		publicBook(ctx, &detail.Book)
+		return
+	}
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	err := c.Service.BuyBook(userID.(int), bookId)
+	detail, err := c.Service.GetBook(ctx.Request.Context(), id, user.ID)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
+	setETag(ctx, &detail.Book)
+	ctx.JSON(200, detail)
+}
 
-	ctx.JSON(200, gin.H{"message": "Buch erfolgreich gekauft"})
+// GetBookByISBN sucht ein Buch über ISBN-10 oder ISBN-13, mit oder ohne
+// Bindestriche.
+func (c *BookController) GetBookByISBN(ctx *gin.Context) {
+	book, err := c.Service.GetBookByISBN(ctx.Request.Context(), ctx.Param("isbn"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if anonymous(ctx) {
+		publicBook(ctx, book)
+		return
+	}
+	setETag(ctx, book)
+	ctx.JSON(200, book)
 }
 
-func (c *BookController) BuyBooks(ctx *gin.Context) {
+func (c *BookController) BuyBook(ctx *gin.Context) {
+	bookId, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 
-	userAny, exists := ctx.Get("user")
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 
-	if !exists {
-		ctx.JSON(400, gin.H{"error": "Nicht eingeloggt"})
+	if err := c.Service.BuyBook(ctx.Request.Context(), user.ID, bookId); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	user := userAny.(models.User)
+	ctx.JSON(200, gin.H{"message": "Buch erfolgreich gekauft"})
+}
 
-	var body struct {
-		Purchases []struct {
-			BookId   int `json:"bookId"`
-			Quantity int `json:"quantity"`
-		} `json:"purchases"`
+func (c *BookController) BuyBooks(ctx *gin.Context) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
 
+	var body BuyBooksRequest
 	if err := ctx.ShouldBindJSON(&body); err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungültige Daten"})
+		ctx.Error(invalidBody(err))
 		return
 	}
 
 	if len(body.Purchases) == 0 {
-		ctx.JSON(400, gin.H{"error": "Keine Buch-IDs übergeben"})
+		ctx.Error(apperr.Validation("empty_purchase", "Keine Buch-IDs übergeben"))
 		return
 	}
 
-	purchases := make([]services.Purchase, len(body.Purchases))
-
-	for i, p := range body.Purchases {
-		purchases[i] = services.Purchase{
-			BookId:   p.BookId,
-			Quantity: p.Quantity,
-		}
-	}
-
-	err := c.Service.BuyBooks(user.ID, purchases)
-
-	if err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	if err := c.Service.BuyBooks(ctx.Request.Context(), user.ID, body.Purchases); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -137,69 +334,60 @@ func (c *BookController) BuyBooks(ctx *gin.Context) {
 }
 
 func (c *BookController) BorrowBook(ctx *gin.Context) {
-	var req struct {
-		Days int `json:"days"`
-	}
-	if err := ctx.BindJSON(&req); err != nil || req.Days <= 0 {
-		ctx.JSON(400, gin.H{"error": "Ungültiger Body: days fehlt oder ist <= 0"})
+	var req BorrowBookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Days <= 0 {
+		ctx.Error(apperr.Validation("invalid_days", "Ungültiger Body: days fehlt oder ist <= 0"))
 		return
 	}
 
-	userAny, exists := ctx.Get("user")
-	if !exists {
-		ctx.JSON(401, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
-	user := userAny.(models.User)
 
-	bookIdStr := ctx.Param("id")
-	bookId, err := strconv.Atoi(bookIdStr)
+	bookId, err := bookIDParam(ctx)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungültige Buch-ID"})
+		ctx.Error(err)
 		return
 	}
 
-	err = c.Service.BorrowBook(user.ID, bookId, req.Days)
-	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+	if err := c.Service.BorrowBook(ctx.Request.Context(), user.ID, bookId, req.Days); err != nil {
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(200, gin.H{"success": true})
 }
 
 func (c *BookController) GetBorrowedBooks(ctx *gin.Context) {
-	userAny, exists := ctx.Get("user")
-	if !exists {
-		ctx.JSON(401, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
-	user := userAny.(models.User)
-	books, err := c.Service.GetBorrowedBooks(user.ID)
+	books, err := c.Service.GetBorrowedBooks(ctx.Request.Context(), user.ID)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(200, books)
 }
 
 func (c *BookController) GiveBorrowedBookBack(ctx *gin.Context) {
-	bookIdStr := ctx.Param("id")
-	bookId, err := strconv.Atoi(bookIdStr)
+	bookId, err := bookIDParam(ctx)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungültige Buch-ID"})
+		ctx.Error(err)
 		return
 	}
 
-	userAny, exists := ctx.Get("user")
-	if !exists {
-		ctx.JSON(401, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
-	user := userAny.(models.User)
 
-	err = c.Service.GiveBorrowedBookBack(user.ID, bookId)
-	if err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	if err := c.Service.GiveBorrowedBookBack(ctx.Request.Context(), user.ID, bookId); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -209,7 +397,7 @@ func (c *BookController) GiveBorrowedBookBack(ctx *gin.Context) {
 func (c *BookController) SetUser(ctx *gin.Context) {
 	userIdFloat, err := strconv.ParseFloat(ctx.Param("userId"), 64)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungültige UserID"})
+		ctx.Error(apperr.Validation("invalid_user_id", "Ungültige UserID"))
 		return
 	}
 
@@ -221,17 +409,15 @@ func (c *BookController) SetUser(ctx *gin.Context) {
 }
 
 func (c *BookController) GetCartBooks(ctx *gin.Context) {
-
-	userAny, exists := ctx.Get("user")
-	if !exists {
-		ctx.JSON(401, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
-	user := userAny.(models.User)
-
-	books, err := c.Service.GetCartBooks(user.ID)
 
+	books, err := c.Service.GetCartBooks(ctx.Request.Context(), user.ID)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -239,23 +425,20 @@ func (c *BookController) GetCartBooks(ctx *gin.Context) {
 }
 
 func (c *BookController) AddToCart(ctx *gin.Context) {
-	userAny, exists := ctx.Get("user")
-	if !exists {
-		ctx.JSON(401, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	bookIdStr := ctx.Param("id")
-	bookId, err := strconv.Atoi(bookIdStr)
+	bookId, err := bookIDParam(ctx)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungültige Buch-ID"})
+		ctx.Error(err)
 		return
 	}
 
-	user := userAny.(models.User)
-
-	if err := c.Service.AddToCart(user.ID, bookId); err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	if err := c.Service.AddToCart(ctx.Request.Context(), user.ID, bookId); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -263,24 +446,20 @@ func (c *BookController) AddToCart(ctx *gin.Context) {
 }
 
 func (c *BookController) RemoveFromCart(ctx *gin.Context) {
-	userAny, exists := ctx.Get("user")
-	if !exists {
-		ctx.JSON(400, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	user := userAny.(models.User)
-
-	bookIdStr := ctx.Param("id")
-	bookId, err := strconv.Atoi(bookIdStr)
-
+	bookId, err := bookIDParam(ctx)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungülitge Buch-ID"})
+		ctx.Error(err)
 		return
 	}
 
-	if err := c.Service.RemoveFromCart(user.ID, bookId); err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	if err := c.Service.RemoveFromCart(ctx.Request.Context(), user.ID, bookId); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -288,27 +467,20 @@ func (c *BookController) RemoveFromCart(ctx *gin.Context) {
 }
 
 func (c *BookController) AddToFavorites(ctx *gin.Context) {
-	userAny, exists := ctx.Get("user")
-	if !exists {
-		ctx.JSON(400, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	user := userAny.(models.User)
-
-	bookIdStr := ctx.Param("id")
-	bookId, err := strconv.Atoi(bookIdStr)
-
-	log.Println("userId: ", user.ID)
-	log.Println("bookId:", bookId)
-
+	bookId, err := bookIDParam(ctx)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungültige Buch-ID"})
+		ctx.Error(err)
 		return
 	}
 
-	if err := c.Service.AddToFavorites(user.ID, bookId); err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	if err := c.Service.AddToFavorites(ctx.Request.Context(), user.ID, bookId); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -316,18 +488,15 @@ func (c *BookController) AddToFavorites(ctx *gin.Context) {
 }
 
 func (c *BookController) GetFavoriteBooks(ctx *gin.Context) {
-	userAny, exists := ctx.Get("user")
-	if !exists {
-		ctx.JSON(400, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	user := userAny.(models.User)
-
-	favorites, err := c.Service.GetFavoriteBooks(user.ID)
-
+	favorites, err := c.Service.GetFavoriteBooks(ctx.Request.Context(), user.ID)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -335,27 +504,20 @@ func (c *BookController) GetFavoriteBooks(ctx *gin.Context) {
 }
 
 func (c *BookController) DeleteFavorite(ctx *gin.Context) {
-	userAny, exists := ctx.Get("user")
-
-	if !exists {
-		ctx.JSON(400, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	user := userAny.(models.User)
-
-	bookIdStr := ctx.Param("id")
-	bookId, err := strconv.Atoi(bookIdStr)
-
+	bookId, err := bookIDParam(ctx)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": "Ungültige Buch-ID"})
+		ctx.Error(err)
 		return
 	}
 
-	err = c.Service.DeleteFavorite(user.ID, bookId)
-
-	if err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	if err := c.Service.DeleteFavorite(ctx.Request.Context(), user.ID, bookId); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -363,19 +525,15 @@ func (c *BookController) DeleteFavorite(ctx *gin.Context) {
 }
 
 func (c *BookController) GetOrderedBooks(ctx *gin.Context) {
-	userAny, exists := ctx.Get("user")
-
-	if !exists {
-		ctx.JSON(400, gin.H{"error": "Nicht eingeloggt"})
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	user := userAny.(models.User)
-
-	orderedBooks, err := c.Service.GetOrderedBooks(user.ID)
-
+	orderedBooks, err := c.Service.GetOrderedBooks(ctx.Request.Context(), user.ID)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(200, orderedBooks)
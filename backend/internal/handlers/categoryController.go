@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryController struct {
+	Service services.CategoryService
+}
+
+func NewCategoryController(s services.CategoryService) *CategoryController {
+	return &CategoryController{Service: s}
+}
+
+// GetCategoryTree liefert den ganzen Baum mit Buchanzahl je Kategorie.
+func (c *CategoryController) GetCategoryTree(ctx *gin.Context) {
+	tree, err := c.Service.Tree(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, tree)
+}
+
+func (c *CategoryController) AddCategory(ctx *gin.Context) {
+	var category models.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		ctx.Error(invalidBody(err))
+		return
+	}
+
+	created, err := c.Service.CreateCategory(ctx.Request.Context(), &category)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, created)
+}
+
+func (c *CategoryController) UpdateCategory(ctx *gin.Context) {
+	id, err := categoryIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	var category models.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		ctx.Error(invalidBody(err))
+		return
+	}
+
+	updated, err := c.Service.UpdateCategory(ctx.Request.Context(), id, category)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, updated)
+}
+
+func (c *CategoryController) DeleteCategory(ctx *gin.Context) {
+	id, err := categoryIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if err := c.Service.DeleteCategory(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "Kategorie gelöscht"})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead ist der Spielraum für Boundary und Part-Header über der
+// eigentlichen Dateigröße.
+const multipartOverhead = 64 << 10
+
+// coverCacheControl: die URL enthält den Hash des Bildes, ein neues Cover
+// bekommt also eine neue URL.
+const coverCacheControl = "public, max-age=31536000, immutable"
+
+type CoverController struct {
+	Service  services.CoverService
+	MaxBytes int
+}
+
+func NewCoverController(s services.CoverService, maxBytes int) *CoverController {
+	return &CoverController{Service: s, MaxBytes: maxBytes}
+}
+
+// UploadCover nimmt ein Cover als multipart/form-data im Feld "cover" an.
+func (c *CoverController) UploadCover(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, int64(c.MaxBytes)+multipartOverhead)
+	file, header, err := ctx.Request.FormFile("cover")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.Error(apperr.TooLarge("cover_too_large", "Cover darf höchstens %d Bytes groß sein", c.MaxBytes))
+			return
+		}
+		ctx.Error(apperr.Validation("invalid_cover", "Feld 'cover' mit der Bilddatei fehlt").WithCause(err))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > int64(c.MaxBytes) {
+		ctx.Error(apperr.TooLarge("cover_too_large", "Cover darf höchstens %d Bytes groß sein", c.MaxBytes))
+		return
+	}
+	// der angegebene Typ ist nur eine Vorabprüfung, entscheidend ist der Inhalt
+	if ct := header.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") && ct != "application/octet-stream" {
+		ctx.Error(apperr.UnsupportedMedia("unsupported_cover_type", "Cover muss JPEG oder PNG sein, nicht %s", ct))
+		return
+	}
+
+	book, err := c.Service.SetCover(ctx.Request.Context(), id, file)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	setETag(ctx, book)
+	ctx.JSON(200, book)
+}
+
+// GetCover liefert eine Größe eines Covers, z.B. /api/covers/12/3fa4…/small.jpg.
+func (c *CoverController) GetCover(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	hash := ctx.Param("hash")
+	size, ok := strings.CutSuffix(ctx.Param("file"), ".jpg")
+	if !ok {
+		ctx.Error(apperr.NotFound("cover_not_found", "Cover gibt es nur als .jpg"))
+		return
+	}
+
+	r, info, err := c.Service.OpenCover(ctx.Request.Context(), id, hash, size)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer r.Close()
+
+	// erst nach dem Nachschlagen: ein gelöschtes oder ersetztes Cover ist 404,
+	// auch für Clients mit einer Kopie im Cache
+	etag := `"` + hash + "-" + size + `"`
+	if match := ctx.GetHeader("If-None-Match"); match == etag || match == "*" {
+		ctx.Header("ETag", etag)
+		ctx.Header("Cache-Control", coverCacheControl)
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.DataFromReader(200, info.Size, "image/jpeg", r, map[string]string{
+		"Cache-Control": coverCacheControl,
+		"ETag":          etag,
+		"Last-Modified": info.ModTime.UTC().Format(http.TimeFormat),
+	})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/catalogio"
+	"bookbazaar-backend/internal/services"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportExtensions sind die Dateiendungen für Content-Disposition.
+var exportExtensions = map[string]string{
+	catalogio.FormatCSV:       "csv",
+	catalogio.FormatJSONLines: "jsonl",
+	catalogio.FormatONIX:      "xml",
+}
+
+// ExportQuery sind die Query-Parameter von GET /api/books/export.
+type ExportQuery struct {
+	Format string `form:"format"`
+}
+
+type ExportController struct {
+	Service services.ExportService
+}
+
+func NewExportController(s services.ExportService) *ExportController {
+	return &ExportController{Service: s}
+}
+
+// ExportBooks streamt den Katalog direkt in die Antwort. Bricht der Export
+// ab, nachdem schon Daten gesendet wurden, lässt sich der Status nicht mehr
+// ändern; die Antwort endet dann ohne Abschluss des Formats (bei ONIX fehlt
+// z.B. </ONIXMessage>).
+func (c *ExportController) ExportBooks(ctx *gin.Context) {
+	q := ExportQuery{Format: catalogio.FormatCSV}
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.Error(apperr.Validation("invalid_query", "Ungültige Query-Parameter").WithCause(err))
+		return
+	}
+	ext, ok := exportExtensions[q.Format]
+	if !ok {
+		ctx.Error(apperr.Validation("invalid_export_format", "Unbekanntes Exportformat %q (erlaubt: csv, jsonl, onix)", q.Format))
+		return
+	}
+
+	ctx.Header("Content-Type", catalogio.ContentTypes[q.Format])
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="katalog-%s.%s"`, time.Now().Format("20060102"), ext))
+	ctx.Status(200)
+	if _, err := c.Service.Export(ctx.Request.Context(), ctx.Writer, q.Format); err != nil {
+		if !ctx.Writer.Written() {
+			ctx.Header("Content-Disposition", "")
+			ctx.Error(err)
+			return
+		}
+		slog.WarnContext(ctx.Request.Context(), "export nach teilantwort abgebrochen", slog.Any("error", err))
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"bookbazaar-backend/internal/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	Checker *health.Checker
+}
+
+func NewHealthController(checker *health.Checker) *HealthController {
+	return &HealthController{Checker: checker}
+}
+
+// Liveness sagt nur, dass der Prozess Requests annimmt. Keine Abhängigkeiten
+// prüfen, sonst startet der Orchestrator bei DB-Problemen alle Pods neu.
+func (c *HealthController) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Readiness prüft DB, Schema-Version und Pool-Auslastung.
+func (c *HealthController) Readiness(ctx *gin.Context) {
+	report := c.Checker.Run(ctx.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(status, report)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser liest den von der AuthMiddleware gesetzten User.
+func currentUser(ctx *gin.Context) (models.User, error) {
+	userAny, exists := ctx.Get("user")
+	if !exists {
+		return models.User{}, apperr.Unauthorized("not_authenticated", "Nicht eingeloggt")
+	}
+	return userAny.(models.User), nil
+}
+
+// bookIDParam liest die Buch-ID aus dem Pfadparameter :id.
+func bookIDParam(ctx *gin.Context) (int, error) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id < 1 {
+		return 0, apperr.Validation("invalid_book_id", "Ungültige Buch-ID")
+	}
+	return id, nil
+}
+
+// authorIDParam liest die Autor-ID aus dem Pfadparameter :id.
+func authorIDParam(ctx *gin.Context) (int, error) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id < 1 {
+		return 0, apperr.Validation("invalid_author_id", "Ungültige Autor-ID")
+	}
+	return id, nil
+}
+
+// categoryIDParam liest die Kategorie-ID aus dem Pfadparameter :id.
+func categoryIDParam(ctx *gin.Context) (int, error) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id < 1 {
+		return 0, apperr.Validation("invalid_category_id", "Ungültige Kategorie-ID")
+	}
+	return id, nil
+}
+
+// ifMatchVersion liest die erwartete Buchversion aus If-Match. "*" ergibt 0
+// (jede Version). Ohne If-Match wird die Änderung abgelehnt, damit zwei
+// Admins sich nicht unbemerkt gegenseitig überschreiben.
+func ifMatchVersion(ctx *gin.Context) (int, error) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	switch {
+	case header == "":
+		return 0, apperr.PreconditionRequired("if_match_required", "If-Match mit der ETag des Buchs ist erforderlich")
+	case header == "*":
+		return 0, nil
+	case strings.HasPrefix(header, "W/"):
+		// If-Match vergleicht stark, eine schwache ETag passt nie
+		return 0, apperr.PreconditionFailed("version_mismatch", "Schwache ETags werden bei If-Match nicht akzeptiert")
+	}
+
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version < 1 {
+		return 0, apperr.Validation("invalid_if_match", "Ungültiger If-Match-Header: %s", header)
+	}
+	return version, nil
+}
+
+// setETag gibt die Version eines Buchs als ETag aus.
+func setETag(ctx *gin.Context, book *models.Book) {
+	ctx.Header("ETag", `"`+strconv.Itoa(book.Version)+`"`)
+}
+
+// invalidBody meldet einen nicht lesbaren Request-Body.
+func invalidBody(err error) error {
+	return apperr.Validation("invalid_body", "Ungültige Daten").WithCause(err)
+}
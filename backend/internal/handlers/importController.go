@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/catalogio"
+	"bookbazaar-backend/internal/services"
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportQuery sind die Query-Parameter von POST /api/books/import. Ohne
+// format entscheidet der Content-Type, ohne chunkSize die Konfiguration.
+type ImportQuery struct {
+	DryRun    bool   `form:"dryRun"`
+	ChunkSize *int   `form:"chunkSize"`
+	Format    string `form:"format"`
+}
+
+type ImportController struct {
+	Service   services.ImportService
+	MaxBytes  int
+	ChunkSize int
+}
+
+func NewImportController(s services.ImportService, maxBytes, chunkSize int) *ImportController {
+	return &ImportController{Service: s, MaxBytes: maxBytes, ChunkSize: chunkSize}
+}
+
+// ImportBooks nimmt die Datei direkt als Body (text/csv oder application/json).
+// Auch ein Import mit fehlerhaften Zeilen antwortet mit 200 und dem Bericht.
+func (c *ImportController) ImportBooks(ctx *gin.Context) {
+	var q ImportQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.Error(apperr.Validation("invalid_query", "Ungültige Query-Parameter").WithCause(err))
+		return
+	}
+	format := q.Format
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = catalogio.FormatCSV
+		case "application/json":
+			format = catalogio.FormatJSON
+		default:
+			ctx.Error(apperr.UnsupportedMedia("unsupported_import_type", "Import erwartet text/csv oder application/json, nicht %q", mediaType))
+			return
+		}
+	}
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	opts := services.ImportOptions{DryRun: q.DryRun, ChunkSize: c.ChunkSize, UserID: user.ID}
+	if q.ChunkSize != nil {
+		opts.ChunkSize = *q.ChunkSize
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, int64(c.MaxBytes)))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.Error(apperr.TooLarge("import_too_large", "Importdatei darf höchstens %d Bytes groß sein", c.MaxBytes))
+			return
+		}
+		ctx.Error(invalidBody(err))
+		return
+	}
+	rows, err := catalogio.Read(bytes.NewReader(data), format)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	report, err := c.Service.Import(ctx.Request.Context(), rows, opts)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, report)
+}
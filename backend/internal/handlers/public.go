@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Antworten für Besucher ohne Token (middleware.OptionalAuth): dieselben
+// Listen, aber mit models.PublicBook statt models.Book.
+
+// PublicBookPage ist repository.BookPage ohne Bestand und Verwaltungsfelder.
+type PublicBookPage struct {
+	Items      []models.PublicBook `json:"items"`
+	Total      int                 `json:"total"`
+	Limit      int                 `json:"limit"`
+	Offset     int                 `json:"offset"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
+
+type PublicBookHit struct {
+	models.PublicBook
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type PublicSearchPage struct {
+	Items  []PublicBookHit `json:"items"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+type PublicAuthorDetail struct {
+	models.Author
+	Books []models.PublicBook `json:"books"`
+}
+
+// anonymous meldet, dass OptionalAuth den Request ohne Token durchgelassen hat.
+func anonymous(ctx *gin.Context) bool {
+	_, ok := ctx.Get("user")
+	return !ok
+}
+
+func publicBooks(books []models.Book) []models.PublicBook {
+	out := make([]models.PublicBook, len(books))
+	for i := range books {
+		out[i] = books[i].Public()
+	}
+	return out
+}
+
+func publicBookPage(page repository.BookPage) PublicBookPage {
+	return PublicBookPage{Items: publicBooks(page.Items), Total: page.Total, Limit: page.Limit, Offset: page.Offset, NextCursor: page.NextCursor}
+}
+
+func publicSearchPage(page repository.SearchPage) PublicSearchPage {
+	hits := make([]PublicBookHit, len(page.Items))
+	for i := range page.Items {
+		hits[i] = PublicBookHit{PublicBook: page.Items[i].Public(), Rank: page.Items[i].Rank, Snippet: page.Items[i].Snippet}
+	}
+	return PublicSearchPage{Items: hits, Total: page.Total, Limit: page.Limit, Offset: page.Offset}
+}
+
+// publicBook gibt ein einzelnes Buch für Besucher aus. Archivierte Bücher
+// werden nicht mehr angeboten und sind für sie nicht zu finden.
+func publicBook(ctx *gin.Context, book *models.Book) {
+	if book.ArchivedAt != nil {
+		ctx.Error(apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", book.ID))
+		return
+	}
+	ctx.JSON(200, book.Public())
+}
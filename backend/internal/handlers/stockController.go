@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/repository"
+	"bookbazaar-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MovementListQuery sind die Query-Parameter von GET /api/books/:id/stockMovements.
+type MovementListQuery struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+// StockAdjustmentRequest ist der Body von POST /api/books/:id/stock: entweder
+// ein neuer Bestand (quantity) oder eine Änderung (delta), mit Begründung.
+type StockAdjustmentRequest struct {
+	Quantity *int   `json:"quantity"`
+	Delta    *int   `json:"delta"`
+	Reason   string `json:"reason"`
+}
+
+// ReorderThresholdRequest ist der Body von PUT /api/books/:id/reorderThreshold;
+// threshold null schaltet die Meldung ab.
+type ReorderThresholdRequest struct {
+	Threshold *int `json:"threshold"`
+}
+
+// LowStockQuery sind die Query-Parameter von GET /api/stock/low.
+type LowStockQuery struct {
+	Days int `form:"days"`
+}
+
+type StockController struct {
+	Service services.StockService
+}
+
+func NewStockController(s services.StockService) *StockController {
+	return &StockController{Service: s}
+}
+
+// GetStockMovements liefert das Bestandsjournal eines Buchs, neueste zuerst.
+func (c *StockController) GetStockMovements(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	var q MovementListQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.Error(apperr.Validation("invalid_query", "Ungültige Query-Parameter").WithCause(err))
+		return
+	}
+
+	page, err := c.Service.ListMovements(ctx.Request.Context(), repository.MovementQuery{BookID: id, Limit: q.Limit, Offset: q.Offset})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, page)
+}
+
+// ReconcileStock vergleicht den Bestand aller Bücher mit dem Journal. Auch
+// bei Abweichungen kommt 200; ok im Body sagt, ob alles stimmt.
+func (c *StockController) ReconcileStock(ctx *gin.Context) {
+	report, err := c.Service.Reconcile(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, report)
+}
+
+func (c *StockController) GetStockLevel(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	level, err := c.Service.GetStockLevel(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, level)
+}
+
+// AdjustStock bucht eine Bestandskorrektur des angemeldeten Admins.
+func (c *StockController) AdjustStock(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	var req StockAdjustmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidBody(err))
+		return
+	}
+	user, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	level, err := c.Service.AdjustStock(ctx.Request.Context(), repository.StockAdjustment{
+		BookID: id, UserID: user.ID, Quantity: req.Quantity, Delta: req.Delta, Note: req.Reason,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, level)
+}
+
+func (c *StockController) SetReorderThreshold(ctx *gin.Context) {
+	id, err := bookIDParam(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	var req ReorderThresholdRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidBody(err))
+		return
+	}
+
+	level, err := c.Service.SetReorderThreshold(ctx.Request.Context(), id, req.Threshold)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, level)
+}
+
+// GetLowStock listet Bücher am oder unter ihrem Meldebestand, knappste zuerst.
+func (c *StockController) GetLowStock(ctx *gin.Context) {
+	var q LowStockQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.Error(apperr.Validation("invalid_query", "Ungültige Query-Parameter").WithCause(err))
+		return
+	}
+
+	report, err := c.Service.LowStock(ctx.Request.Context(), q.Days)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(200, report)
+}
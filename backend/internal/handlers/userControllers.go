@@ -3,14 +3,10 @@ package handlers
 import (
 	"bookbazaar-backend/internal/models"
 	"bookbazaar-backend/internal/services"
-	"log"
 
 	"github.com/gin-gonic/gin"
 )
 
-type BuyedBookRequest struct {
-}
-
 type UserController struct {
 	Service services.UserService
 }
@@ -20,49 +16,48 @@ func NewUserController(s services.UserService) *UserController {
 }
 
 func (c *UserController) GetUsers(ctx *gin.Context) {
-	log.Println("Controller.GetUsers wurde aufgerufen")
-	users, err := c.Service.GetAllUsers()
+	users, err := c.Service.GetAllUsers(ctx.Request.Context())
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
+	for i := range users {
+		users[i].Password = ""
+	}
 	ctx.JSON(200, users)
 }
 
 func (c *UserController) AddUser(ctx *gin.Context) {
-	log.Println("Controller.AddUser wurde aufgerufen")
-
 	var user models.User
-	if err := ctx.BindJSON(&user); err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
-		log.Println("Controller error")
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		ctx.Error(invalidBody(err))
 		return
 	}
 
-	createdUser, err := c.Service.AddUser(&user)
+	createdUser, err := c.Service.AddUser(ctx.Request.Context(), &user)
 
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
+	createdUser.Password = "" // laut Spec writeOnly
 	ctx.JSON(200, createdUser)
 }
 
 func (c *UserController) GetUserByUserId(ctx *gin.Context) {
-	userAny, exists := ctx.Get("user")
-	if !exists {
-		ctx.JSON(401, gin.H{"error": "Nicht eingeloggt"})
+	current, err := currentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	userID := userAny.(models.User).ID
-
-	user, err := c.Service.GetUserByUserId(userID)
+	user, err := c.Service.GetUserByUserId(ctx.Request.Context(), current.ID)
 
 	if err != nil {
-		ctx.JSON(400, "Ungültiger User")
+		ctx.Error(err)
 		return
 	}
+	user.Password = ""
 	ctx.JSON(200, user)
 }
@@ -0,0 +1,131 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// CheckFunc prüft eine Abhängigkeit. nil bedeutet gesund.
+type CheckFunc func(ctx context.Context) error
+
+type Result struct {
+	Status    Status  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type namedCheck struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker führt alle registrierten Checks parallel mit gemeinsamem Timeout aus.
+type Checker struct {
+	timeout      time.Duration
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, fn: fn})
+}
+
+// MarkShuttingDown lässt die Readiness sofort fehlschlagen, damit der
+// Loadbalancer keinen neuen Traffic mehr schickt, während wir noch abarbeiten.
+func (c *Checker) MarkShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks)+1)}
+	if c.shuttingDown.Load() {
+		report.Status = StatusFail
+		report.Checks["shutdown"] = Result{Status: StatusFail, Error: "server fährt herunter"}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check namedCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := runCheck(ctx, check.fn)
+			res := Result{Status: StatusOK, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				res.Status = StatusFail
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.name] = res
+			if err != nil {
+				report.Status = StatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+// runCheck sorgt dafür, dass ein hängender Check spätestens beim Timeout als Fehler zählt.
+func runCheck(ctx context.Context, fn CheckFunc) error {
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timeout: %w", ctx.Err())
+	}
+}
+
+// DBPing prüft, ob die Datenbank antwortet.
+func DBPing(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// PoolSaturation schlägt fehl, wenn mindestens threshold (0..1) der maximal
+// erlaubten Verbindungen belegt sind und bereits Anfragen auf eine Verbindung warten.
+func PoolSaturation(db *sql.DB, threshold float64) CheckFunc {
+	var lastWaitCount atomic.Int64
+	return func(ctx context.Context) error {
+		stats := db.Stats()
+		waited := stats.WaitCount - lastWaitCount.Swap(stats.WaitCount)
+		if stats.MaxOpenConnections == 0 {
+			return nil
+		}
+		usage := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		if usage >= threshold && waited > 0 {
+			return fmt.Errorf("pool ausgelastet: %d/%d verbindungen belegt, %d wartende anfragen seit letztem check",
+				stats.InUse, stats.MaxOpenConnections, waited)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	t.Run("alle Checks ok", func(t *testing.T) {
+		c := NewChecker(time.Second)
+		c.Add("db", func(ctx context.Context) error { return nil })
+
+		report := c.Run(context.Background())
+
+		assert.Equal(t, StatusOK, report.Status)
+		assert.Equal(t, StatusOK, report.Checks["db"].Status)
+	})
+
+	t.Run("ein fehlgeschlagener Check macht den Report rot", func(t *testing.T) {
+		c := NewChecker(time.Second)
+		c.Add("db", func(ctx context.Context) error { return nil })
+		c.Add("schema", func(ctx context.Context) error { return errors.New("version 1, erwartet 2") })
+
+		report := c.Run(context.Background())
+
+		assert.Equal(t, StatusFail, report.Status)
+		assert.Equal(t, StatusOK, report.Checks["db"].Status)
+		assert.Equal(t, "version 1, erwartet 2", report.Checks["schema"].Error)
+	})
+
+	t.Run("hängender Check läuft in den Timeout", func(t *testing.T) {
+		c := NewChecker(20 * time.Millisecond)
+		c.Add("db", func(ctx context.Context) error {
+			time.Sleep(time.Second) // ignoriert ctx absichtlich
+			return nil
+		})
+
+		start := time.Now()
+		report := c.Run(context.Background())
+
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, StatusFail, report.Checks["db"].Status)
+		assert.Contains(t, report.Checks["db"].Error, "timeout")
+	})
+
+	t.Run("beim Herunterfahren nicht mehr bereit", func(t *testing.T) {
+		c := NewChecker(time.Second)
+		c.MarkShuttingDown()
+
+		report := c.Run(context.Background())
+
+		assert.Equal(t, StatusFail, report.Status)
+		assert.Equal(t, StatusFail, report.Checks["shutdown"].Status)
+	})
+}
@@ -0,0 +1,99 @@
+// Package isbn prüft ISBN-10 und ISBN-13 (Prüfziffer) und rechnet zwischen
+// beiden Formen um. Gespeichert wird immer die ISBN-13 ohne Bindestriche.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("ungültige ISBN")
+
+// clean entfernt Bindestriche und Leerzeichen und macht ein "x" groß.
+func clean(s string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s)))
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDigit10 berechnet die Prüfziffer aus den ersten neun Stellen ("X" für 10).
+func checkDigit10(first9 string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(first9[i]-'0') * (10 - i)
+	}
+	d := (11 - sum%11) % 11
+	if d == 10 {
+		return 'X'
+	}
+	return byte('0' + d)
+}
+
+// checkDigit13 berechnet die Prüfziffer aus den ersten zwölf Stellen.
+func checkDigit13(first12 string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		w := 1
+		if i%2 == 1 {
+			w = 3
+		}
+		sum += int(first12[i]-'0') * w
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Valid10 meldet, ob s eine gültige ISBN-10 ist (Bindestriche erlaubt).
+func Valid10(s string) bool {
+	s = clean(s)
+	return len(s) == 10 && digits(s[:9]) && (digits(s[9:]) || s[9] == 'X') && checkDigit10(s[:9]) == s[9]
+}
+
+// Valid13 meldet, ob s eine gültige ISBN-13 mit Präfix 978 oder 979 ist.
+func Valid13(s string) bool {
+	s = clean(s)
+	return len(s) == 13 && digits(s) && (strings.HasPrefix(s, "978") || strings.HasPrefix(s, "979")) &&
+		checkDigit13(s[:12]) == s[12]
+}
+
+// To13 wandelt eine ISBN-10 in die ISBN-13 (Präfix 978) um.
+func To13(isbn10 string) (string, error) {
+	s := clean(isbn10)
+	if !Valid10(s) {
+		return "", ErrInvalid
+	}
+	first12 := "978" + s[:9]
+	return first12 + string(checkDigit13(first12)), nil
+}
+
+// To10 wandelt eine ISBN-13 in die ISBN-10 um. ISBNs mit Präfix 979 haben
+// keine ISBN-10; dann ist ok false.
+func To10(isbn13 string) (isbn10 string, ok bool) {
+	s := clean(isbn13)
+	if !Valid13(s) || !strings.HasPrefix(s, "978") {
+		return "", false
+	}
+	first9 := s[3:12]
+	return first9 + string(checkDigit10(first9)), true
+}
+
+// Normalize nimmt eine ISBN-10 oder ISBN-13 in beliebiger Schreibweise und
+// liefert die ISBN-13 ohne Bindestriche.
+func Normalize(s string) (string, error) {
+	c := clean(s)
+	switch len(c) {
+	case 10:
+		return To13(c)
+	case 13:
+		if Valid13(c) {
+			return c, nil
+		}
+	}
+	return "", ErrInvalid
+}
@@ -0,0 +1,51 @@
+package isbn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"ISBN-13 mit Bindestrichen", "978-3-608-93828-9", "9783608938289"},
+		{"ISBN-10", "3-608-93828-1", "9783608938289"},
+		{"ISBN-10 mit X", "0-8044-2957-X", "9780804429573"},
+		{"kleines x", "080442957x", "9780804429573"},
+		{"Präfix 979", "979-10-90636-07-1", "9791090636071"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Normalize(tc.in)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	for _, in := range []string{"", "978-3-608-93828-0", "3-608-93828-4", "123", "1234567890123", "97836089382X9"} {
+		t.Run("ungültig "+in, func(t *testing.T) {
+			_, err := Normalize(in)
+
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestTo10(t *testing.T) {
+	t.Run("Präfix 978", func(t *testing.T) {
+		got, ok := To10("9780804429573")
+
+		assert.True(t, ok)
+		assert.Equal(t, "080442957X", got)
+	})
+
+	t.Run("Präfix 979 hat keine ISBN-10", func(t *testing.T) {
+		_, ok := To10("9791090636071")
+
+		assert.False(t, ok)
+	})
+}
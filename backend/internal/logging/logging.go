@@ -0,0 +1,114 @@
+// Package logging stellt den slog-Logger für das Backend bereit. Request-
+// bezogene Felder (Request-ID, User-ID, Route) hängen am context.Context und
+// werden von jedem Log-Aufruf mit *Context-Variante automatisch mitgeschrieben,
+// egal ob er aus Handler, Service oder Repository kommt.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Formate für Config.Format
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Attributnamen, die Middleware und Aufrufer einheitlich verwenden.
+const (
+	KeyRequestID = "request_id"
+	KeyUserID    = "user_id"
+	KeyRoute     = "route"
+)
+
+// redacted ersetzt den Wert sensibler Attribute.
+const redacted = "[REDACTED]"
+
+// sensitiveKeys werden unabhängig von Groß-/Kleinschreibung als Teilstring
+// gesucht, so dass z.B. auch "refresh_token" oder "newPassword" greifen.
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "cookie", "email"}
+
+// New baut einen Logger, der nach w schreibt. format ist "json" oder "text",
+// level einer von "debug", "info", "warn", "error".
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("log-level %q unbekannt (erlaubt: debug, info, warn, error)", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("log-format %q unbekannt (erlaubt: %s, %s)", format, FormatJSON, FormatText)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+// redact maskiert Passwörter, Tokens und E-Mail-Adressen, bevor sie den
+// Prozess verlassen.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	return a
+}
+
+type ctxKey struct{}
+
+// With liefert einen Kontext, dessen Logs zusätzlich attrs enthalten.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	merged = append(merged, prev...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+// Attrs liefert die mit With gesetzten Attribute.
+func Attrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+// RequestID liefert die Request-ID aus dem Kontext oder "".
+func RequestID(ctx context.Context) string {
+	for _, a := range Attrs(ctx) {
+		if a.Key == KeyRequestID {
+			return a.Value.String()
+		}
+	}
+	return ""
+}
+
+// contextHandler ergänzt jeden Eintrag um die Attribute aus dem Kontext.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		r.AddAttrs(Attrs(ctx)...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
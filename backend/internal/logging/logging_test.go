@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bookbazaar-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]any {
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	return entry
+}
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "info")
+	require.NoError(t, err)
+
+	logger.Info("login",
+		slog.String("password", "geheim123"),
+		slog.String("refresh_token", "abc"),
+		slog.String("Authorization", "Bearer xyz"),
+		slog.String("email", "m@example.com"),
+		slog.String("username", "malek1"),
+	)
+
+	entry := decode(t, &buf)
+	assert.Equal(t, "[REDACTED]", entry["password"])
+	assert.Equal(t, "[REDACTED]", entry["refresh_token"])
+	assert.Equal(t, "[REDACTED]", entry["Authorization"])
+	assert.Equal(t, "[REDACTED]", entry["email"])
+	assert.Equal(t, "malek1", entry["username"])
+	assert.NotContains(t, buf.String(), "geheim123")
+}
+
+func TestUserLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "info")
+	require.NoError(t, err)
+
+	logger.Info("user", slog.Any("user", models.User{ID: 3, Username: "malek1", Password: "geheim123", Email: "m@example.com"}))
+
+	assert.NotContains(t, buf.String(), "geheim123")
+	assert.NotContains(t, buf.String(), "m@example.com")
+	assert.Contains(t, buf.String(), "malek1")
+}
+
+func TestContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "debug")
+	require.NoError(t, err)
+
+	ctx := With(context.Background(), slog.String(KeyRequestID, "req-1"), slog.String(KeyRoute, "/api/books/buyBooks"))
+	ctx = With(ctx, slog.Int(KeyUserID, 42))
+	logger.DebugContext(ctx, "kauf fehlgeschlagen", slog.Int("book_id", 7))
+
+	entry := decode(t, &buf)
+	assert.Equal(t, "req-1", entry[KeyRequestID])
+	assert.Equal(t, "/api/books/buyBooks", entry[KeyRoute])
+	assert.Equal(t, float64(42), entry[KeyUserID])
+	assert.Equal(t, float64(7), entry["book_id"])
+	assert.Equal(t, "req-1", RequestID(ctx))
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Level filtert", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := New(&buf, FormatText, "warn")
+		require.NoError(t, err)
+
+		logger.Info("unsichtbar")
+		logger.Warn("sichtbar")
+
+		assert.NotContains(t, buf.String(), "unsichtbar")
+		assert.Contains(t, buf.String(), "sichtbar")
+	})
+
+	t.Run("unbekanntes Format", func(t *testing.T) {
+		_, err := New(&bytes.Buffer{}, "xml", "info")
+		assert.Error(t, err)
+	})
+
+	t.Run("unbekanntes Level", func(t *testing.T) {
+		_, err := New(&bytes.Buffer{}, FormatJSON, "laut")
+		assert.Error(t, err)
+	})
+}
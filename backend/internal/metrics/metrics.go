@@ -0,0 +1,157 @@
+// Package metrics sammelt die Prometheus-Metriken des Backends: HTTP-Latenzen
+// pro Route, Zustand des DB-Pools und Business-Ereignisse (Käufe, Ausleihen,
+// Warenkorb, Logins). Alle Methoden sind auf einem nil-*Metrics ein No-op, so
+// dass Services in Tests ohne Metriken gebaut werden können.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bookbazaar"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpDuration *prometheus.HistogramVec
+
+	booksSold        prometheus.Counter
+	revenue          prometheus.Counter
+	loansCreated     prometheus.Counter
+	loansReturned    prometheus.Counter
+	cartAdds         prometheus.Counter
+	cartExpirations  prometheus.Counter
+	failedLogins     prometheus.Counter
+	refreshTokenUses prometheus.Counter
+}
+
+// New legt eine eigene Registry an (statt der globalen), damit Tests und
+// mehrere Router sich nicht gegenseitig stören.
+func New() *Metrics {
+	counter := func(name, help string) prometheus.Counter {
+		return prometheus.NewCounter(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help})
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Dauer der HTTP-Requests nach Methode, gin-Route und Status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		booksSold:        counter("books_sold_total", "Verkaufte Bücher (Stückzahl) über BuyBook und BuyBooks."),
+		revenue:          counter("revenue_euros_total", "Umsatz aus Buchverkäufen in Euro."),
+		loansCreated:     counter("loans_created_total", "Neu angelegte Ausleihen."),
+		loansReturned:    counter("loans_returned_total", "Zurückgegebene Ausleihen."),
+		cartAdds:         counter("cart_adds_total", "In den Warenkorb gelegte Bücher."),
+		cartExpirations:  counter("cart_expirations_total", "Abgelaufene Warenkorb-Reservierungen."),
+		failedLogins:     counter("failed_logins_total", "Fehlgeschlagene Logins."),
+		refreshTokenUses: counter("refresh_token_uses_total", "Erfolgreich eingelöste Refresh-Tokens."),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.booksSold,
+		m.revenue,
+		m.loansCreated,
+		m.loansReturned,
+		m.cartAdds,
+		m.cartExpirations,
+		m.failedLogins,
+		m.refreshTokenUses,
+	)
+	return m
+}
+
+// RegisterDB exportiert die database/sql-Poolstatistik (offene, belegte und
+// wartende Verbindungen).
+func (m *Metrics) RegisterDB(db *sql.DB) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Handler liefert den /metrics-Endpunkt.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware misst jeden Request. Als Label dient die gin-Route (z.B.
+// /api/books/:id), nicht der konkrete Pfad, damit die Kardinalität begrenzt bleibt.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.httpDuration.
+			WithLabelValues(ctx.Request.Method, route, strconv.Itoa(ctx.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// BooksSold zählt einen erfolgreichen Kauf von quantity Büchern zum Gesamtpreis total.
+func (m *Metrics) BooksSold(quantity int, total float64) {
+	if m == nil {
+		return
+	}
+	m.booksSold.Add(float64(quantity))
+	m.revenue.Add(total)
+}
+
+func (m *Metrics) LoanCreated() {
+	if m == nil {
+		return
+	}
+	m.loansCreated.Inc()
+}
+
+func (m *Metrics) LoanReturned() {
+	if m == nil {
+		return
+	}
+	m.loansReturned.Inc()
+}
+
+func (m *Metrics) CartAdded() {
+	if m == nil {
+		return
+	}
+	m.cartAdds.Inc()
+}
+
+func (m *Metrics) CartExpired(n int64) {
+	if m == nil {
+		return
+	}
+	m.cartExpirations.Add(float64(n))
+}
+
+func (m *Metrics) LoginFailed() {
+	if m == nil {
+		return
+	}
+	m.failedLogins.Inc()
+}
+
+func (m *Metrics) RefreshTokenUsed() {
+	if m == nil {
+		return
+	}
+	m.refreshTokenUses.Inc()
+}
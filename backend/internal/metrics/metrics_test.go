@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	resp := httptest.NewRecorder()
+	m.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	return resp.Body.String()
+}
+
+func TestMiddlewareUsesRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+
+	r := gin.New()
+	r.Use(m.Middleware())
+	r.GET("/api/books/:id", func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/api/books/1", "/api/books/2", "/nirgendwo"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `bookbazaar_http_request_duration_seconds_count{method="GET",route="/api/books/:id",status="204"} 2`)
+	assert.Contains(t, body, `bookbazaar_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, body, `route="/api/books/1"`)
+}
+
+func TestBusinessCounters(t *testing.T) {
+	m := New()
+
+	m.BooksSold(3, 29.97)
+	m.BooksSold(1, 10)
+	m.LoanCreated()
+	m.CartExpired(4)
+	m.LoginFailed()
+
+	body := scrape(t, m)
+	assert.Contains(t, body, "bookbazaar_books_sold_total 4")
+	assert.Contains(t, body, "bookbazaar_revenue_euros_total 39.97")
+	assert.Contains(t, body, "bookbazaar_loans_created_total 1")
+	assert.Contains(t, body, "bookbazaar_cart_expirations_total 4")
+	assert.Contains(t, body, "bookbazaar_failed_logins_total 1")
+}
+
+func TestNilMetricsIsNoop(t *testing.T) {
+	var m *Metrics
+
+	assert.NotPanics(t, func() {
+		m.BooksSold(1, 9.99)
+		m.LoanReturned()
+		m.RefreshTokenUsed()
+	})
+}
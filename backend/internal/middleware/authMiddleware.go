@@ -1,7 +1,12 @@
 package middleware
 
 import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/logging"
 	"bookbazaar-backend/internal/models"
+	"log/slog"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -13,39 +18,74 @@ func AuthMiddleware(secret string) gin.HandlerFunc {
 		// Also die Middleware wird beim Login aufgerufen und extrahiert die userId aus dem header
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
-			ctx.AbortWithStatusJSON(401, gin.H{"error": "Authorization Header fehlt"})
+			WriteProblem(ctx, apperr.Unauthorized("missing_token", "Authorization Header fehlt"))
 			return
 		}
+		if authenticate(ctx, secret, authHeader) {
+			ctx.Next()
+		}
+	}
+}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			ctx.AbortWithStatusJSON(401, gin.H{"error": "Token abgelaufen"})
+// OptionalAuth lässt Anfragen ohne Authorization-Header als anonym durch,
+// begrenzt durch limiter je Client-IP; Handler erkennen sie daran, dass kein
+// user gesetzt ist. Ein mitgeschicktes, aber ungültiges Token gibt wie bei
+// AuthMiddleware 401, damit der Client es erneuert statt still weniger zu
+// sehen. Ohne limiter ist kein anonymer Zugriff erlaubt.
+func OptionalAuth(secret string, limiter *RateLimiter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authHeader := ctx.GetHeader("Authorization")
+		if authHeader != "" {
+			if authenticate(ctx, secret, authHeader) {
+				ctx.Next()
+			}
 			return
 		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			ctx.AbortWithStatusJSON(401, gin.H{"error": "Token ungültig"})
+		if limiter == nil {
+			WriteProblem(ctx, apperr.Unauthorized("missing_token", "Authorization Header fehlt"))
 			return
 		}
-
-		userIdFloat, ok := claims["userId"].(float64)
-		if !ok {
-			ctx.AbortWithStatusJSON(401, gin.H{"error": "UserId fehlt"})
+		if ok, wait := limiter.Allow(ctx.ClientIP()); !ok {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			WriteProblem(ctx, apperr.TooManyRequests("rate_limited", "Zu viele Anfragen ohne Anmeldung, bitte kurz warten oder einloggen"))
 			return
 		}
+		ctx.Next()
+	}
+}
 
-		role, ok := claims["role"].(string)
-		if !ok {
-			role = "user"
-		}
+// authenticate prüft das Bearer-Token und setzt den User. Bei einem Fehler
+// ist die Antwort schon geschrieben und der Request abgebrochen.
+func authenticate(ctx *gin.Context, secret, authHeader string) bool {
+	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
 
-		ctx.Set("user", models.User{ID: int(userIdFloat), Role: role})
-		ctx.Next()
+	if err != nil || !token.Valid {
+		WriteProblem(ctx, apperr.Unauthorized("token_expired", "Token abgelaufen"))
+		return false
 	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		WriteProblem(ctx, apperr.Unauthorized("invalid_token", "Token ungültig"))
+		return false
+	}
+
+	userIdFloat, ok := claims["userId"].(float64)
+	if !ok {
+		WriteProblem(ctx, apperr.Unauthorized("invalid_token", "UserId fehlt"))
+		return false
+	}
+
+	role, ok := claims["role"].(string)
+	if !ok {
+		role = "user"
+	}
+
+	ctx.Set("user", models.User{ID: int(userIdFloat), Role: role})
+	ctx.Request = ctx.Request.WithContext(logging.With(ctx.Request.Context(), slog.Int(logging.KeyUserID, int(userIdFloat))))
+	return true
 }
 
 // AdminOnly prüft, ob der eingeloggte User ein Admin ist
@@ -53,15 +93,13 @@ func AdminOnly() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		userInterface, exists := ctx.Get("user") // Auth-Middleware muss user setzen
 		if !exists {
-			ctx.JSON(401, gin.H{"message": "Nicht eingeloggt"})
-			ctx.Abort()
+			WriteProblem(ctx, apperr.Unauthorized("not_authenticated", "Nicht eingeloggt"))
 			return
 		}
 
 		user := userInterface.(models.User)
 		if user.Role != "admin" {
-			ctx.JSON(403, gin.H{"message": "Nicht autorisiert"})
-			ctx.Abort()
+			WriteProblem(ctx, apperr.Forbidden("admin_required", "Nicht autorisiert"))
 			return
 		}
 		ctx.Next()
@@ -32,3 +32,51 @@ func TestAdminOnly(t *testing.T) {
 	})
 
 }
+
+func TestOptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setup := func(limiter *RateLimiter) *gin.Engine {
+		router := gin.New()
+		router.Use(OptionalAuth("test-secret", limiter))
+		router.GET("/books", func(ctx *gin.Context) {
+			_, loggedIn := ctx.Get("user")
+			ctx.JSON(200, gin.H{"loggedIn": loggedIn})
+		})
+		return router
+	}
+	get := func(router *gin.Engine, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/books", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("ohne Token anonym, bis das Limit greift", func(t *testing.T) {
+		router := setup(NewRateLimiter(60, 1))
+
+		first := get(router, "")
+		second := get(router, "")
+
+		assert.Equal(t, 200, first.Code)
+		assert.JSONEq(t, `{"loggedIn":false}`, first.Body.String())
+		assert.Equal(t, http.StatusTooManyRequests, second.Code)
+		assert.Equal(t, "1", second.Header().Get("Retry-After"))
+		assert.Contains(t, second.Body.String(), "rate_limited")
+	})
+
+	t.Run("ungültiges Token → 401", func(t *testing.T) {
+		resp := get(setup(NewRateLimiter(60, 1)), "kaputt")
+
+		assert.Equal(t, 401, resp.Code)
+	})
+
+	t.Run("ohne Limiter kein anonymer Zugriff", func(t *testing.T) {
+		resp := get(setup(nil), "")
+
+		assert.Equal(t, 401, resp.Code)
+		assert.Contains(t, resp.Body.String(), "missing_token")
+	})
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+// statusClientClosedRequest ist kein offizieller Statuscode (nginx-Konvention),
+// taucht aber nur im Log auf – der Client hat die Verbindung bereits getrennt.
+const statusClientClosedRequest = 499
+
+// Problem ist eine Fehlerantwort nach RFC 7807. Code ist stabil und für das
+// Frontend gedacht, Detail ist ein deutscher Text für die Anzeige.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []apperr.FieldError `json:"errors,omitempty"`
+}
+
+var kindStatus = []struct {
+	kind   error
+	status int
+}{
+	{apperr.ErrValidation, http.StatusBadRequest},
+	{apperr.ErrUnauthorized, http.StatusUnauthorized},
+	{apperr.ErrInsufficientBalance, http.StatusPaymentRequired},
+	{apperr.ErrForbidden, http.StatusForbidden},
+	{apperr.ErrNotFound, http.StatusNotFound},
+	{apperr.ErrConflict, http.StatusConflict},
+	{apperr.ErrOutOfStock, http.StatusConflict},
+	{apperr.ErrPreconditionFailed, http.StatusPreconditionFailed},
+	{apperr.ErrPreconditionRequired, http.StatusPreconditionRequired},
+	{apperr.ErrTooLarge, http.StatusRequestEntityTooLarge},
+	{apperr.ErrUnsupportedMedia, http.StatusUnsupportedMediaType},
+	{apperr.ErrTooManyRequests, http.StatusTooManyRequests},
+}
+
+// NewProblem bildet einen Fehler auf Status und Problem-Body ab. Unbekannte
+// Fehler werden zu 500 ohne Details, damit keine Interna nach außen gelangen.
+func NewProblem(err error, instance string) Problem {
+	e, ok := apperr.As(err)
+	if !ok && errors.Is(err, context.DeadlineExceeded) {
+		return Problem{
+			Type:     problemType("request_timeout"),
+			Title:    http.StatusText(http.StatusGatewayTimeout),
+			Status:   http.StatusGatewayTimeout,
+			Detail:   "Die Anfrage hat zu lange gedauert",
+			Instance: instance,
+			Code:     "request_timeout",
+		}
+	}
+	if !ok {
+		return Problem{
+			Type:     problemType("internal_error"),
+			Title:    http.StatusText(http.StatusInternalServerError),
+			Status:   http.StatusInternalServerError,
+			Detail:   "Interner Fehler",
+			Instance: instance,
+			Code:     "internal_error",
+		}
+	}
+
+	status := http.StatusInternalServerError
+	for _, ks := range kindStatus {
+		if errors.Is(e.Kind, ks.kind) {
+			status = ks.status
+			break
+		}
+	}
+
+	return Problem{
+		Type:     problemType(e.Code),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   e.Message,
+		Instance: instance,
+		Code:     e.Code,
+		Errors:   e.Fields,
+	}
+}
+
+func problemType(code string) string {
+	return "urn:bookbazaar:problem:" + code
+}
+
+// WriteProblem schreibt den Fehler als application/problem+json und bricht die Kette ab.
+func WriteProblem(ctx *gin.Context, err error) {
+	// Treiber melden einen Abbruch nicht immer mit gewrapptem ctx.Err(),
+	// daher zählt im Zweifel der Zustand des Request-Kontexts.
+	if _, ok := apperr.As(err); !ok {
+		if ctxErr := ctx.Request.Context().Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+			err = fmt.Errorf("%w: %w", ctxErr, err)
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		ctx.AbortWithStatus(statusClientClosedRequest)
+		return
+	}
+
+	p := NewProblem(err, ctx.Request.URL.Path)
+	ctx.Set(problemCodeKey, p.Code)
+	if p.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx.Request.Context(), "anfrage fehlgeschlagen", slog.String("code", p.Code), slog.Any("error", err))
+	} else {
+		slog.DebugContext(ctx.Request.Context(), "anfrage abgelehnt", slog.String("code", p.Code), slog.Any("error", err))
+	}
+	// gin überschreibt einen bereits gesetzten Content-Type nicht
+	ctx.Header("Content-Type", problemContentType)
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
+
+// ErrorHandler wertet nach dem Handler den letzten per ctx.Error gemeldeten
+// Fehler aus. Handler melden Fehler nur noch und entscheiden keine Statuscodes.
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+		WriteProblem(ctx, ctx.Errors.Last().Err)
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProblem(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"NotFound", apperr.NotFound("book_not_found", "kein Buch"), 404, "book_not_found"},
+		{"Conflict", apperr.Conflict("book_exists", "existiert"), 409, "book_exists"},
+		{"OutOfStock", apperr.OutOfStock("out_of_stock", "weg"), 409, "out_of_stock"},
+		{"InsufficientBalance", apperr.InsufficientBalance("insufficient_balance", "pleite"), 402, "insufficient_balance"},
+		{"Validation", apperr.Validation("invalid_book", "kaputt"), 400, "invalid_book"},
+		{"Unauthorized", apperr.Unauthorized("invalid_credentials", "falsch"), 401, "invalid_credentials"},
+		{"Forbidden", apperr.Forbidden("admin_required", "nein"), 403, "admin_required"},
+		{"PreconditionFailed", apperr.PreconditionFailed("version_mismatch", "veraltet"), 412, "version_mismatch"},
+		{"PreconditionRequired", apperr.PreconditionRequired("if_match_required", "fehlt"), 428, "if_match_required"},
+		{"TooLarge", apperr.TooLarge("cover_too_large", "zu groß"), 413, "cover_too_large"},
+		{"UnsupportedMedia", apperr.UnsupportedMedia("unsupported_cover_type", "kein Bild"), 415, "unsupported_cover_type"},
+		{"gewrappt", fmt.Errorf("service: %w", apperr.NotFound("loan_not_found", "keine Ausleihe")), 404, "loan_not_found"},
+		{"unbekannt", errors.New("pq: connection reset"), 500, "internal_error"},
+		{"Timeout", fmt.Errorf("query: %w", context.DeadlineExceeded), 504, "request_timeout"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewProblem(tc.err, "/api/books/1")
+
+			assert.Equal(t, tc.status, p.Status)
+			assert.Equal(t, tc.code, p.Code)
+			assert.Equal(t, "urn:bookbazaar:problem:"+tc.code, p.Type)
+			assert.Equal(t, "/api/books/1", p.Instance)
+		})
+	}
+
+	t.Run("interne Details werden nicht ausgeliefert", func(t *testing.T) {
+		p := NewProblem(errors.New("password authentication failed for user postgres"), "/")
+		assert.NotContains(t, p.Detail, "postgres")
+	})
+
+	t.Run("Ursache bleibt intern", func(t *testing.T) {
+		err := apperr.Conflict("username_taken", "username existiert bereits").WithCause(errors.New("duplicate key"))
+
+		p := NewProblem(err, "/")
+
+		assert.Equal(t, "username existiert bereits", p.Detail)
+		assert.True(t, errors.Is(err, apperr.ErrConflict))
+	})
+}
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/books/:id", func(ctx *gin.Context) {
+		err := apperr.Validation("invalid_book", "Ungültige Eingabe")
+		err.Fields = []apperr.FieldError{{Field: "name", Message: "ist erforderlich"}}
+		ctx.Error(err)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/books/7", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 400, resp.Code)
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+
+	var p Problem
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &p))
+	assert.Equal(t, "invalid_book", p.Code)
+	assert.Equal(t, "/books/7", p.Instance)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "name", p.Errors[0].Field)
+}
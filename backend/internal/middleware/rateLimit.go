@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval bestimmt, wie oft RateLimiter volle Buckets wegräumt.
+const sweepInterval = time.Minute
+
+// RateLimiter ist ein Token-Bucket je Schlüssel (z.B. Client-IP): jeder
+// Schlüssel darf burst Anfragen auf einmal stellen, danach perMinute pro
+// Minute. Der Zustand liegt im Prozess; mehrere Instanzen zählen getrennt.
+type RateLimiter struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	perSecond float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	return &RateLimiter{
+		now:       time.Now,
+		perSecond: float64(perMinute) / 60,
+		burst:     float64(burst),
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow verbraucht ein Token für key. Ist keins übrig, liefert es false und
+// die Wartezeit bis zum nächsten.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.perSecond)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep entfernt Buckets, die inzwischen wieder voll wären; sie verhalten
+// sich wie ein neuer Bucket. Aufrufer hält l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.perSecond >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
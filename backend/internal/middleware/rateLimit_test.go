@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	setup := func() (*RateLimiter, *time.Time) {
+		now := start
+		l := NewRateLimiter(60, 2)
+		l.now = func() time.Time { return now }
+		l.lastSweep = start
+		return l, &now
+	}
+
+	t.Run("Burst, dann Wartezeit", func(t *testing.T) {
+		l, _ := setup()
+
+		ok1, _ := l.Allow("1.2.3.4")
+		ok2, _ := l.Allow("1.2.3.4")
+		ok3, wait := l.Allow("1.2.3.4")
+
+		assert.True(t, ok1)
+		assert.True(t, ok2)
+		assert.False(t, ok3)
+		assert.Equal(t, time.Second, wait)
+	})
+
+	t.Run("Schlüssel zählen getrennt und füllen sich wieder auf", func(t *testing.T) {
+		l, now := setup()
+		l.Allow("a")
+		l.Allow("a")
+
+		ok, _ := l.Allow("b")
+		assert.True(t, ok)
+
+		*now = now.Add(time.Second)
+		ok, _ = l.Allow("a")
+		assert.True(t, ok)
+		ok, _ = l.Allow("a")
+		assert.False(t, ok)
+	})
+
+	t.Run("volle Buckets werden weggeräumt", func(t *testing.T) {
+		l, now := setup()
+		l.Allow("a")
+
+		*now = now.Add(2 * sweepInterval)
+		l.Allow("b")
+
+		assert.NotContains(t, l.buckets, "a")
+		assert.Contains(t, l.buckets, "b")
+	})
+}
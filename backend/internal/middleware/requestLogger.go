@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bookbazaar-backend/internal/logging"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderRequestID wird von einem vorgeschalteten Proxy übernommen oder neu
+// erzeugt und in der Antwort zurückgegeben, damit Support einen Fehler über
+// alle Log-Einträge hinweg verfolgen kann.
+const HeaderRequestID = "X-Request-ID"
+
+// problemCodeKey ist der gin-Key, unter dem WriteProblem den Fehlercode ablegt.
+const problemCodeKey = "problem_code"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestLogger setzt Request-ID und Route in den Request-Kontext und schreibt
+// nach dem Request genau einen Log-Eintrag. Muss als erste Middleware laufen.
+func RequestLogger() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		requestID := ctx.GetHeader(HeaderRequestID)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		ctx.Header(HeaderRequestID, requestID)
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx.Request = ctx.Request.WithContext(logging.With(ctx.Request.Context(),
+			slog.String(logging.KeyRequestID, requestID),
+			slog.String(logging.KeyRoute, route),
+		))
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", ctx.Writer.Size()),
+			slog.String("client_ip", ctx.ClientIP()),
+		}
+		if code := ctx.GetString(problemCodeKey); code != "" {
+			attrs = append(attrs, slog.String("error_code", code))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(ctx.Request.Context(), level, "request", attrs...)
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Recovery fängt Panics ab, loggt sie mit Request-Kontext und antwortet mit 500.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(ctx *gin.Context, recovered any) {
+		slog.ErrorContext(ctx.Request.Context(), "panic im Handler",
+			slog.Any("panic", recovered),
+			slog.String("stack", string(debug.Stack())),
+		)
+		ctx.Set(problemCodeKey, "internal_error")
+		ctx.Header("Content-Type", problemContentType)
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, NewProblem(nil, ctx.Request.URL.Path))
+	})
+}
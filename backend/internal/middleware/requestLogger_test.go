@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/logging"
+	"bookbazaar-backend/internal/models"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs leitet den Default-Logger für die Dauer des Tests in einen Puffer um.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatJSON, "debug")
+	require.NoError(t, err)
+
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// entries liefert alle JSON-Logzeilen mit der angegebenen Nachricht.
+func entries(t *testing.T, buf *bytes.Buffer, msg string) []map[string]any {
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		if e["msg"] == msg {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func() *gin.Engine {
+		r := gin.New()
+		r.Use(RequestLogger(), ErrorHandler())
+		// simuliert AuthMiddleware + Service, der mit dem Request-Kontext loggt
+		r.POST("/api/books/buyBooks", func(ctx *gin.Context) {
+			ctx.Request = ctx.Request.WithContext(logging.With(ctx.Request.Context(), slog.Int(logging.KeyUserID, 42)))
+			ctx.Set("user", models.User{ID: 42})
+			slog.WarnContext(ctx.Request.Context(), "kauf fehlgeschlagen")
+			ctx.Error(apperr.InsufficientBalance("insufficient_balance", "pleite"))
+		})
+		return r
+	}
+
+	t.Run("Request-ID wird übernommen und an alle Einträge gehängt", func(t *testing.T) {
+		buf := captureLogs(t)
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/books/buyBooks", nil)
+		req.Header.Set(HeaderRequestID, "support-123")
+		resp := httptest.NewRecorder()
+		newRouter().ServeHTTP(resp, req)
+
+		assert.Equal(t, "support-123", resp.Header().Get(HeaderRequestID))
+
+		service := entries(t, buf, "kauf fehlgeschlagen")
+		require.Len(t, service, 1)
+		assert.Equal(t, "support-123", service[0][logging.KeyRequestID])
+		assert.Equal(t, float64(42), service[0][logging.KeyUserID])
+		assert.Equal(t, "/api/books/buyBooks", service[0][logging.KeyRoute])
+
+		request := entries(t, buf, "request")
+		require.Len(t, request, 1)
+		assert.Equal(t, "support-123", request[0][logging.KeyRequestID])
+		assert.Equal(t, float64(42), request[0][logging.KeyUserID])
+		assert.Equal(t, float64(402), request[0]["status"])
+		assert.Equal(t, "insufficient_balance", request[0]["error_code"])
+		assert.Equal(t, "WARN", request[0]["level"])
+	})
+
+	t.Run("ungültige Request-ID wird ersetzt", func(t *testing.T) {
+		captureLogs(t)
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/books/buyBooks", nil)
+		req.Header.Set(HeaderRequestID, "<script>")
+		resp := httptest.NewRecorder()
+		newRouter().ServeHTTP(resp, req)
+
+		id := resp.Header().Get(HeaderRequestID)
+		assert.NotEqual(t, "<script>", id)
+		assert.Len(t, id, 16)
+	})
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout setzt eine Deadline auf den Request-Kontext. Handler, Services und
+// Repositories reichen diesen Kontext bis zu QueryContext/BeginTx durch, so
+// dass Postgres die Abfrage abbricht und die Verbindung zurück in den Pool
+// geht. Der Handler selbst wird nicht abgebrochen; die Fehlerantwort (504)
+// entsteht über den ErrorHandler.
+//
+// Kann pro Route oder Gruppe gesetzt werden. Verschachtelte Timeouts können
+// die Deadline nur verkürzen, nicht verlängern.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if d <= 0 {
+			ctx.Next()
+			return
+		}
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), d)
+		defer cancel()
+
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
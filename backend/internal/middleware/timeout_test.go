@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	// der Handler wartet wie eine hängende SQL-Abfrage auf den Kontext
+	router.GET("/slow", Timeout(20*time.Millisecond), func(ctx *gin.Context) {
+		select {
+		case <-ctx.Request.Context().Done():
+			ctx.Error(ctx.Request.Context().Err())
+		case <-time.After(time.Second):
+			ctx.JSON(200, gin.H{"ok": true})
+		}
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/slow", nil)
+	resp := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(resp, req)
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.Code)
+
+	var p Problem
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &p))
+	assert.Equal(t, "request_timeout", p.Code)
+}
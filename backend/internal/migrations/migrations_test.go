@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("eingebettete Migrationen sind vollständig", func(t *testing.T) {
+		migrations, err := load(files, "sql")
+
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+		assert.Equal(t, 1, migrations[0].Version)
+		assert.Contains(t, migrations[0].Up, "CREATE TABLE IF NOT EXISTS books")
+	})
+
+	t.Run("sortiert nach Version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"sql/0002_b.up.sql":   {Data: []byte("B")},
+			"sql/0002_b.down.sql": {Data: []byte("-B")},
+			"sql/0001_a.up.sql":   {Data: []byte("A")},
+			"sql/0001_a.down.sql": {Data: []byte("-A")},
+		}
+
+		migrations, err := load(fsys, "sql")
+
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+		assert.Equal(t, "a", migrations[0].Name)
+		assert.Equal(t, "-B", migrations[1].Down)
+	})
+
+	t.Run("fehlende down-Datei", func(t *testing.T) {
+		fsys := fstest.MapFS{"sql/0001_a.up.sql": {Data: []byte("A")}}
+
+		_, err := load(fsys, "sql")
+
+		assert.ErrorContains(t, err, "down")
+	})
+
+	t.Run("Lücke in den Versionen", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"sql/0001_a.up.sql":   {Data: []byte("A")},
+			"sql/0001_a.down.sql": {Data: []byte("-A")},
+			"sql/0003_c.up.sql":   {Data: []byte("C")},
+			"sql/0003_c.down.sql": {Data: []byte("-C")},
+		}
+
+		_, err := load(fsys, "sql")
+
+		assert.ErrorContains(t, err, "migration 2 fehlt")
+	})
+}
+
+func TestEnsureCurrent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := &Migrator{db: db, migrations: []Migration{{Version: 1}, {Version: 2}}}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT to_regclass('schema_migrations') IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+
+	err = m.EnsureCurrent(context.Background())
+
+	assert.True(t, errors.Is(err, ErrSchemaBehind), "Schema mit Version 1 von 2 muss abgelehnt werden")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS user_favorites;
+DROP TABLE IF EXISTS user_cart;
+DROP TABLE IF EXISTS borrowed_books;
+DROP TABLE IF EXISTS user_books;
+DROP TABLE IF EXISTS books;
+DROP TABLE IF EXISTS users;
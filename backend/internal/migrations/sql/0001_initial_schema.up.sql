@@ -0,0 +1,87 @@
+-- Ausgangsschema, so wie es von den Repositories erwartet wird.
+-- IF NOT EXISTS, damit bestehende, von Hand angelegte Datenbanken
+-- ohne Datenverlust übernommen werden können.
+
+CREATE TABLE IF NOT EXISTS users (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT           NOT NULL,
+    lastname   TEXT           NOT NULL,
+    username   TEXT           NOT NULL,
+    email      TEXT           NOT NULL,
+    password   TEXT           NOT NULL,
+    created    TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    balance    NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    role       TEXT           NOT NULL DEFAULT 'user',
+    CONSTRAINT users_username_key UNIQUE (username),
+    CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'))
+);
+
+CREATE TABLE IF NOT EXISTS books (
+    id              SERIAL PRIMARY KEY,
+    author          TEXT           NOT NULL,
+    name            TEXT           NOT NULL,
+    price           NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    genre           TEXT           NOT NULL DEFAULT '',
+    description     TEXT           NOT NULL DEFAULT '',
+    descriptionlong TEXT           NOT NULL DEFAULT '',
+    quantity        INTEGER        NOT NULL DEFAULT 0,
+    borrowprice     NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    CONSTRAINT books_price_check CHECK (price >= 0),
+    CONSTRAINT books_borrowprice_check CHECK (borrowprice >= 0),
+    CONSTRAINT books_quantity_check CHECK (quantity >= 0)
+);
+
+-- Käufe: BuyBooks zählt per ON CONFLICT (user_id, book_id) hoch
+CREATE TABLE IF NOT EXISTS user_books (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id      INTEGER     NOT NULL REFERENCES books (id),
+    quantity     INTEGER     NOT NULL DEFAULT 1,
+    purchased_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT user_books_user_book_key UNIQUE (user_id, book_id),
+    CONSTRAINT user_books_quantity_check CHECK (quantity > 0)
+);
+
+CREATE TABLE IF NOT EXISTS borrowed_books (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id     INTEGER     NOT NULL REFERENCES books (id),
+    borrowed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    due_at      TIMESTAMPTZ NOT NULL,
+    returned_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS borrowed_books_user_open_idx
+    ON borrowed_books (user_id) WHERE returned_at IS NULL;
+
+-- Warenkorb: AddToCart nutzt ON CONFLICT (user_id, cart_book_id)
+CREATE TABLE IF NOT EXISTS user_cart (
+    id                     SERIAL PRIMARY KEY,
+    user_id                INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    cart_book_id           INTEGER     NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    created_at             TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reservation_expires_at TIMESTAMPTZ,
+    removed_at             TIMESTAMPTZ,
+    CONSTRAINT user_cart_user_book_key UNIQUE (user_id, cart_book_id)
+);
+
+-- Favoriten: AddToFavorites nutzt ON CONFLICT (user_id, book_id)
+CREATE TABLE IF NOT EXISTS user_favorites (
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id    INTEGER     NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, book_id)
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash   TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    revoked      BOOLEAN     NOT NULL DEFAULT FALSE,
+    CONSTRAINT refresh_tokens_token_hash_key UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_idx ON refresh_tokens (user_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS books_genre_lower_idx;
+DROP INDEX IF EXISTS books_created_at_id_idx;
+DROP INDEX IF EXISTS books_price_id_idx;
+DROP INDEX IF EXISTS books_name_id_idx;
+
+ALTER TABLE books DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,8 @@
+-- Katalog-Listing: Sortierung nach "neu" und Indizes für Filter und
+-- Keyset-Paginierung (Sortierspalte, id).
+ALTER TABLE books ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS books_name_id_idx ON books (name, id);
+CREATE INDEX IF NOT EXISTS books_price_id_idx ON books (price, id);
+CREATE INDEX IF NOT EXISTS books_created_at_id_idx ON books (created_at, id);
+CREATE INDEX IF NOT EXISTS books_genre_lower_idx ON books (lower(genre));
@@ -0,0 +1,11 @@
+-- Volltextsuche: gewichteter tsvector (Titel > Autor > Kurz- > Langbeschreibung)
+-- mit deutscher Stammformreduktion. Die Spalte wird von Postgres gepflegt.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('german'::regconfig, coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('german'::regconfig, coalesce(author, '')), 'B') ||
+        setweight(to_tsvector('german'::regconfig, coalesce(description, '')), 'C') ||
+        setweight(to_tsvector('german'::regconfig, coalesce(descriptionlong, '')), 'D')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS books_search_vector_idx ON books USING GIN (search_vector);
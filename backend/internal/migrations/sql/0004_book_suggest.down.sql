@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS books_author_trgm_idx;
+DROP INDEX IF EXISTS books_name_trgm_idx;
+
+-- die Extension bleibt installiert, andere Schemas könnten sie nutzen
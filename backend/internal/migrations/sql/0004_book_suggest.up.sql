@@ -0,0 +1,7 @@
+-- Autovervollständigung: Trigramm-Indizes für word_similarity über Titel und
+-- Autor, damit "Tolkein" auch "J.R.R. Tolkien" findet. pg_trgm muss auf dem
+-- Server verfügbar sein (contrib, in den offiziellen Images enthalten).
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS books_name_trgm_idx ON books USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS books_author_trgm_idx ON books USING GIN (author gin_trgm_ops);
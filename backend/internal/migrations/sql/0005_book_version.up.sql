@@ -0,0 +1,3 @@
+-- Optimistische Nebenläufigkeit: jede Änderung an einem Buch erhöht version,
+-- die API liefert sie als ETag aus und prüft If-Match dagegen.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
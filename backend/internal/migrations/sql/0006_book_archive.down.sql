@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS books_archived_at_idx;
+
+ALTER TABLE books DROP COLUMN IF EXISTS archived_at;
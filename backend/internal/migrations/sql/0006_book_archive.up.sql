@@ -0,0 +1,5 @@
+-- Archivieren statt Löschen: archivierte Bücher verschwinden aus dem Katalog,
+-- Käufe und Ausleihen verweisen weiter auf sie.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS books_archived_at_idx ON books (archived_at) WHERE archived_at IS NOT NULL;
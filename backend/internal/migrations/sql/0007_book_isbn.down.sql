@@ -0,0 +1,3 @@
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_isbn13_check;
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_isbn13_key;
+ALTER TABLE books DROP COLUMN IF EXISTS isbn13;
@@ -0,0 +1,6 @@
+-- ISBN: gespeichert wird nur die ISBN-13 ohne Bindestriche, die ISBN-10 wird
+-- bei Bedarf daraus berechnet. Bücher ohne ISBN bleiben erlaubt (NULL).
+ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn13 CHAR(13);
+
+ALTER TABLE books ADD CONSTRAINT books_isbn13_key UNIQUE (isbn13);
+ALTER TABLE books ADD CONSTRAINT books_isbn13_check CHECK (isbn13 ~ '^97[89][0-9]{10}$');
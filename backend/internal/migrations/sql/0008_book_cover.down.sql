@@ -0,0 +1,2 @@
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_cover_hash_check;
+ALTER TABLE books DROP COLUMN IF EXISTS cover_hash;
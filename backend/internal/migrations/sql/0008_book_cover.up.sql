@@ -0,0 +1,5 @@
+-- Cover: gespeichert wird nur der Hash des hochgeladenen Bildes, die Dateien
+-- liegen im Blob-Speicher unter covers/<id>/<hash>/<größe>.jpg.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS cover_hash TEXT;
+
+ALTER TABLE books ADD CONSTRAINT books_cover_hash_check CHECK (cover_hash ~ '^[0-9a-f]{16}$');
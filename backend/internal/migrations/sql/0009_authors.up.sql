@@ -0,0 +1,38 @@
+-- Autoren als eigene Tabelle, Bücher verweisen über book_authors darauf
+-- (mehrere Autoren pro Buch, position = Reihenfolge). books.author bleibt als
+-- Anzeige- und Suchtext ("A, B") erhalten und wird vom Repository gepflegt.
+CREATE TABLE IF NOT EXISTS authors (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    bio        TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT authors_name_check CHECK (length(btrim(name)) >= 3)
+);
+
+-- gleicher Name in anderer Schreibweise ist derselbe Autor
+CREATE UNIQUE INDEX IF NOT EXISTS authors_name_key ON authors (lower(name));
+
+CREATE TABLE IF NOT EXISTS book_authors (
+    book_id   INTEGER  NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    author_id INTEGER  NOT NULL REFERENCES authors (id),
+    position  SMALLINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (book_id, author_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_authors_author_idx ON book_authors (author_id);
+
+-- Bestand übernehmen: je Schreibweise (ohne Groß-/Kleinschreibung) ein Autor,
+-- jedes Buch mit genau diesem einen Autor. Mitautoren und Dubletten wie
+-- "JRR Tolkien" werden danach per Merge bereinigt.
+INSERT INTO authors (name)
+SELECT DISTINCT ON (lower(btrim(author))) btrim(author)
+FROM books
+WHERE length(btrim(author)) >= 3
+ORDER BY lower(btrim(author)), id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO book_authors (book_id, author_id)
+SELECT b.id, a.id
+FROM books b
+JOIN authors a ON lower(a.name) = lower(btrim(b.author))
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,39 @@
+-- Kategorien als Baum (Belletristik > Fantasy > High Fantasy). Ein Buch kann
+-- in mehreren Kategorien stehen; die erste (position 0) ist seine
+-- Hauptkategorie, deren Name das Repository in books.genre spiegelt.
+CREATE TABLE IF NOT EXISTS categories (
+    id         SERIAL PRIMARY KEY,
+    parent_id  INTEGER REFERENCES categories (id),
+    name       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT categories_name_check CHECK (length(btrim(name)) BETWEEN 2 AND 60),
+    CONSTRAINT categories_parent_check CHECK (parent_id <> id)
+);
+
+-- Namen sind nur unter Geschwistern eindeutig ("Krimi" darf es unter
+-- Belletristik und unter Jugendbuch geben)
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_key ON categories (COALESCE(parent_id, 0), lower(name));
+
+CREATE TABLE IF NOT EXISTS book_categories (
+    book_id     INTEGER  NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    category_id INTEGER  NOT NULL REFERENCES categories (id),
+    position    SMALLINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (book_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_categories_category_idx ON book_categories (category_id);
+
+-- Bestand übernehmen: jedes bisherige Genre wird eine Kategorie der obersten
+-- Ebene. Einordnen in den Baum geschieht danach über die Admin-API.
+INSERT INTO categories (name)
+SELECT DISTINCT ON (lower(btrim(genre))) btrim(genre)
+FROM books
+WHERE length(btrim(genre)) BETWEEN 2 AND 60
+ORDER BY lower(btrim(genre)), id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO book_categories (book_id, category_id)
+SELECT b.id, c.id
+FROM books b
+JOIN categories c ON c.parent_id IS NULL AND lower(c.name) = lower(btrim(b.genre))
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,25 @@
+-- Bestandsjournal: jede Änderung von books.quantity wird in derselben
+-- Transaktion mit Grund und Bezug festgehalten. Die Summe der Deltas eines
+-- Buchs ist sein Bestand (siehe ReconcileStock).
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id             BIGSERIAL PRIMARY KEY,
+    book_id        INTEGER     NOT NULL REFERENCES books (id),
+    delta          INTEGER     NOT NULL,
+    reason         TEXT        NOT NULL,
+    user_id        INTEGER     REFERENCES users (id) ON DELETE SET NULL,
+    purchase_id    INTEGER     REFERENCES user_books (id) ON DELETE SET NULL,
+    loan_id        INTEGER     REFERENCES borrowed_books (id) ON DELETE SET NULL,
+    note           TEXT        NOT NULL DEFAULT '',
+    quantity_after INTEGER     NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT stock_movements_delta_check CHECK (delta <> 0),
+    CONSTRAINT stock_movements_reason_check CHECK (reason IN ('sale', 'loan', 'return', 'adjustment', 'import'))
+);
+
+CREATE INDEX IF NOT EXISTS stock_movements_book_idx ON stock_movements (book_id, id);
+
+-- bisheriger Bestand als Anfangsbuchung, damit das Journal von Anfang an aufgeht
+INSERT INTO stock_movements (book_id, delta, reason, note, quantity_after)
+SELECT id, quantity, 'adjustment', 'Anfangsbestand', quantity
+FROM books
+WHERE quantity <> 0;
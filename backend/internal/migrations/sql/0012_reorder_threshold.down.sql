@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS stock_movements_book_sale_idx;
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_reorder_threshold_check;
+ALTER TABLE books DROP COLUMN IF EXISTS reorder_threshold;
@@ -0,0 +1,9 @@
+-- Meldebestand je Buch: ab quantity <= reorder_threshold steht das Buch im
+-- Bericht GET /api/stock/low. NULL = keine Meldung.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS reorder_threshold INTEGER;
+
+ALTER TABLE books ADD CONSTRAINT books_reorder_threshold_check CHECK (reorder_threshold >= 0);
+
+-- Verkaufsgeschwindigkeit im Bericht: Verkäufe eines Buchs laut Journal seit
+-- einem Zeitpunkt
+CREATE INDEX IF NOT EXISTS stock_movements_book_sale_idx ON stock_movements (book_id, created_at) WHERE reason = 'sale';
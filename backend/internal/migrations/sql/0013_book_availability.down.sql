@@ -0,0 +1 @@
+DROP INDEX IF EXISTS borrowed_books_book_open_idx;
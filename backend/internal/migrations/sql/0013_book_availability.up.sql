@@ -0,0 +1,4 @@
+-- Detailseite: offene Ausleihen eines Buchs zählen und die nächste Rückgabe finden
+CREATE INDEX IF NOT EXISTS borrowed_books_book_open_idx
+    ON borrowed_books (book_id, due_at)
+    WHERE returned_at IS NULL;
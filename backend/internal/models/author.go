@@ -0,0 +1,31 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type Author struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name" validate:"required,min=3"`
+	Bio       string     `json:"bio"`
+	BookCount int        `json:"bookCount"` // Bücher im Katalog (ohne archivierte)
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// AuthorRef verweist aus einem Buch auf einen Autor. Beim Anlegen und Ändern
+// eines Buchs reicht die ID eines bestehenden Autors oder ein Name; zu einem
+// unbekannten Namen wird der Autor angelegt.
+type AuthorRef struct {
+	ID   int    `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// JoinAuthors baut daraus das Feld Book.Author ("Terry Pratchett, Neil Gaiman").
+func JoinAuthors(refs []AuthorRef) string {
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = ref.Name
+	}
+	return strings.Join(names, ", ")
+}
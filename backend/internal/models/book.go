@@ -1,16 +1,55 @@
 package models
 
+import "time"
+
 type Book struct {
-	ID                   int     `json:"id"`
-	Author               string  `json:"author" validate:"required,min=3"`
-	Name                 string  `json:"name" validate:"required,min=3"`
-	Price                float64 `json:"price" validate:"min=0"`
-	Genre                string  `json:"genre"`
-	Description          string  `json:"description"`
-	Descriptionlong      string  `json:"descriptionLong"`
-	Quantity             int     `json:"quantity"` // Lagerbestand
-	BorrowPrice          float64 `json:"borrowprice"`
-	DueAt                string  `json:"dueAt,omitempty"`
-	ReservationExpiresAt string  `json:"reservationExpiresAt,omitempty"`
-	OrderedQuantity      int     `json:"orderedQuantity,omitempty"` // NEU: Kaufanzahl für Order-Views
+	ID                   int           `json:"id"`
+	Author               string        `json:"author" validate:"required,min=3"` // alle Autoren, kommagetrennt
+	Authors              []AuthorRef   `json:"authors,omitempty"`
+	Name                 string        `json:"name" validate:"required,min=3"`
+	ISBN13               string        `json:"isbn13,omitempty"` // ohne Bindestriche, eindeutig
+	ISBN10               string        `json:"isbn10,omitempty"` // aus ISBN13 abgeleitet, falls Präfix 978
+	Price                float64       `json:"price" validate:"min=0"`
+	Genre                string        `json:"genre"` // Name der Hauptkategorie (erste in Categories)
+	Categories           []CategoryRef `json:"categories,omitempty"`
+	Description          string        `json:"description"`
+	Descriptionlong      string        `json:"descriptionLong"`
+	Quantity             int           `json:"quantity" validate:"min=0"` // Lagerbestand
+	BorrowPrice          float64       `json:"borrowprice"`
+	DueAt                string        `json:"dueAt,omitempty"`
+	ReservationExpiresAt string        `json:"reservationExpiresAt,omitempty"`
+	OrderedQuantity      int           `json:"orderedQuantity,omitempty"` // NEU: Kaufanzahl für Order-Views
+	CreatedAt            *time.Time    `json:"createdAt,omitempty"`       // Anlagezeitpunkt, bei jedem gelesenen Buch gesetzt
+	Version              int           `json:"version,omitempty"`         // für If-Match, steigt mit jeder Änderung
+	CoverURL             string        `json:"coverUrl,omitempty"`        // mittlere Größe, siehe covers.Sizes
+	CoverHash            string        `json:"-"`
+	ArchivedAt           *time.Time    `json:"archivedAt,omitempty"` // gesetzt, wenn das Buch nicht mehr angeboten wird
+}
+
+// PublicBook ist die Sicht ohne Anmeldung: Katalogangaben und ob das Buch
+// verfügbar ist, aber weder Bestand noch Version oder Verwaltungsfelder.
+type PublicBook struct {
+	ID              int           `json:"id"`
+	Author          string        `json:"author"`
+	Authors         []AuthorRef   `json:"authors,omitempty"`
+	Name            string        `json:"name"`
+	ISBN13          string        `json:"isbn13,omitempty"`
+	ISBN10          string        `json:"isbn10,omitempty"`
+	Price           float64       `json:"price"`
+	Genre           string        `json:"genre"`
+	Categories      []CategoryRef `json:"categories,omitempty"`
+	Description     string        `json:"description"`
+	Descriptionlong string        `json:"descriptionLong"`
+	BorrowPrice     float64       `json:"borrowprice"`
+	CoverURL        string        `json:"coverUrl,omitempty"`
+	Available       bool          `json:"available"` // quantity > 0
+}
+
+func (b *Book) Public() PublicBook {
+	return PublicBook{
+		ID: b.ID, Author: b.Author, Authors: b.Authors, Name: b.Name, ISBN13: b.ISBN13, ISBN10: b.ISBN10,
+		Price: b.Price, Genre: b.Genre, Categories: b.Categories, Description: b.Description,
+		Descriptionlong: b.Descriptionlong, BorrowPrice: b.BorrowPrice, CoverURL: b.CoverURL,
+		Available: b.Quantity > 0,
+	}
 }
@@ -0,0 +1,15 @@
+package models
+
+type Category struct {
+	ID        int    `json:"id"`
+	ParentID  *int   `json:"parentId"` // nil auf oberster Ebene
+	Name      string `json:"name" validate:"required,min=2,max=60"`
+	BookCount int    `json:"bookCount"` // Bücher im Katalog samt Unterkategorien, jedes nur einmal
+}
+
+// CategoryRef verweist aus einem Buch auf eine Kategorie. Beim Schreiben
+// zählt nur die ID; Kategorien legt ein Admin über die Kategorie-API an.
+type CategoryRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
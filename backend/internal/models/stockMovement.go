@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Gründe einer Bestandsbewegung (stock_movements.reason).
+const (
+	MovementSale       = "sale"
+	MovementLoan       = "loan"
+	MovementReturn     = "return"
+	MovementAdjustment = "adjustment" // Anlage oder Korrektur durch einen Admin
+	MovementImport     = "import"
+)
+
+// StockMovement ist ein Eintrag im Bestandsjournal. Je nach Grund verweist
+// er auf den Kauf (user_books) oder die Ausleihe (borrowed_books).
+type StockMovement struct {
+	ID            int       `json:"id"`
+	BookID        int       `json:"bookId"`
+	Delta         int       `json:"delta"`
+	Reason        string    `json:"reason"`
+	UserID        *int      `json:"userId,omitempty"`
+	PurchaseID    *int      `json:"purchaseId,omitempty"`
+	LoanID        *int      `json:"loanId,omitempty"`
+	Note          string    `json:"note,omitempty"`
+	QuantityAfter int       `json:"quantityAfter"` // Bestand nach der Bewegung
+	CreatedAt     time.Time `json:"createdAt"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"log/slog"
+	"time"
+)
 
 type User struct {
 	ID       int       `json:"id"`
@@ -9,7 +12,16 @@ type User struct {
 	Username string    `json:"username" validate:"required,min=5,max=20"`
 	Created  time.Time `json:"created"`
 	Email    string    `json:"email" validate:"required,email"`
-	Password string    `json:"password" validate:"required,min=6,max=72"`
+	Password string    `json:"password,omitempty" validate:"required,min=6,max=72"` // nur Eingabe, Handler leeren es vor der Antwort
 	Balance  float64   `json:"balance"`
 	Role     string    `json:"role"`
 }
+
+// LogValue sorgt dafür, dass ein geloggter User nie Passwort oder E-Mail enthält.
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", u.ID),
+		slog.String("username", u.Username),
+		slog.String("role", u.Role),
+	)
+}
@@ -0,0 +1,74 @@
+// Package openapi bettet die gepflegte OpenAPI-3-Spezifikation ein und
+// liefert sie als JSON aus. Gepflegt wird openapi.yaml von Hand; der
+// TypeScript-Client im Frontend wird daraus generiert.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+// httpMethods sind die Schlüssel eines Path-Items, die Operationen beschreiben.
+var httpMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+var loadJSON = sync.OnceValues(func() ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(specYAML, &doc); err != nil {
+		return nil, fmt.Errorf("openapi.yaml ist ungültig: %w", err)
+	}
+	return json.Marshal(doc)
+})
+
+// JSON liefert die Spezifikation als JSON-Dokument.
+func JSON() ([]byte, error) {
+	return loadJSON()
+}
+
+// Operations listet alle Operationen der Spec als "METHODE /pfad", z.B.
+// "GET /api/books/{id}", sortiert.
+func Operations() ([]string, error) {
+	var doc struct {
+		Paths map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(specYAML, &doc); err != nil {
+		return nil, fmt.Errorf("openapi.yaml ist ungültig: %w", err)
+	}
+
+	var ops []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			if httpMethods[method] {
+				ops = append(ops, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops, nil
+}
+
+// Handler liefert GET /api/openapi.json.
+func Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		spec, err := JSON()
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	}
+}
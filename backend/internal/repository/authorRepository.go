@@ -0,0 +1,387 @@
+package repository
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AuthorRepository struct {
+	db *sql.DB
+}
+
+func NewAuthorRepository(db *sql.DB) *AuthorRepository {
+	return &AuthorRepository{db: db}
+}
+
+// AuthorQuery ist eine Seite der Autorenliste, alphabetisch sortiert.
+type AuthorQuery struct {
+	Name   string // Teilstring, ohne Groß-/Kleinschreibung
+	Limit  int
+	Offset int
+}
+
+type AuthorPage struct {
+	Items  []models.Author `json:"items"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+// querier ist *sql.DB oder *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// intArray schreibt IDs als Postgres-Array-Literal ("{1,2,3}"), gebunden als
+// $n::int[]. So braucht es keine treiberspezifischen Array-Typen.
+func intArray(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// loadAuthors hängt an alle Bücher ihre Autoren, mit einer Abfrage für alle.
+func loadAuthors(ctx context.Context, q querier, books []models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	ids := make([]int, len(books))
+	index := make(map[int]int, len(books))
+	for i, b := range books {
+		ids[i] = b.ID
+		index[b.ID] = i
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT ba.book_id, a.id, a.name
+		FROM book_authors ba JOIN authors a ON a.id = ba.author_id
+		WHERE ba.book_id = ANY($1::int[])
+		ORDER BY ba.book_id, ba.position, a.id`, intArray(ids))
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim laden der autoren", slog.Any("error", err))
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bookID int
+		var ref models.AuthorRef
+		if err := rows.Scan(&bookID, &ref.ID, &ref.Name); err != nil {
+			return err
+		}
+		b := &books[index[bookID]]
+		b.Authors = append(b.Authors, ref)
+	}
+	return rows.Err()
+}
+
+// resolveAuthors vervollständigt book.Authors: Verweise per ID bekommen den
+// aktuellen Namen, zu einem Namen wird der Autor gesucht oder angelegt.
+// Danach ist book.Author der daraus gebildete Anzeigetext.
+func resolveAuthors(ctx context.Context, tx *sql.Tx, book *models.Book) error {
+	seen := map[int]bool{}
+	refs := book.Authors[:0]
+	for _, ref := range book.Authors {
+		var err error
+		if ref.ID != 0 {
+			err = tx.QueryRowContext(ctx, "SELECT name FROM authors WHERE id = $1", ref.ID).Scan(&ref.Name)
+			if err == sql.ErrNoRows {
+				return apperr.Validation("invalid_book", "Autor mit ID %d existiert nicht", ref.ID)
+			}
+		} else {
+			// DO UPDATE statt DO NOTHING, damit RETURNING auch den bestehenden Autor liefert
+			err = tx.QueryRowContext(ctx, `
+				INSERT INTO authors (name) VALUES ($1)
+				ON CONFLICT ((lower(name))) DO UPDATE SET name = authors.name
+				RETURNING id, name`, strings.TrimSpace(ref.Name)).Scan(&ref.ID, &ref.Name)
+			if pgErrorCode(err) == pgCheckViolation {
+				return apperr.Validation("invalid_book", "Autorenname '%s' ist zu kurz", ref.Name).WithCause(err)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if !seen[ref.ID] {
+			seen[ref.ID] = true
+			refs = append(refs, ref)
+		}
+	}
+	book.Authors = refs
+	if len(refs) > 0 {
+		book.Author = models.JoinAuthors(refs)
+	}
+	return nil
+}
+
+// linkAuthors ersetzt die Autoren eines Buchs durch book.Authors.
+func linkAuthors(ctx context.Context, tx *sql.Tx, book *models.Book) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM book_authors WHERE book_id = $1", book.ID); err != nil {
+		return err
+	}
+	for i, ref := range book.Authors {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO book_authors (book_id, author_id, position) VALUES ($1, $2, $3)", book.ID, ref.ID, i); err != nil {
+			logPgError(ctx, "autor konnte nicht verknüpft werden", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshBookAuthors setzt books.author aller Bücher der angegebenen Autoren
+// neu zusammen, z.B. nach Umbenennen oder Zusammenführen. Geänderte Bücher
+// bekommen eine neue Version.
+func refreshBookAuthors(ctx context.Context, tx *sql.Tx, authorIDs []int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE books b SET author = s.names, version = b.version + 1
+		FROM (
+			SELECT ba.book_id, string_agg(a.name, ', ' ORDER BY ba.position, a.id) AS names
+			FROM book_authors ba JOIN authors a ON a.id = ba.author_id
+			WHERE ba.book_id IN (SELECT book_id FROM book_authors WHERE author_id = ANY($1::int[]))
+			GROUP BY ba.book_id
+		) s
+		WHERE b.id = s.book_id AND b.author IS DISTINCT FROM s.names`, intArray(authorIDs))
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim aktualisieren der autorennamen", slog.Any("error", err))
+	}
+	return err
+}
+
+// AuthorNames liefert die Namen der Autoren mit den angegebenen IDs;
+// unbekannte IDs fehlen in der Map.
+func (r *BookRepository) AuthorNames(ctx context.Context, ids []int) (map[int]string, error) {
+	names := make(map[int]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name FROM authors WHERE id = ANY($1::int[])", intArray(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+const authorColumns = `a.id, a.name, a.bio, a.created_at,
+	(SELECT COUNT(*) FROM book_authors ba JOIN books b ON b.id = ba.book_id
+	 WHERE ba.author_id = a.id AND b.archived_at IS NULL)`
+
+func scanAuthor(row interface{ Scan(dest ...any) error }) (models.Author, error) {
+	var a models.Author
+	var createdAt time.Time
+	if err := row.Scan(&a.ID, &a.Name, &a.Bio, &createdAt, &a.BookCount); err != nil {
+		return models.Author{}, err
+	}
+	a.CreatedAt = &createdAt
+	return a, nil
+}
+
+func (r *AuthorRepository) ListAuthors(ctx context.Context, q AuthorQuery) (AuthorPage, error) {
+	page := AuthorPage{Items: []models.Author{}, Limit: q.Limit, Offset: q.Offset}
+	pattern := "%" + escapeLike(q.Name) + "%"
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM authors a WHERE a.name ILIKE $1", pattern).Scan(&page.Total); err != nil {
+		return AuthorPage{}, err
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT `+authorColumns+` FROM authors a
+		WHERE a.name ILIKE $1 ORDER BY lower(a.name), a.id LIMIT $2 OFFSET $3`, pattern, q.Limit, q.Offset)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler bei der autorenliste", slog.Any("error", err))
+		return AuthorPage{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a, err := scanAuthor(rows)
+		if err != nil {
+			return AuthorPage{}, err
+		}
+		page.Items = append(page.Items, a)
+	}
+	return page, rows.Err()
+}
+
+// GetAuthor liefert einen Autor oder nil, wenn es keinen gibt.
+func (r *AuthorRepository) GetAuthor(ctx context.Context, id int) (*models.Author, error) {
+	a, err := scanAuthor(r.db.QueryRowContext(ctx, `SELECT `+authorColumns+` FROM authors a WHERE a.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAuthorBooks liefert die Bücher eines Autors im Katalog, nach Titel.
+func (r *AuthorRepository) GetAuthorBooks(ctx context.Context, id int) ([]models.Book, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+bookColumns+` FROM books
+		JOIN book_authors ON book_id = id
+		WHERE author_id = $1 AND archived_at IS NULL
+		ORDER BY name, id`, id)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler bei den büchern des autors", slog.Int("author_id", id), slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []models.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return books, loadLinks(ctx, r.db, books)
+}
+
+// authorError übersetzt Constraint-Verletzungen beim Schreiben eines Autors.
+func authorError(err error, author *models.Author) error {
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return apperr.Conflict("author_exists", "Autor '%s' existiert bereits", author.Name).WithCause(err)
+	case pgCheckViolation:
+		return apperr.Validation("invalid_author", "Autorenname '%s' ist zu kurz", author.Name).WithCause(err)
+	}
+	return err
+}
+
+func (r *AuthorRepository) AddAuthor(ctx context.Context, author *models.Author) error {
+	var createdAt time.Time
+	err := r.db.QueryRowContext(ctx, "INSERT INTO authors (name, bio) VALUES ($1, $2) RETURNING id, created_at",
+		author.Name, author.Bio).Scan(&author.ID, &createdAt)
+	if err != nil {
+		logPgError(ctx, "autor konnte nicht angelegt werden", err)
+		return authorError(err, author)
+	}
+	author.CreatedAt = &createdAt
+	slog.InfoContext(ctx, "autor angelegt", slog.Int("author_id", author.ID))
+	return nil
+}
+
+// UpdateAuthor ändert Name und Bio; bei neuem Namen wird books.author der
+// Bücher des Autors mitgezogen.
+func (r *AuthorRepository) UpdateAuthor(ctx context.Context, author *models.Author) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE authors SET name = $2, bio = $3 WHERE id = $1", author.ID, author.Name, author.Bio)
+	if err != nil {
+		logPgError(ctx, "autor konnte nicht geändert werden", err)
+		return authorError(err, author)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return apperr.NotFound("author_not_found", "kein Autor mit ID %d gefunden", author.ID)
+	}
+	if err := refreshBookAuthors(ctx, tx, []int{author.ID}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "autor geändert", slog.Int("author_id", author.ID))
+	return nil
+}
+
+// DeleteAuthor löscht einen Autor ohne Bücher. Hat er noch Bücher, muss er
+// stattdessen mit einem anderen Autor zusammengeführt werden.
+func (r *AuthorRepository) DeleteAuthor(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM authors WHERE id = $1", id)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return apperr.Conflict("author_has_books", "Autor mit ID %d hat noch Bücher", id).WithCause(err)
+	}
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return apperr.NotFound("author_not_found", "kein Autor mit ID %d gefunden", id)
+	}
+	slog.InfoContext(ctx, "autor gelöscht", slog.Int("author_id", id))
+	return nil
+}
+
+// MergeAuthors hängt alle Bücher der Dubletten an targetID und löscht die
+// Dubletten. Hatte ein Buch beide, bleibt target an der vorderen Position.
+func (r *AuthorRepository) MergeAuthors(ctx context.Context, targetID int, duplicateIDs []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
+
+	all := append([]int{targetID}, duplicateIDs...)
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM authors WHERE id = ANY($1::int[]) FOR UPDATE", intArray(all))
+	if err != nil {
+		return err
+	}
+	found := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		found[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range all {
+		if !found[id] {
+			return apperr.NotFound("author_not_found", "kein Autor mit ID %d gefunden", id)
+		}
+	}
+
+	dups := intArray(duplicateIDs)
+	steps := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO book_authors (book_id, author_id, position)
+		  SELECT book_id, $1, MIN(position) FROM book_authors WHERE author_id = ANY($2::int[]) GROUP BY book_id
+		  ON CONFLICT (book_id, author_id) DO UPDATE SET position = LEAST(book_authors.position, EXCLUDED.position)`, []any{targetID, dups}},
+		{"DELETE FROM book_authors WHERE author_id = ANY($1::int[])", []any{dups}},
+		{"DELETE FROM authors WHERE id = ANY($1::int[])", []any{dups}},
+	}
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step.query, step.args...); err != nil {
+			logPgError(ctx, "autoren konnten nicht zusammengeführt werden", err)
+			return err
+		}
+	}
+	if err := refreshBookAuthors(ctx, tx, []int{targetID}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "autoren zusammengeführt", slog.Int("author_id", targetID), slog.Any("duplicate_ids", duplicateIDs))
+	return nil
+}
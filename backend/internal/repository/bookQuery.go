@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sortierungen des Katalogs. Ein "-" davor heißt absteigend.
+const (
+	SortName      = "name"
+	SortNameDesc  = "-name"
+	SortPrice     = "price"
+	SortPriceDesc = "-price"
+	SortNewest    = "newest"
+)
+
+// BookQuery beschreibt eine Seite des Katalogs. Leere Felder filtern nicht.
+// Blättern geht entweder über Offset oder über Cursor (NextCursor der
+// vorherigen Seite), nicht beides gleichzeitig.
+type BookQuery struct {
+	Genre      string   // exakt, ohne Groß-/Kleinschreibung
+	Category   int      // Kategorie samt aller Unterkategorien
+	Author     string   // Teilstring, ohne Groß-/Kleinschreibung
+	MinPrice   *float64 // inklusive
+	MaxPrice   *float64 // inklusive
+	InStock    bool     // nur quantity > 0
+	Borrowable bool     // nur ausleihbar: Leihpreis gesetzt und auf Lager
+	Archived   bool     // statt des Katalogs nur archivierte Bücher (Admin)
+	Sort       string
+	Limit      int
+	Offset     int
+	Cursor     string
+}
+
+// BookPage ist eine Seite des Katalogs. Total zählt alle Treffer der Filter,
+// unabhängig von Limit, Offset und Cursor.
+type BookPage struct {
+	Items      []models.Book `json:"items"`
+	Total      int           `json:"total"`
+	Limit      int           `json:"limit"`
+	Offset     int           `json:"offset"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// ValidBookSort meldet, ob sort eine bekannte Sortierung ist.
+func ValidBookSort(sort string) bool {
+	switch sort {
+	case SortName, SortNameDesc, SortPrice, SortPriceDesc, SortNewest:
+		return true
+	}
+	return false
+}
+
+// bookCursor ist der Inhalt eines Cursors: Sortierung, Sortierschlüssel und ID
+// des letzten Buchs der Seite. Für Clients ist er opak (Base64).
+type bookCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   int    `json:"id"`
+}
+
+// sortKey liefert den Sortierschlüssel eines Buchs als Text, so wie er im
+// Cursor steht.
+func sortKey(sort string, b models.Book) string {
+	switch sort {
+	case SortPrice, SortPriceDesc:
+		return strconv.FormatFloat(b.Price, 'f', -1, 64)
+	case SortNewest:
+		if b.CreatedAt == nil {
+			return ""
+		}
+		return b.CreatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return b.Name
+	}
+}
+
+// EncodeBookCursor baut den Cursor, der hinter last weiterblättert.
+func EncodeBookCursor(sort string, last models.Book) string {
+	raw, _ := json.Marshal(bookCursor{Sort: sort, Key: sortKey(sort, last), ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeBookCursor liest einen Cursor und liefert das Buch, hinter dem die
+// Seite beginnt; gefüllt sind nur ID und das Sortierfeld. Ein Cursor gilt nur
+// für die Sortierung, mit der er erzeugt wurde.
+func DecodeBookCursor(sort, cursor string) (models.Book, error) {
+	invalid := apperr.Validation("invalid_cursor", "Ungültiger Cursor")
+	invalid.Fields = []apperr.FieldError{{Field: "cursor", Message: "ist ungültig oder gehört zu einer anderen Sortierung"}}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return models.Book{}, invalid.WithCause(err)
+	}
+	var c bookCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return models.Book{}, invalid.WithCause(err)
+	}
+	if c.Sort != sort || c.ID < 1 {
+		return models.Book{}, invalid
+	}
+
+	after := models.Book{ID: c.ID}
+	switch sort {
+	case SortPrice, SortPriceDesc:
+		if after.Price, err = strconv.ParseFloat(c.Key, 64); err != nil {
+			return models.Book{}, invalid.WithCause(err)
+		}
+	case SortNewest:
+		t, err := time.Parse(time.RFC3339Nano, c.Key)
+		if err != nil {
+			return models.Book{}, invalid.WithCause(err)
+		}
+		after.CreatedAt = &t
+	default:
+		after.Name = c.Key
+	}
+	return after, nil
+}
+
+// SearchQuery ist eine Volltextsuche im Katalog. Text wird wie eine
+// Websuche gelesen ("ring -herr", "\"der kleine hobbit\"", "tolkien or lewis").
+type SearchQuery struct {
+	Text   string
+	Limit  int
+	Offset int
+}
+
+// BookHit ist ein Suchtreffer: das Buch, seine Relevanz und ein Ausschnitt
+// aus der Beschreibung, in dem die Treffer mit <mark> hervorgehoben sind.
+type BookHit struct {
+	models.Book
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchPage ist eine Seite von Suchtreffern, absteigend nach Relevanz.
+type SearchPage struct {
+	Items  []BookHit `json:"items"`
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
+
+// Markierungen, mit denen Treffer im Snippet zunächst eingerahmt werden. Als
+// Steuerzeichen kommen sie in Buchtexten nicht vor; SafeSnippet ersetzt sie
+// erst nach dem HTML-Escaping durch <mark>, damit Beschreibungen kein HTML
+// einschleusen können.
+const (
+	MarkStart = "\x02"
+	MarkStop  = "\x03"
+)
+
+var snippetMarks = strings.NewReplacer(MarkStart, "<mark>", MarkStop, "</mark>")
+
+// SafeSnippet escaped einen Ausschnitt mit MarkStart/MarkStop-Markierungen
+// als HTML und setzt die Markierungen als <mark>-Tags ein.
+func SafeSnippet(raw string) string {
+	return snippetMarks.Replace(html.EscapeString(raw))
+}
+
+// Arten von Vorschlägen der Autovervollständigung.
+const (
+	SuggestTitle  = "title"
+	SuggestAuthor = "author"
+)
+
+// SuggestThreshold ist die Mindestähnlichkeit (word_similarity von pg_trgm),
+// ab der ein Titel oder Autor vorgeschlagen wird. Niedriger als der
+// Postgres-Standard von 0.6, damit auch vertauschte Buchstaben treffen.
+const SuggestThreshold = 0.3
+
+// Suggestion ist ein Vorschlag für das Suchfeld. BookID ist nur bei Titeln
+// gesetzt; Autoren werden zusammengefasst.
+type Suggestion struct {
+	Kind   string  `json:"kind"`
+	Text   string  `json:"text"`
+	BookID int     `json:"bookId,omitempty"`
+	Score  float64 `json:"score"`
+}
@@ -1,14 +1,17 @@
 package repository
 
 import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/covers"
+	"bookbazaar-backend/internal/isbn"
 	"bookbazaar-backend/internal/models"
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
-
-	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type BookRepository struct {
@@ -19,169 +22,855 @@ func NewBookRepository(db *sql.DB) *BookRepository {
 	return &BookRepository{db: db}
 }
 
-func (r *BookRepository) GetAll() ([]models.Book, error) {
-	rows, err := r.db.Query("SELECT id, author, name, price, genre, description, descriptionlong, quantity, borrowprice FROM books")
+// bookSortColumns bildet die Sortierungen auf Spalte, Richtung und den Typ ab,
+// mit dem der Sortierschlüssel aus dem Cursor gebunden wird.
+var bookSortColumns = map[string]struct {
+	column, cast string
+	desc         bool
+}{
+	SortName:      {"name", "text", false},
+	SortNameDesc:  {"name", "text", true},
+	SortPrice:     {"price", "numeric", false},
+	SortPriceDesc: {"price", "numeric", true},
+	SortNewest:    {"created_at", "timestamptz", true},
+}
+
+// ListBooks liefert eine Seite des Katalogs. Bei Cursor-Paginierung wird per
+// Keyset (Sortierspalte, id) weitergeblättert, damit tiefe Seiten nicht
+// teurer werden als die erste. Sortierung und Limit prüft der Service.
+func (r *BookRepository) ListBooks(ctx context.Context, q BookQuery) (BookPage, error) {
+	var (
+		where = []string{"archived_at IS NULL"}
+		args  []any
+	)
+	if q.Archived {
+		where[0] = "archived_at IS NOT NULL"
+	}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q.Genre != "" {
+		where = append(where, "lower(genre) = lower("+arg(q.Genre)+")")
+	}
+	if q.Category != 0 {
+		where = append(where, categoryFilter(arg(q.Category)))
+	}
+	if q.Author != "" {
+		where = append(where, "author ILIKE "+arg("%"+escapeLike(q.Author)+"%"))
+	}
+	if q.MinPrice != nil {
+		where = append(where, "price >= "+arg(*q.MinPrice))
+	}
+	if q.MaxPrice != nil {
+		where = append(where, "price <= "+arg(*q.MaxPrice))
+	}
+	if q.InStock {
+		where = append(where, "quantity > 0")
+	}
+	if q.Borrowable {
+		where = append(where, "borrowprice > 0 AND quantity > 0")
+	}
+
+	filter := " WHERE " + strings.Join(where, " AND ")
+
+	page := BookPage{Items: []models.Book{}, Limit: q.Limit, Offset: q.Offset}
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM books"+filter, args...).Scan(&page.Total); err != nil {
+		slog.WarnContext(ctx, "fehler beim zählen des katalogs", slog.Any("error", err))
+		return BookPage{}, err
+	}
+
+	sort := bookSortColumns[q.Sort]
+	dir, cmp := "ASC", ">"
+	if sort.desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if q.Cursor != "" {
+		after, err := DecodeBookCursor(q.Sort, q.Cursor)
+		if err != nil {
+			return BookPage{}, err
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s::%s, %s)",
+			sort.column, cmp, arg(sortKey(q.Sort, after)), sort.cast, arg(after.ID)))
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	// eine Zeile mehr lesen, um zu wissen, ob es eine nächste Seite gibt
+	query := fmt.Sprintf(`SELECT %s FROM books%s ORDER BY %s %s, id %s LIMIT %s OFFSET %s`,
+		bookColumns, filter, sort.column, dir, dir, arg(q.Limit+1), arg(q.Offset))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		log.Println("Fehler bei Query:", err)
+		slog.WarnContext(ctx, "fehler bei query", slog.Any("error", err))
+		return BookPage{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			slog.WarnContext(ctx, "fehler beim scan", slog.Any("error", err))
+			return BookPage{}, err
+		}
+		page.Items = append(page.Items, book)
+	}
+	if err := rows.Err(); err != nil {
+		return BookPage{}, err
+	}
+	rows.Close()
+
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = EncodeBookCursor(q.Sort, page.Items[q.Limit-1])
+	}
+	if err := loadLinks(ctx, r.db, page.Items); err != nil {
+		return BookPage{}, err
+	}
+	return page, nil
+}
+
+// bookColumns sind die Spalten, die scanBook erwartet.
+const bookColumns = `id, author, name, isbn13, price, genre, description, descriptionlong, quantity, borrowprice, cover_hash, created_at, version, archived_at`
+
+// bookColumnsB sind bookColumns mit dem Alias b, für Abfragen mit Joins.
+const bookColumnsB = `b.id, b.author, b.name, b.isbn13, b.price, b.genre, b.description, b.descriptionlong, b.quantity, b.borrowprice, b.cover_hash, b.created_at, b.version, b.archived_at`
+
+// scanBook liest eine Zeile mit bookColumns; extra nimmt weitere Spalten
+// dahinter auf.
+func scanBook(row interface{ Scan(dest ...any) error }, extra ...any) (models.Book, error) {
+	var book models.Book
+	var isbn13, coverHash sql.NullString
+	var createdAt time.Time
+	var archivedAt sql.NullTime
+	dest := []any{&book.ID, &book.Author, &book.Name, &isbn13, &book.Price, &book.Genre, &book.Description, &book.Descriptionlong, &book.Quantity, &book.BorrowPrice, &coverHash, &createdAt, &book.Version, &archivedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return models.Book{}, err
+	}
+	setISBN(&book, isbn13)
+	setCover(&book, coverHash)
+	book.CreatedAt = &createdAt
+	book.ArchivedAt = nullTime(archivedAt)
+	return book, nil
+}
+
+// loadLinks hängt Autoren und Kategorien an die Bücher.
+func loadLinks(ctx context.Context, q querier, books []models.Book) error {
+	if err := loadAuthors(ctx, q, books); err != nil {
+		return err
+	}
+	return loadCategories(ctx, q, books)
+}
+
+// headlineOptions steuert die Snippets von ts_headline: bis zu zwei Fragmente
+// aus der Beschreibung, Treffer mit MarkStart/MarkStop eingerahmt.
+var headlineOptions = fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "`, MarkStart, MarkStop)
+
+// SearchBooks sucht per Volltext über search_vector (siehe Migration
+// 0003_book_search) und sortiert nach ts_rank_cd, bei Gleichstand nach ID.
+func (r *BookRepository) SearchBooks(ctx context.Context, q SearchQuery) (SearchPage, error) {
+	page := SearchPage{Items: []BookHit{}, Limit: q.Limit, Offset: q.Offset}
+
+	const count = `SELECT COUNT(*) FROM books WHERE search_vector @@ websearch_to_tsquery('german', $1) AND archived_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, count, q.Text).Scan(&page.Total); err != nil {
+		slog.WarnContext(ctx, "fehler beim zählen der suchtreffer", slog.Any("error", err))
+		return SearchPage{}, err
+	}
+	if page.Total == 0 {
+		return page, nil
+	}
+
+	const query = `
+		WITH q AS (SELECT websearch_to_tsquery('german', $1) AS query)
+		SELECT ` + bookColumns + `,
+		       ts_rank_cd(b.search_vector, q.query) AS rank,
+		       ts_headline('german', concat_ws(' ', b.description, b.descriptionlong), q.query, $4) AS snippet
+		FROM books b, q
+		WHERE b.search_vector @@ q.query AND b.archived_at IS NULL
+		ORDER BY rank DESC, b.id
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, q.Text, q.Limit, q.Offset, headlineOptions)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler bei der volltextsuche", slog.Any("error", err))
+		return SearchPage{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hit BookHit
+		book, err := scanBook(rows, &hit.Rank, &hit.Snippet)
+		if err != nil {
+			slog.WarnContext(ctx, "fehler beim scan der suchtreffer", slog.Any("error", err))
+			return SearchPage{}, err
+		}
+		hit.Book = book
+		hit.Snippet = SafeSnippet(hit.Snippet)
+		page.Items = append(page.Items, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return SearchPage{}, err
+	}
+
+	books := make([]models.Book, len(page.Items))
+	for i, hit := range page.Items {
+		books[i] = hit.Book
+	}
+	if err := loadLinks(ctx, r.db, books); err != nil {
+		return SearchPage{}, err
+	}
+	for i := range page.Items {
+		page.Items[i].Book = books[i]
+	}
+	return page, nil
+}
+
+// SuggestBooks liefert die ähnlichsten Titel und Autoren zu prefix. Die
+// Operatoren <% nutzen die Trigramm-Indizes aus 0004_book_suggest; die
+// Schwelle wird nur für diese Transaktion gesenkt.
+func (r *BookRepository) SuggestBooks(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)",
+		strconv.FormatFloat(SuggestThreshold, 'f', -1, 64)); err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT kind, text, book_id, score FROM (
+			SELECT 'title' AS kind, name AS text, id AS book_id, word_similarity($1, name) AS score
+			FROM books WHERE $1 <% name AND archived_at IS NULL
+			UNION ALL
+			SELECT 'author', author, 0, MAX(word_similarity($1, author))
+			FROM books WHERE $1 <% author AND archived_at IS NULL GROUP BY author
+		) s
+		ORDER BY score DESC, text
+		LIMIT $2`
+
+	rows, err := tx.QueryContext(ctx, query, prefix, limit)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler bei der vorschlagssuche", slog.Any("error", err))
 		return nil, err
 	}
 	defer rows.Close()
-	var books []models.Book
+
+	suggestions := []Suggestion{}
 	for rows.Next() {
-		var book models.Book
-		if err := rows.Scan(&book.ID, &book.Author, &book.Name, &book.Price, &book.Genre, &book.Description, &book.Descriptionlong, &book.Quantity, &book.BorrowPrice); err != nil {
-			log.Println("Fehler beim Scan:", err)
+		var s Suggestion
+		if err := rows.Scan(&s.Kind, &s.Text, &s.BookID, &s.Score); err != nil {
 			return nil, err
 		}
-		books = append(books, book)
+		suggestions = append(suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return books, nil
+	return suggestions, tx.Commit()
 }
 
-func (r *BookRepository) GetBorrowedBooks(userId int) ([]models.Book, error) {
-	rows, err := r.db.Query("SELECT b.id, b.author, b.name, b.price, b.genre, b.description, b.descriptionlong, b.quantity, b.borrowprice, bb.due_at FROM books b INNER JOIN borrowed_books bb ON bb.book_id = b.id WHERE bb.user_id = $1 AND bb.returned_at IS NULL", userId)
+// nullTime macht aus einer optionalen Spalte einen Zeiger (nil bei NULL).
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// nullISBN speichert ein Buch ohne ISBN als NULL, damit der Unique-Index
+// nur echte ISBNs vergleicht.
+func nullISBN(isbn13 string) sql.NullString {
+	return sql.NullString{String: isbn13, Valid: isbn13 != ""}
+}
+
+// setISBN übernimmt die gespeicherte ISBN-13 und leitet die ISBN-10 ab.
+func setISBN(book *models.Book, isbn13 sql.NullString) {
+	book.ISBN13 = isbn13.String
+	book.ISBN10, _ = isbn.To10(isbn13.String)
+}
+
+// setCover übernimmt den Hash des Covers und baut daraus die URL.
+func setCover(book *models.Book, hash sql.NullString) {
+	book.CoverHash = hash.String
+	book.CoverURL = ""
+	if hash.Valid {
+		book.CoverURL = covers.URL(book.ID, hash.String, covers.DefaultSize)
+	}
+}
+
+// isbnConflict übersetzt die Verletzung von books_isbn13_key (zwei Anfragen
+// mit derselben ISBN gleichzeitig) in denselben Konflikt wie FindDuplicate.
+func isbnConflict(err error, book *models.Book) error {
+	if pgErrorCode(err) != pgUniqueViolation {
+		return nil
+	}
+	return apperr.Conflict("book_exists", "Buch mit ISBN %s existiert bereits", book.ISBN13).WithCause(err)
+}
+
+// escapeLike maskiert die LIKE-Platzhalter, damit "%" oder "_" in der Suche
+// wörtlich genommen werden.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *BookRepository) GetBorrowedBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+bookColumnsB+", bb.due_at FROM books b INNER JOIN borrowed_books bb ON bb.book_id = b.id WHERE bb.user_id = $1 AND bb.returned_at IS NULL", userId)
 
 	if err != nil {
-		log.Println("Fehler bei der BorrowedBooks-Query")
+		slog.WarnContext(ctx, "fehler bei der abfrage ausgeliehener bücher", slog.Any("error", err))
 		return nil, err
 	}
 	defer rows.Close()
 
 	var borrowedBooks []models.Book
 	for rows.Next() {
-		var book models.Book
 		var dueAt time.Time
-		if err := rows.Scan(&book.ID, &book.Author, &book.Name, &book.Price, &book.Genre, &book.Description, &book.Descriptionlong, &book.Quantity, &book.BorrowPrice, &dueAt); err != nil {
-			log.Println("Fehler beim Scan der ausgeliehenen Bücher", err)
+		book, err := scanBook(rows, &dueAt)
+		if err != nil {
+			slog.WarnContext(ctx, "fehler beim scan der ausgeliehenen bücher", slog.Any("error", err))
 			return nil, err
 		}
 		book.DueAt = dueAt.Format("2006-01-02T15:04:05") // lokale Zeit, keine Zeitzone
 		borrowedBooks = append(borrowedBooks, book)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-	return borrowedBooks, nil
+	return borrowedBooks, loadLinks(ctx, r.db, borrowedBooks)
 }
 
-func (r *BookRepository) GetBookByName(bookName string) (*models.Book, error) {
+// GetBookByISBN liefert das Buch mit der ISBN-13 isbn13 (auch archiviert)
+// oder nil, wenn es keins gibt.
+func (r *BookRepository) GetBookByISBN(ctx context.Context, isbn13 string) (*models.Book, error) {
+	return r.getBook(ctx, "isbn13 = $1", isbn13)
+}
 
-	var book models.Book
+// FindDuplicate sucht ein anderes Buch, das book entspricht: bei gesetzter
+// ISBN das Buch mit derselben ISBN, sonst eines mit gleichem Titel und Autor
+// (ohne Groß-/Kleinschreibung). Archivierte Bücher zählen mit, damit sie
+// wiederhergestellt statt doppelt angelegt werden.
+func (r *BookRepository) FindDuplicate(ctx context.Context, book *models.Book) (*models.Book, error) {
+	if book.ISBN13 != "" {
+		return r.getBook(ctx, "isbn13 = $1 AND id <> $2", book.ISBN13, book.ID)
+	}
+	return r.getBook(ctx, "lower(name) = lower($1) AND lower(author) = lower($2) AND id <> $3 ORDER BY id LIMIT 1", book.Name, book.Author, book.ID)
+}
 
-	query := `SELECT id, author, name, price, genre, description, descriptionlong, quantity FROM books Where name=$1`
+// Add legt ein Buch samt Autoren und Kategorien an; unbekannte Autoren (nur
+// Name) werden dabei angelegt, book.Author wird aus den Autoren gebildet.
+func (r *BookRepository) Add(ctx context.Context, book *models.Book) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
 
-	err := r.db.QueryRow(query, bookName).Scan(&book.ID, &book.Author, &book.Name, &book.Price, &book.Genre, &book.Description, &book.Descriptionlong, &book.Quantity)
+	stock := models.StockMovement{Reason: models.MovementAdjustment, Note: "Anlage"}
+	if err := insertBook(ctx, tx, book, stock); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "buch angelegt", slog.Int("book_id", book.ID))
+	return nil
+}
+
+// insertBook legt ein Buch innerhalb von tx an. Der Anfangsbestand wird mit
+// Grund, User und Notiz aus stock ins Journal gebucht.
+func insertBook(ctx context.Context, tx *sql.Tx, book *models.Book, stock models.StockMovement) error {
+	if err := resolveAuthors(ctx, tx, book); err != nil {
+		return err
+	}
+	if err := resolveCategories(ctx, tx, book); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO books (author, name, isbn13, price, genre, description, descriptionlong, quantity, borrowprice) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, version`
+
+	var createdAt time.Time
+	err := tx.QueryRowContext(ctx, query, book.Author, book.Name, nullISBN(book.ISBN13), book.Price, book.Genre, book.Description, book.Descriptionlong, book.Quantity, book.BorrowPrice).Scan(&book.ID, &createdAt, &book.Version)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+		slog.WarnContext(ctx, "fehler beim insert", slog.Any("error", err))
+		if conflict := isbnConflict(err, book); conflict != nil {
+			return conflict
 		}
-		return nil, err
+		return err
+	}
+	if err := linkAuthors(ctx, tx, book); err != nil {
+		return err
 	}
-	return &book, nil
+	if err := linkCategories(ctx, tx, book); err != nil {
+		return err
+	}
+	if book.Quantity != 0 {
+		stock.BookID, stock.Delta = book.ID, book.Quantity
+		if err := recordMovement(ctx, tx, &stock); err != nil {
+			return err
+		}
+	}
+	book.CreatedAt = &createdAt
+	book.ArchivedAt = nil
+	return nil
 }
 
-func (r *BookRepository) Add(book *models.Book) error {
-
-	var exists bool
-	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM books Where name= $1 AND author=$2)", book.Name, book.Author).Scan(&exists)
+// UpsertBooks schreibt einen Import in einer Transaktion: Bücher ohne ID
+// werden angelegt, Bücher mit ID bekommen die Katalogfelder und den Bestand
+// aus dem Import. Schlägt ein Buch fehl, wird nichts geschrieben.
+// Bestandsänderungen werden als Import von userID (0 = ohne User) gebucht.
+func (r *BookRepository) UpsertBooks(ctx context.Context, userID int, books []*models.Book) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
+
+	stock := models.StockMovement{Reason: models.MovementImport, UserID: optionalID(userID)}
+	for _, book := range books {
+		if book.ID == 0 {
+			err = insertBook(ctx, tx, book, stock)
+		} else {
+			err = importUpdate(ctx, tx, book, stock)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
-	if exists {
-		return fmt.Errorf("buch '%s' von '%s' existiert bereits", book.Name, book.Author)
+	slog.InfoContext(ctx, "import geschrieben", slog.Int("books", len(books)))
+	return nil
+}
+
+// exportBatch ist die Anzahl Bücher, die ExportBooks auf einmal liest.
+const exportBatch = 500
+
+// ExportBooks ruft fn für jedes nicht archivierte Buch in ID-Reihenfolge auf.
+// Gelesen wird blockweise in einer lesenden REPEATABLE-READ-Transaktion: der
+// Export ist ein konsistenter Stand, ohne dass der Katalog im Speicher liegt.
+// Ein Fehler von fn bricht den Export ab und wird zurückgegeben.
+func (r *BookRepository) ExportBooks(ctx context.Context, fn func(book *models.Book) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
+
+	afterID := 0
+	for {
+		books, err := exportBatchAfter(ctx, tx, afterID)
+		if err != nil {
+			return err
+		}
+		for i := range books {
+			if err := fn(&books[i]); err != nil {
+				return err
+			}
+		}
+		if len(books) < exportBatch {
+			return tx.Commit()
+		}
+		afterID = books[len(books)-1].ID
 	}
+}
+
+func exportBatchAfter(ctx context.Context, tx *sql.Tx, afterID int) ([]models.Book, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT `+bookColumns+` FROM books
+		WHERE archived_at IS NULL AND id > $1
+		ORDER BY id
+		LIMIT $2`, afterID, exportBatch)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim export", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
 
-	query := `INSERT INTO books (author, name, price, genre, description, descriptionlong, quantity, borrowprice) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	var books []models.Book
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return books, loadLinks(ctx, tx, books)
+}
 
-	err = r.db.QueryRow(query, book.Author, book.Name, book.Price, book.Genre, book.Description, book.Descriptionlong, book.Quantity, book.BorrowPrice).Scan(&book.ID)
+// importUpdate überschreibt ein bestehendes Buch mit einer Importzeile.
+// Archivierte Bücher bleiben unangetastet. Die Differenz zum bisherigen
+// Bestand wird ins Journal gebucht.
+func importUpdate(ctx context.Context, tx *sql.Tx, book *models.Book, stock models.StockMovement) error {
+	if err := resolveAuthors(ctx, tx, book); err != nil {
+		return err
+	}
+	if err := resolveCategories(ctx, tx, book); err != nil {
+		return err
+	}
 
+	// old sperrt die Zeile und liefert den Bestand vor dem Update
+	const query = `
+		UPDATE books b
+		SET author = $2, name = $3, price = $4, genre = $5, description = $6, descriptionlong = $7,
+		    borrowprice = $8, isbn13 = $9, quantity = $10, version = b.version + 1
+		FROM (SELECT id, quantity FROM books WHERE id = $1 FOR UPDATE) old
+		WHERE b.id = old.id AND b.archived_at IS NULL
+		RETURNING b.version, b.created_at, old.quantity`
+
+	var createdAt time.Time
+	var oldQuantity int
+	err := tx.QueryRowContext(ctx, query, book.ID, book.Author, book.Name, book.Price, book.Genre, book.Description,
+		book.Descriptionlong, book.BorrowPrice, nullISBN(book.ISBN13), book.Quantity).Scan(&book.Version, &createdAt, &oldQuantity)
+	if err == sql.ErrNoRows {
+		return bookArchived(book.ID)
+	}
 	if err != nil {
-		log.Println("Fehler beim Insert", err)
+		logPgError(ctx, "buch konnte nicht importiert werden", err)
+		if conflict := isbnConflict(err, book); conflict != nil {
+			return conflict
+		}
+		return err
+	}
+	if err := linkAuthors(ctx, tx, book); err != nil {
+		return err
+	}
+	if err := linkCategories(ctx, tx, book); err != nil {
 		return err
 	}
-	log.Println("Neues Buch ID:", book.ID)
+	if delta := book.Quantity - oldQuantity; delta != 0 {
+		stock.BookID, stock.Delta = book.ID, delta
+		if err := recordMovement(ctx, tx, &stock); err != nil {
+			return err
+		}
+	}
+	book.CreatedAt = &createdAt
 	return nil
 }
 
-func (r *BookRepository) Delete(id int) error {
+// GetBookByID liefert ein Buch mit Version oder nil, wenn es keins gibt.
+func (r *BookRepository) GetBookByID(ctx context.Context, id int) (*models.Book, error) {
+	return r.getBook(ctx, "id = $1", id)
+}
 
-	query := `DELETE FROM books WHERE id=$1`
+// Availability ist die aktuelle Verfügbarkeit eines Buchs. User fehlt ohne
+// angemeldeten User.
+type Availability struct {
+	InStock   int            `json:"inStock"`
+	OnLoan    int            `json:"onLoan"`    // offene Ausleihen
+	NextDueAt *time.Time     `json:"nextDueAt"` // früheste noch nicht fällige Rückgabe
+	User      *UserBookState `json:"user,omitempty"`
+}
 
-	result, err := r.db.Exec(query, id)
+// UserBookState sagt, was der angemeldete User mit dem Buch zu tun hat.
+type UserBookState struct {
+	Favorite  bool `json:"favorite"`
+	InCart    bool `json:"inCart"` // mit gültiger Reservierung
+	OnLoan    bool `json:"onLoan"`
+	Purchased bool `json:"purchased"`
+}
 
+// GetBookDetail liest ein Buch (auch archiviert) mit Ausleihen und dem Stand
+// des Users (0 = ohne) in einer Abfrage, damit Bestand und Verfügbarkeit
+// zueinander passen. Nur Autoren und Kategorien kommen danach.
+func (r *BookRepository) GetBookDetail(ctx context.Context, id, userID int) (*models.Book, Availability, error) {
+	var a Availability
+	var state UserBookState
+	var nextDue sql.NullTime
+	book, err := scanBook(r.db.QueryRowContext(ctx, `
+		SELECT `+bookColumnsB+`,
+		       (SELECT COUNT(*) FROM borrowed_books WHERE book_id = b.id AND returned_at IS NULL),
+		       (SELECT MIN(due_at) FROM borrowed_books WHERE book_id = b.id AND returned_at IS NULL AND due_at > NOW()),
+		       EXISTS (SELECT 1 FROM user_favorites WHERE book_id = b.id AND user_id = $2),
+		       EXISTS (SELECT 1 FROM user_cart WHERE cart_book_id = b.id AND user_id = $2 AND removed_at IS NULL
+		               AND (reservation_expires_at IS NULL OR reservation_expires_at > NOW())),
+		       EXISTS (SELECT 1 FROM borrowed_books WHERE book_id = b.id AND user_id = $2 AND returned_at IS NULL),
+		       EXISTS (SELECT 1 FROM user_books WHERE book_id = b.id AND user_id = $2)
+		FROM books b
+		WHERE b.id = $1`, id, userID), &a.OnLoan, &nextDue, &state.Favorite, &state.InCart, &state.OnLoan, &state.Purchased)
+	if err == sql.ErrNoRows {
+		return nil, Availability{}, apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", id)
+	}
 	if err != nil {
-		log.Println("Fehler beim Delete", err)
+		slog.WarnContext(ctx, "fehler beim laden des buchs mit verfügbarkeit", slog.Int("book_id", id), slog.Any("error", err))
+		return nil, Availability{}, err
+	}
+	a.InStock = book.Quantity
+	a.NextDueAt = nullTime(nextDue)
+	if userID != 0 {
+		a.User = &state
+	}
+	books := []models.Book{book}
+	if err := loadLinks(ctx, r.db, books); err != nil {
+		return nil, Availability{}, err
+	}
+	return &books[0], a, nil
+}
+
+// getBook liest das erste Buch, auf das where zutrifft, oder nil.
+func (r *BookRepository) getBook(ctx context.Context, where string, args ...any) (*models.Book, error) {
+	book, err := scanBook(r.db.QueryRowContext(ctx, "SELECT "+bookColumns+" FROM books WHERE "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	books := []models.Book{book}
+	if err := loadLinks(ctx, r.db, books); err != nil {
+		return nil, err
+	}
+	return &books[0], nil
+}
+
+// UpdateBook überschreibt die Katalogfelder eines Buchs, sofern seine Version
+// noch expectedVersion ist (0 = ohne Prüfung), und setzt book.Version auf die
+// neue Version. Die ID bleibt gleich, Käufe und Ausleihen hängen weiter daran.
+// Den Bestand ändert UpdateBook nicht – der wird von Käufen und Ausleihen
+// ohne Versionssprung bewegt und würde sonst mit veralteten Werten überschrieben;
+// book.Quantity enthält danach den aktuellen Bestand.
+func (r *BookRepository) UpdateBook(ctx context.Context, book *models.Book, expectedVersion int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := resolveAuthors(ctx, tx, book); err != nil {
+		return err
+	}
+	if err := resolveCategories(ctx, tx, book); err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	const query = `
+		UPDATE books
+		SET author = $2, name = $3, price = $4, genre = $5, description = $6, descriptionlong = $7,
+		    borrowprice = $8, isbn13 = $10, version = version + 1
+		WHERE id = $1 AND ($9 = 0 OR version = $9)
+		RETURNING version, quantity, created_at, archived_at`
+
+	var createdAt time.Time
+	var archivedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, query, book.ID, book.Author, book.Name, book.Price, book.Genre, book.Description,
+		book.Descriptionlong, book.BorrowPrice, expectedVersion, nullISBN(book.ISBN13)).Scan(&book.Version, &book.Quantity, &createdAt, &archivedAt)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		// entweder gibt es das Buch nicht oder jemand war schneller
+		current, err := r.GetBookByID(ctx, book.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", book.ID)
+		}
+		return apperr.PreconditionFailed("version_mismatch", "Buch mit ID %d wurde inzwischen geändert (aktuelle Version %d)", book.ID, current.Version)
+	}
+	if err != nil {
+		logPgError(ctx, "buch konnte nicht aktualisiert werden", err)
+		if conflict := isbnConflict(err, book); conflict != nil {
+			return conflict
+		}
+		if pgErrorCode(err) == pgCheckViolation {
+			return apperr.Validation("invalid_book", "Ungültige Werte für Buch mit ID %d", book.ID).WithCause(err)
+		}
+		return err
+	}
+	if err := linkAuthors(ctx, tx, book); err != nil {
+		return err
+	}
+	if err := linkCategories(ctx, tx, book); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	book.CreatedAt = &createdAt
+	book.ArchivedAt = nullTime(archivedAt)
+	slog.InfoContext(ctx, "buch aktualisiert", slog.Int("book_id", book.ID), slog.Int("version", book.Version))
+	return nil
+}
+
+// Archive nimmt ein Buch aus dem Katalog, statt es zu löschen: Käufe und
+// Ausleihen verweisen weiter darauf, offene Warenkorb-Einträge werden
+// entfernt. Ein bereits archiviertes Buch bleibt unverändert.
+func (r *BookRepository) Archive(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
 
+	res, err := tx.ExecContext(ctx, "UPDATE books SET archived_at = NOW(), version = version + 1 WHERE id = $1 AND archived_at IS NULL", id)
 	if err != nil {
+		slog.WarnContext(ctx, "fehler beim archivieren", slog.Int("book_id", id), slog.Any("error", err))
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
 		return err
+	} else if n == 0 {
+		return r.requireBook(ctx, tx, id)
 	}
 
-	if rowsAffected == 0 {
-		log.Println("Kein Buch gefunden mit ID: ", id)
-		return fmt.Errorf("kein Buch mit ID %d gefunden", id)
+	_, err = tx.ExecContext(ctx, "UPDATE user_cart SET removed_at = NOW() WHERE cart_book_id = $1 AND removed_at IS NULL", id)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim leeren der warenkörbe", slog.Int("book_id", id), slog.Any("error", err))
+		return err
 	}
 
-	log.Println("Buch erfolgreich gelöscht mit ID:", id)
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "buch archiviert", slog.Int("book_id", id))
+	return nil
+}
 
+// SetCover setzt den Hash des aktuellen Covers ("" entfernt es). Wie jede
+// sichtbare Änderung am Buch erhöht das die Version.
+func (r *BookRepository) SetCover(ctx context.Context, id int, hash string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE books SET cover_hash = NULLIF($2, ''), version = version + 1 WHERE id = $1", id, hash)
+	if err != nil {
+		logPgError(ctx, "cover konnte nicht gespeichert werden", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", id)
+	}
 	return nil
 }
 
-func (r *BookRepository) BuyBook(userID, bookID int) error {
-	tx, err := r.db.Begin()
+// CoverHash liefert nur den Hash des aktuellen Covers ("" ohne Cover), für
+// die Auslieferung der Bilder ohne das ganze Buch zu laden.
+func (r *BookRepository) CoverHash(ctx context.Context, id int) (string, error) {
+	var hash sql.NullString
+	err := r.db.QueryRowContext(ctx, "SELECT cover_hash FROM books WHERE id = $1", id).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", id)
+	}
+	return hash.String, err
+}
+
+// Restore nimmt ein archiviertes Buch wieder in den Katalog auf.
+func (r *BookRepository) Restore(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE books SET archived_at = NULL, version = version + 1 WHERE id = $1 AND archived_at IS NOT NULL", id)
 	if err != nil {
+		slog.WarnContext(ctx, "fehler beim wiederherstellen", slog.Int("book_id", id), slog.Any("error", err))
 		return err
 	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return r.requireBook(ctx, r.db, id)
+	}
+	slog.InfoContext(ctx, "buch wiederhergestellt", slog.Int("book_id", id))
+	return nil
+}
+
+// queryRower ist *sql.DB oder *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// requireBook liefert book_not_found, wenn es kein Buch mit der ID gibt.
+func (r *BookRepository) requireBook(ctx context.Context, q queryRower, id int) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM books WHERE id = $1)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", id)
+	}
+	return nil
+}
+
+// bookArchived ist der Fehler für Käufe, Ausleihen und Warenkorb bei
+// archivierten Büchern.
+func bookArchived(id int) error {
+	return apperr.Conflict("book_archived", "Buch mit ID %d wird nicht mehr angeboten", id)
+}
+
+func (r *BookRepository) BuyBook(ctx context.Context, userID, bookID int) (float64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
 	defer tx.Rollback()
 
 	// Guthaben prüfen
-	var balance float64
-	err = tx.QueryRow("SELECT balance FROM users WHERE id=$1", userID).Scan(&balance)
-
+	balance, err := lockBalance(ctx, tx, userID)
 	if err != nil {
-		log.Println("Fehler beim Scannen des Guthabens")
-		return err
+		slog.WarnContext(ctx, "fehler beim scannen des guthabens", slog.Int("book_id", bookID), slog.Any("error", err))
+		return 0, err
 	}
 
-	// Menge prüfen
+	// Menge und Preis prüfen, Zeile bis zum Commit sperren
 	var quantity int
-	err = tx.QueryRow("SELECT quantity FROM books WHERE id=$1", bookID).Scan(&quantity)
+	var price float64
+	var archived bool
+	err = tx.QueryRowContext(ctx, "SELECT quantity, price, archived_at IS NOT NULL FROM books WHERE id=$1 FOR UPDATE", bookID).Scan(&quantity, &price, &archived)
+	if err == sql.ErrNoRows {
+		return 0, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookID)
+	}
 	if err != nil {
-		log.Println("Fehler beim Scannen der Menge")
-		return err
+		slog.WarnContext(ctx, "fehler beim scannen der menge", slog.Int("book_id", bookID), slog.Any("error", err))
+		return 0, err
+	}
+	if archived {
+		return 0, bookArchived(bookID)
 	}
 
 	if quantity < 1 {
-		return fmt.Errorf("buch ist nicht mehr verfügbar")
+		return 0, apperr.OutOfStock("out_of_stock", "buch ist nicht mehr verfügbar")
 	}
 
-	var price float64
-	err = tx.QueryRow("SELECT price FROM books WHERE id=$1", bookID).Scan(&price)
-	if err != nil {
-		log.Println("Fehler beim Setzen des Preises")
-		return err
+	if balance < price {
+		return 0, apperr.InsufficientBalance("insufficient_balance", "nicht genügend Guthaben: %.2f benötigt, %.2f verfügbar", price, balance)
 	}
 
-	_, err = tx.Exec("UPDATE users SET balance = balance - $1 WHERE id=$2", price, userID)
+	_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance - $1 WHERE id=$2", price, userID)
 	if err != nil {
-		log.Println("Fehler beim Update Kauf")
-		return err
+		slog.WarnContext(ctx, "fehler beim abbuchen des kaufpreises", slog.Int("book_id", bookID), slog.Any("error", err))
+		return 0, err
 	}
 
-	_, err = tx.Exec("UPDATE books SET quantity = quantity - 1 Where id=$1", bookID)
+	_, err = tx.ExecContext(ctx, "UPDATE books SET quantity = quantity - 1 Where id=$1", bookID)
 	if err != nil {
-		log.Println("Fehler beim Update Menge Buch")
+		slog.WarnContext(ctx, "fehler beim update menge buch", slog.Int("book_id", bookID), slog.Any("error", err))
+		return 0, err
 	}
 
-	_, err = tx.Exec("INSERT INTO user_books (user_id, book_id) VALUES ($1, $2)", userID, bookID)
+	var purchaseID int
+	err = tx.QueryRowContext(ctx, "INSERT INTO user_books (user_id, book_id) VALUES ($1, $2) ON CONFLICT(user_id, book_id) DO UPDATE SET quantity = user_books.quantity + 1 RETURNING id", userID, bookID).Scan(&purchaseID)
 	if err != nil {
-		log.Println("Fehler beim Insert Kauf")
-		return err
+		slog.WarnContext(ctx, "fehler beim insert kauf", slog.Int("book_id", bookID), slog.Any("error", err))
+		return 0, err
+	}
+	err = recordMovement(ctx, tx, &models.StockMovement{BookID: bookID, Delta: -1, Reason: models.MovementSale, UserID: &userID, PurchaseID: &purchaseID})
+	if err != nil {
+		return 0, err
 	}
 
-	return tx.Commit()
+	return price, tx.Commit()
+}
+
+// lockBalance liest das Guthaben und sperrt die User-Zeile bis zum Ende der
+// Transaktion, damit parallele Käufe nicht beide dasselbe Guthaben sehen.
+func lockBalance(ctx context.Context, tx *sql.Tx, userID int) (float64, error) {
+	var balance float64
+	err := tx.QueryRowContext(ctx, "SELECT balance FROM users WHERE id=$1 FOR UPDATE", userID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, apperr.NotFound("user_not_found", "user mit ID %d existiert nicht", userID)
+	}
+	return balance, err
 }
 
 type Purchase struct {
@@ -189,22 +878,20 @@ type Purchase struct {
 	Quantity int `json:"quantity"`
 }
 
-func (r *BookRepository) BuyBooks(userID int, purchases []Purchase) error {
-	tx, err := r.db.Begin()
+func (r *BookRepository) BuyBooks(ctx context.Context, userID int, purchases []Purchase) (float64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	defer tx.Rollback()
 
 	// Guthaben prüfen
-	var balance float64
-	err = tx.QueryRow("SELECT balance FROM users WHERE id=$1", userID).Scan(&balance)
-
+	balance, err := lockBalance(ctx, tx, userID)
 	if err != nil {
-		log.Println("Fehler beim Scannen des Guthabens")
-		return err
+		slog.WarnContext(ctx, "fehler beim scannen des guthabens", slog.Any("error", err))
+		return 0, err
 	}
 
 	var totalprice float64
@@ -212,94 +899,103 @@ func (r *BookRepository) BuyBooks(userID int, purchases []Purchase) error {
 	for _, p := range purchases {
 		var price float64
 		var stock int
-		err = tx.QueryRow("Select price, quantity FROM books Where id=$1", p.BookId).Scan(&price, &stock)
+		var archived bool
+		err = tx.QueryRowContext(ctx, "Select price, quantity, archived_at IS NOT NULL FROM books Where id=$1 FOR UPDATE", p.BookId).Scan(&price, &stock, &archived)
 
 		if err != nil {
 			if err == sql.ErrNoRows {
-				return fmt.Errorf("buch mit ID %d existiert nicht", p.BookId)
+				return 0, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", p.BookId)
 			}
-			log.Println("Fehler beim Preis und Bestand abfragen:", err)
-			return err
+			slog.WarnContext(ctx, "fehler beim preis und bestand abfragen", slog.Int("book_id", p.BookId), slog.Any("error", err))
+			return 0, err
+		}
+		if archived {
+			return 0, bookArchived(p.BookId)
 		}
 		if stock < p.Quantity {
-			return fmt.Errorf("nicht genug Bestand für BuchID %d", p.BookId)
+			return 0, apperr.OutOfStock("out_of_stock", "nicht genug Bestand für BuchID %d", p.BookId)
 		}
 		totalprice += price * float64(p.Quantity)
 	}
 
 	if balance < totalprice {
-		return fmt.Errorf("nicht genügend Guthaben: %.2f benötigt, %.2f verfügbar", totalprice, balance)
+		return 0, apperr.InsufficientBalance("insufficient_balance", "nicht genügend Guthaben: %.2f benötigt, %.2f verfügbar", totalprice, balance)
 	}
 
-	_, err = tx.Exec("UPDATE users SET balance = balance - $1 WHERE id=$2", totalprice, userID)
+	_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance - $1 WHERE id=$2", totalprice, userID)
 	if err != nil {
-		log.Println("Fehler beim Guthaben-Update")
-		return err
+		slog.WarnContext(ctx, "fehler beim guthaben-update", slog.Any("error", err))
+		return 0, err
 	}
 
 	for _, p := range purchases {
-		_, err = tx.Exec("Update books Set quantity = quantity- $1 Where id=$2", p.Quantity, p.BookId)
+		_, err = tx.ExecContext(ctx, "Update books Set quantity = quantity- $1 Where id=$2", p.Quantity, p.BookId)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
-		_, err = tx.Exec("INSERT INTO user_books (user_id, book_id, quantity) VALUES ($1,$2,$3) ON CONFLICT(user_id, book_id) DO UPDATE SET quantity = user_books.quantity + EXCLUDED.quantity", userID, p.BookId, p.Quantity)
+		var purchaseID int
+		err = tx.QueryRowContext(ctx, "INSERT INTO user_books (user_id, book_id, quantity) VALUES ($1,$2,$3) ON CONFLICT(user_id, book_id) DO UPDATE SET quantity = user_books.quantity + EXCLUDED.quantity RETURNING id", userID, p.BookId, p.Quantity).Scan(&purchaseID)
 		if err != nil {
-			log.Println("Fehler beim Insert in user_books")
-			return err
+			slog.WarnContext(ctx, "fehler beim insert in user_books", slog.Int("book_id", p.BookId), slog.Any("error", err))
+			return 0, err
+		}
+		err = recordMovement(ctx, tx, &models.StockMovement{BookID: p.BookId, Delta: -p.Quantity, Reason: models.MovementSale, UserID: &userID, PurchaseID: &purchaseID})
+		if err != nil {
+			return 0, err
 		}
 	}
-	return tx.Commit()
+	return totalprice, tx.Commit()
 }
 
-func (r *BookRepository) BorrowBook(userId, bookId, days int) error {
-	tx, err := r.db.Begin()
+func (r *BookRepository) BorrowBook(ctx context.Context, userId, bookId, days int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("datenbank nicht erreichbar")
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
 	}
 
 	defer tx.Rollback()
 
 	// Guthaben prüfen
-	var balance float64
-
-	err = tx.QueryRow("SELECT balance FROM users WHERE id=$1", userId).Scan(&balance)
-
+	balance, err := lockBalance(ctx, tx, userId)
 	if err != nil {
 		return err
 	}
 
-	// Menge prüfen
+	// Menge und Leihpreis prüfen
 	var quantity int
-	err = tx.QueryRow("SELECT quantity FROM books WHERE id=$1", bookId).Scan(&quantity)
+	var borrowprice float64
+	var archived bool
+	err = tx.QueryRowContext(ctx, "SELECT quantity, borrowprice, archived_at IS NOT NULL FROM books WHERE id=$1 FOR UPDATE", bookId).Scan(&quantity, &borrowprice, &archived)
+	if err == sql.ErrNoRows {
+		return apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookId)
+	}
 	if err != nil {
-		log.Println("Fehler beim Scannen der Menge")
+		slog.WarnContext(ctx, "fehler beim scannen der menge", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
+	if archived {
+		return bookArchived(bookId)
+	}
 
 	if quantity < 1 {
-		return fmt.Errorf("buch ist nicht mehr verfügbar")
+		return apperr.OutOfStock("out_of_stock", "buch ist nicht mehr verfügbar")
 	}
 
-	var borrowprice float64
-
-	err = tx.QueryRow("SELECT borrowprice FROM books WHERE id=$1", bookId).Scan(&borrowprice)
-
-	if err != nil {
-		log.Println("Fehler bei borrowPrice query")
-		return err
+	if balance < borrowprice {
+		return apperr.InsufficientBalance("insufficient_balance", "nicht genügend Guthaben: %.2f benötigt, %.2f verfügbar", borrowprice, balance)
 	}
 
 	//Preis vom Guthaben abziehen
-	_, err = tx.Exec("UPDATE users Set balance = balance - $1 Where id=$2", borrowprice, userId)
+	_, err = tx.ExecContext(ctx, "UPDATE users Set balance = balance - $1 Where id=$2", borrowprice, userId)
 	if err != nil {
-		log.Println("Fehler beim Update Userbalance")
+		slog.WarnContext(ctx, "fehler beim update userbalance", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
 
-	_, err = tx.Exec("Update books Set quantity = quantity - $1 Where id=$2", 1, bookId)
+	_, err = tx.ExecContext(ctx, "Update books Set quantity = quantity - $1 Where id=$2", 1, bookId)
 	if err != nil {
-		log.Println("Fehler beim Update quantity - 1")
+		slog.WarnContext(ctx, "fehler beim update quantity - 1", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
 
@@ -307,67 +1003,121 @@ func (r *BookRepository) BorrowBook(userId, bookId, days int) error {
 	dueAt := time.Now().In(loc).Add(10 * time.Minute)
 
 	// Relationstabelle Eintrag
-	_, err = tx.Exec("INSERT INTO borrowed_books (user_id, book_id, borrowed_at, due_at) VALUES ($1, $2, Now(), $3)", userId, bookId, dueAt)
+	var loanID int
+	err = tx.QueryRowContext(ctx, "INSERT INTO borrowed_books (user_id, book_id, borrowed_at, due_at) VALUES ($1, $2, Now(), $3) RETURNING id", userId, bookId, dueAt).Scan(&loanID)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim insert in borrowed_books", slog.Int("book_id", bookId), slog.Any("error", err))
+		return err
+	}
+	err = recordMovement(ctx, tx, &models.StockMovement{BookID: bookId, Delta: -1, Reason: models.MovementLoan, UserID: &userId, LoanID: &loanID})
 	if err != nil {
-		log.Println("Fehler beim Insert in borrowed_books")
 		return err
 	}
 
 	return tx.Commit()
 }
 
-func (r *BookRepository) GiveBorrowedBookBack(userId, bookId int) error {
-	tx, err := r.db.Begin()
+func (r *BookRepository) GiveBorrowedBookBack(ctx context.Context, userId, bookId int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 
 	if err != nil {
-		log.Println("Datenbank nicht erreichbar:", err)
+		slog.WarnContext(ctx, "datenbank nicht erreichbar", slog.Any("error", err))
 		return err
 	}
 
 	defer tx.Rollback()
 
-	_, err = tx.Exec("UPDATE borrowed_books Set returned_at= NOW() WHERE book_id = $1 AND user_id = $2", bookId, userId)
+	// nur eine offene Ausleihe zurückgeben, sonst würde der Bestand mehrfach erhöht
+	var loanID int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE borrowed_books SET returned_at = NOW()
+		WHERE id = (
+			SELECT id FROM borrowed_books
+			WHERE book_id = $1 AND user_id = $2 AND returned_at IS NULL
+			ORDER BY due_at
+			LIMIT 1
+			FOR UPDATE
+		)
+		RETURNING id`, bookId, userId).Scan(&loanID)
+	if err == sql.ErrNoRows {
+		return apperr.NotFound("loan_not_found", "keine offene Ausleihe für Buch %d gefunden", bookId)
+	}
 	if err != nil {
-		log.Println("Fehler beim update returned_at")
+		slog.WarnContext(ctx, "fehler beim update returned_at", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
 
-	_, err = tx.Exec("Update books Set quantity = quantity + $1 Where id=$2", 1, bookId)
+	_, err = tx.ExecContext(ctx, "Update books Set quantity = quantity + $1 Where id=$2", 1, bookId)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim update book quantity + 1", slog.Int("book_id", bookId), slog.Any("error", err))
+		return err
+	}
+	err = recordMovement(ctx, tx, &models.StockMovement{BookID: bookId, Delta: 1, Reason: models.MovementReturn, UserID: &userId, LoanID: &loanID})
 	if err != nil {
-		log.Println("Fehler beim update book quantity + 1")
 		return err
 	}
 
 	return tx.Commit()
 }
 
-func (r *BookRepository) AddToCart(userId, bookId int) error {
-	tx, err := r.db.Begin()
+func (r *BookRepository) AddToCart(ctx context.Context, userId, bookId int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("datenbank nicht erreichbar")
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
 	}
 
 	defer tx.Rollback()
 
-	_, err = tx.Exec("INSERT INTO user_cart (user_id, cart_book_id, reservation_expires_at) VALUES ($1, $2, Now() + INTERVAL '5 minutes') ON CONFLICT (user_id, cart_book_id) DO UPDATE SET reservation_expires_at = EXCLUDED.reservation_expires_at, removed_at = NULL", userId, bookId)
+	// Zeile teilen-sperren, damit ein paralleles Archivieren abwartet
+	var archived bool
+	err = tx.QueryRowContext(ctx, "SELECT archived_at IS NOT NULL FROM books WHERE id=$1 FOR SHARE", bookId).Scan(&archived)
+	if err == sql.ErrNoRows {
+		return apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookId)
+	}
 	if err != nil {
-		log.Println("Fehler beim Insert in user_cart")
+		return err
+	}
+	if archived {
+		return bookArchived(bookId)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_cart (user_id, cart_book_id, reservation_expires_at) VALUES ($1, $2, Now() + INTERVAL '5 minutes') ON CONFLICT (user_id, cart_book_id) DO UPDATE SET reservation_expires_at = EXCLUDED.reservation_expires_at, removed_at = NULL", userId, bookId)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim insert in user_cart", slog.Int("book_id", bookId), slog.Any("error", err))
+		if pgErrorCode(err) == pgForeignKeyViolation {
+			return apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookId).WithCause(err)
+		}
 		return err
 	}
 
 	return tx.Commit()
 }
 
-func (r *BookRepository) GetCartBooks(userId int) ([]models.Book, error) {
-	tx, err := r.db.Begin()
+// ExpireCartReservations markiert alle abgelaufenen Reservierungen als entfernt
+// und gibt die Anzahl der betroffenen Einträge zurück.
+func (r *BookRepository) ExpireCartReservations(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_cart
+		SET removed_at = NOW()
+		WHERE removed_at IS NULL
+		  AND reservation_expires_at IS NOT NULL
+		  AND reservation_expires_at <= NOW()
+	`)
 	if err != nil {
-		return nil, fmt.Errorf("datenbank nicht erreichbar")
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *BookRepository) GetCartBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("datenbank nicht erreichbar: %w", err)
 	}
 	defer tx.Rollback()
 
 	query := `
-      SELECT b.id, b.author, b.name, b.price, b.genre, b.description, b.descriptionlong,
-             b.quantity, b.borrowprice, uc.id AS cart_id, uc.reservation_expires_at
+      SELECT ` + bookColumnsB + `, uc.id AS cart_id, uc.reservation_expires_at
       FROM books b
       INNER JOIN user_cart uc ON b.id = uc.cart_book_id
       WHERE uc.user_id = $1
@@ -375,9 +1125,9 @@ func (r *BookRepository) GetCartBooks(userId int) ([]models.Book, error) {
 		AND uc.removed_at IS NULL
 	`
 
-	rows, err := tx.Query(query, userId)
+	rows, err := tx.QueryContext(ctx, query, userId)
 	if err != nil {
-		log.Println("Fehler bei der Cart-Query", err)
+		slog.WarnContext(ctx, "fehler bei der cart-query", slog.Any("error", err))
 		return nil, err
 	}
 	defer rows.Close()
@@ -385,24 +1135,12 @@ func (r *BookRepository) GetCartBooks(userId int) ([]models.Book, error) {
 	var books []models.Book
 
 	for rows.Next() {
-		var book models.Book
 		var cartID sql.NullInt64
 		var reservation sql.NullTime
 
-		if err := rows.Scan(
-			&book.ID,
-			&book.Author,
-			&book.Name,
-			&book.Price,
-			&book.Genre,
-			&book.Description,
-			&book.Descriptionlong,
-			&book.Quantity,
-			&book.BorrowPrice,
-			&cartID,
-			&reservation,
-		); err != nil {
-			log.Println("Fehler beim Scan der Cart-Zeile:", err)
+		book, err := scanBook(rows, &cartID, &reservation)
+		if err != nil {
+			slog.WarnContext(ctx, "fehler beim scan der cart-zeile", slog.Any("error", err))
 			return nil, err
 		}
 
@@ -417,14 +1155,15 @@ func (r *BookRepository) GetCartBooks(userId int) ([]models.Book, error) {
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	rows.Close()
 
-	return books, nil
+	return books, loadLinks(ctx, tx, books)
 }
 
-func (r *BookRepository) RemoveFromCart(userId, bookId int) error {
-	tx, err := r.db.Begin()
+func (r *BookRepository) RemoveFromCart(ctx context.Context, userId, bookId int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Println("datenbank nicht erreichbar")
+		slog.WarnContext(ctx, "datenbank nicht erreichbar", slog.Any("error", err))
 		return err
 	}
 
@@ -432,20 +1171,20 @@ func (r *BookRepository) RemoveFromCart(userId, bookId int) error {
 
 	query := "DELETE FROM user_cart WHERE user_id=$1 AND cart_book_id=$2"
 
-	res, err := tx.Exec(query, userId, bookId)
+	res, err := tx.ExecContext(ctx, query, userId, bookId)
 	if err != nil {
-		log.Println("Repository Fehler bei RemoveFromCart", err)
+		slog.WarnContext(ctx, "fehler beim entfernen aus dem warenkorb", slog.Any("error", err))
 		return err
 	}
 
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		log.Println("Fehler beim Abfragen der betroffenen Zeilen in RemoveFromCart", err)
+		slog.WarnContext(ctx, "fehler beim abfragen der betroffenen zeilen", slog.Any("error", err))
 		return err
 	}
 
 	if rowsAffected == 0 {
-		log.Println("RemoveFromCart: kein Eintrag gefunden für user", userId, "book", bookId)
+		slog.DebugContext(ctx, "warenkorb: kein eintrag gefunden", slog.Int("book_id", bookId))
 		if err := tx.Commit(); err != nil {
 			return err
 		}
@@ -453,26 +1192,17 @@ func (r *BookRepository) RemoveFromCart(userId, bookId int) error {
 	}
 
 	if err := tx.Commit(); err != nil {
-		log.Println("Fehler beim Commit in RemoveFromCart", err)
+		slog.WarnContext(ctx, "fehler beim commit", slog.Any("error", err))
 		return err
 	}
 
-	log.Println("RemoveFromCart: Eintrag entfernt für user", userId, "book", bookId)
+	slog.DebugContext(ctx, "warenkorb: eintrag entfernt", slog.Int("book_id", bookId))
 	return nil
 }
 
-func (r *BookRepository) GetFavoriteBooks(userId int) ([]models.Book, error) {
-	rows, err := r.db.Query(`
-        SELECT
-            b.id,
-            b.author,
-            b.name,
-            b.price,
-            b.genre,
-            b.description,
-            b.descriptionlong,
-            b.quantity,
-            b.borrowprice
+func (r *BookRepository) GetFavoriteBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+bookColumnsB+`
         FROM books b
         INNER JOIN user_favorites uf ON b.id = uf.book_id
         WHERE uf.user_id = $1
@@ -485,18 +1215,8 @@ func (r *BookRepository) GetFavoriteBooks(userId int) ([]models.Book, error) {
 
 	var books []models.Book
 	for rows.Next() {
-		var book models.Book
-		if err := rows.Scan(
-			&book.ID,
-			&book.Author,
-			&book.Name,
-			&book.Price,
-			&book.Genre,
-			&book.Description,
-			&book.Descriptionlong,
-			&book.Quantity,
-			&book.BorrowPrice,
-		); err != nil {
+		book, err := scanBook(rows)
+		if err != nil {
 			return nil, err
 		}
 		books = append(books, book)
@@ -504,10 +1224,11 @@ func (r *BookRepository) GetFavoriteBooks(userId int) ([]models.Book, error) {
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return books, nil
+	rows.Close()
+	return books, loadLinks(ctx, r.db, books)
 }
 
-func (r *BookRepository) AddToFavorites(userId, bookId int) error {
+func (r *BookRepository) AddToFavorites(ctx context.Context, userId, bookId int) error {
 	// idempotent: doppelte Einträge sind ok (tun nichts)
 	const q = `
         INSERT INTO user_favorites (user_id, book_id)
@@ -515,47 +1236,39 @@ func (r *BookRepository) AddToFavorites(userId, bookId int) error {
         ON CONFLICT (user_id, book_id) DO NOTHING;
     `
 
-	_, err := r.db.Exec(q, userId, bookId)
+	_, err := r.db.ExecContext(ctx, q, userId, bookId)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			// hilfreiche Logs im Backend
-			log.Printf("AddToFavorites PG error: code=%s detail=%s msg=%s where=%s", pgErr.Code, pgErr.Detail, pgErr.Message, pgErr.Where)
-		} else {
-			log.Printf("AddToFavorites SQL error: %v", err)
+		logPgError(ctx, "favorit konnte nicht gespeichert werden", err)
+		if pgErrorCode(err) == pgForeignKeyViolation {
+			return apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookId).WithCause(err)
 		}
 		return err
 	}
 	return nil
 }
 
-func (r *BookRepository) DeleteFavorite(userId, bookId int) error {
+func (r *BookRepository) DeleteFavorite(ctx context.Context, userId, bookId int) error {
 	const q = `DELETE FROM user_favorites WHERE user_id=$1 and book_id=$2`
 
-	_, err := r.db.Exec(q, userId, bookId)
+	_, err := r.db.ExecContext(ctx, q, userId, bookId)
 
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			log.Printf("deleteFavorite PG error: code:%s detail=%s msg=%s where=%s", pgErr.Code, pgErr.Detail, pgErr.Message, pgErr.Where)
-		} else {
-			log.Printf("deleteFavorite SQL error: %v", err)
-		}
+		logPgError(ctx, "favorit konnte nicht gelöscht werden", err)
 		return err
 	}
 
 	return nil
 }
 
-func (r *BookRepository) GetOrderedBooks(userId int) ([]models.Book, error) {
-	rows, err := r.db.Query(`
+func (r *BookRepository) GetOrderedBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	rows, err := r.db.QueryContext(ctx, `
         SELECT
-            b.id, b.author, b.name, b.price, b.genre, b.description, b.descriptionlong, b.quantity, b.borrowprice,
+            `+bookColumnsB+`,
             COALESCE(SUM(ub.quantity), COUNT(*)) AS ordered_quantity
         FROM books b
         INNER JOIN user_books ub ON b.id = ub.book_id
         WHERE ub.user_id = $1
-        GROUP BY b.id, b.author, b.name, b.price, b.genre, b.description, b.descriptionlong, b.quantity, b.borrowprice
+        GROUP BY b.id
         ORDER BY MAX(ub.purchased_at) DESC
     `, userId)
 	if err != nil {
@@ -565,20 +1278,9 @@ func (r *BookRepository) GetOrderedBooks(userId int) ([]models.Book, error) {
 
 	var books []models.Book
 	for rows.Next() {
-		var b models.Book
 		var orderedQty int
-		if err := rows.Scan(
-			&b.ID,
-			&b.Author,
-			&b.Name,
-			&b.Price,
-			&b.Genre,
-			&b.Description,
-			&b.Descriptionlong,
-			&b.Quantity,
-			&b.BorrowPrice,
-			&orderedQty,
-		); err != nil {
+		b, err := scanBook(rows, &orderedQty)
+		if err != nil {
 			return nil, err
 		}
 		b.OrderedQuantity = orderedQty
@@ -587,5 +1289,6 @@ func (r *BookRepository) GetOrderedBooks(userId int) ([]models.Book, error) {
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return books, nil
+	rows.Close()
+	return books, loadLinks(ctx, r.db, books)
 }
@@ -1,9 +1,14 @@
 package repository
 
 import (
-	"database/sql" // Standardbibliothek: generische DB Schnittstelle
-	"regexp"       // Wird genutzt um den SQL String zu escapen (QuoteMeta)
-	"testing"      // Go's Testing-Paket
+	"bookbazaar-backend/internal/apperr" // Fehlerkategorien für errors.Is
+	"bookbazaar-backend/internal/models" // Buch-Modell für Cursor
+	"context"                            // Request-Kontext für Abbruch und Timeouts
+	"database/sql"                       // Standardbibliothek: generische DB Schnittstelle
+	"errors"                             // errors.Is für Fehlerkategorien
+	"regexp"                             // Wird genutzt um den SQL String zu escapen (QuoteMeta)
+	"testing"                            // Go's Testing-Paket
+	"time"                               // Deadlines für Abbruch-Tests
 
 	"github.com/DATA-DOG/go-sqlmock"      // Mocking-Library für database/sql
 	"github.com/stretchr/testify/assert"  // Komfortable Assertions (nicht fatal)
@@ -22,50 +27,396 @@ func setupMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *BookRepository) {
 	return db, mock, NewBookRepository(db) // BookRepository verwendet dieselbe *sql.DB
 }
 
-// TestBookRepository_GetAll prüft den Happy Path der GetAll()-Methode:
-// 1. Die erwartete SELECT Query wird abgesetzt.
-// 2. Die Reihenfolge und Anzahl der gescannten Spalten stimmt.
-// 3. Die zurückgegebenen Slice-Werte haben die erwarteten Inhalte.
+// TestBookRepository_ListBooks prüft den Happy Path der ListBooks()-Methode:
+// 1. Zuerst wird die Gesamtzahl mit denselben Filtern gezählt.
+// 2. Dann folgt die SELECT Query mit Limit+1 (um eine nächste Seite zu erkennen).
+// 3. Die Reihenfolge und Anzahl der gescannten Spalten stimmt.
 // 4. Alle gesetzten Erwartungen (ExpectQuery) wurden erfüllt.
-func TestBookRepository_GetAll(t *testing.T) {
+func TestBookRepository_ListBooks(t *testing.T) {
 	// Test-Setup: gemockte DB + Mock Controller + Repository
 	db, mock, repo := setupMockDB(t)
 	defer db.Close() // Wichtig: Verbindung schließen, damit sqlmock alle Erwartungen sauber validieren kann
 
-	// Query-String exakt so wie in der echten Implementierung.
-	// regexp.QuoteMeta sorgt dafür, dass Sonderzeichen escaped werden
-	// (gibt uns Stabilität, falls wir z.B. Leerzeichen oder Klammern haben).
-	query := regexp.QuoteMeta(`SELECT id, author, name, price, genre, description, descriptionlong, quantity, borrowprice FROM books`)
+	// Zählung mit dem Genre-Filter als einzigem Argument
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM books WHERE archived_at IS NULL AND lower(genre) = lower($1)`)).
+		WithArgs("roman").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
 
 	// Wir definieren hier die simulierten Result-Set Zeilen in EXACT der Reihenfolge,
-	// in der GetAll() später rows.Scan(...) aufruft.
+	// in der ListBooks() später rows.Scan(...) aufruft.
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
 	rows := sqlmock.NewRows([]string{
-		"id", "author", "name", "price", "genre", "description", "descriptionlong", "quantity", "borrowprice",
+		"id", "author", "name", "isbn13", "price", "genre", "description", "descriptionlong", "quantity", "borrowprice", "cover_hash", "created_at", "version", "archived_at",
 	}).
 		// Erste Buch-Zeile
-		AddRow(1, "Autor A", "Buch A", 9.99, "Roman", "Kurz", "Lang", 5, 1.99).
+		AddRow(1, "Autor A", "Buch A", "9783608938289", 9.99, "Roman", "Kurz", "Lang", 5, 1.99, "0123456789abcdef", created, 1, nil).
 		// Zweite Buch-Zeile
-		AddRow(2, "Autor B", "Buch B", 19.49, "SciFi", "Kurz2", "Lang2", 2, 2.49)
+		AddRow(2, "Autor B", "Buch B", nil, 19.49, "Roman", "Kurz2", "Lang2", 2, 2.49, nil, created, 1, nil).
+		// Dritte Zeile: nur da, weil Limit+1 gelesen wird
+		AddRow(3, "Autor C", "Buch C", nil, 4.99, "Roman", "Kurz3", "Lang3", 1, 0, nil, created, 1, nil)
 
-	// Erwartung: Genau diese Query wird ausgeführt und liefert obige Rows zurück
-	mock.ExpectQuery(query).WillReturnRows(rows)
+	// Erwartung: sortiert nach Name, Limit 2 (+1), Offset 0
+	mock.ExpectQuery(`ORDER BY name ASC, id ASC LIMIT \$2 OFFSET \$3`).
+		WithArgs("roman", 3, 0).
+		WillReturnRows(rows)
+
+	// Autoren und Kategorien aller Bücher der Seite kommen mit je einer weiteren Abfrage
+	mock.ExpectQuery(`FROM book_authors`).
+		WithArgs("{1,2}").
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}).
+			AddRow(1, 7, "Autor A").
+			AddRow(2, 8, "Autor B"))
+	mock.ExpectQuery(`FROM book_categories`).
+		WithArgs("{1,2}").
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}).AddRow(1, 3, "Roman"))
 
 	// ACT: Methode unter Test aufrufen
-	books, err := repo.GetAll()
+	page, err := repo.ListBooks(context.Background(), BookQuery{Genre: "roman", Sort: SortName, Limit: 2})
 
 	// VALIDIERUNG: Kein Fehler beim Ausführen
 	require.NoError(t, err)
-	// Genau 2 Einträge im Slice
-	require.Len(t, books, 2)
+	// Genau 2 Einträge auf der Seite, insgesamt 3 Treffer
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, 3, page.Total)
 
 	// Feldinhalte der ersten Zeile prüfen
-	assert.Equal(t, 1, books[0].ID)
-	assert.Equal(t, "Autor A", books[0].Author)
-	assert.Equal(t, 5, books[0].Quantity)
+	assert.Equal(t, 1, page.Items[0].ID)
+	assert.Equal(t, "Autor A", page.Items[0].Author)
+	assert.Equal(t, 5, page.Items[0].Quantity)
+	// ISBN-10 wird aus der gespeicherten ISBN-13 abgeleitet
+	assert.Equal(t, "9783608938289", page.Items[0].ISBN13)
+	assert.Equal(t, "3608938281", page.Items[0].ISBN10)
 	// Zweite Zeile
-	assert.Equal(t, 2, books[1].ID)
-	assert.Equal(t, "Buch B", books[1].Name)
+	assert.Equal(t, 2, page.Items[1].ID)
+	assert.Equal(t, []models.AuthorRef{{ID: 8, Name: "Autor B"}}, page.Items[1].Authors)
+	assert.Equal(t, []models.CategoryRef{{ID: 3, Name: "Roman"}}, page.Items[0].Categories)
+	assert.Empty(t, page.Items[1].Categories)
+	assert.Equal(t, "Buch B", page.Items[1].Name)
+	assert.Empty(t, page.Items[1].ISBN10)
+	// Cover-URL nur, wenn ein Cover gesetzt ist
+	assert.Equal(t, "/api/covers/1/0123456789abcdef/medium.jpg", page.Items[0].CoverURL)
+	assert.Empty(t, page.Items[1].CoverURL)
+	// Der Cursor zeigt hinter das letzte Buch der Seite
+	assert.Equal(t, EncodeBookCursor(SortName, page.Items[1]), page.NextCursor)
 
 	// Stellt sicher, dass ALLE definierten Erwartungen (ExpectQuery etc.) wirklich aufgerufen wurden.
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+// TestBookRepository_ListBooksCursor prüft, dass ein Cursor als Keyset-Bedingung
+// in die Abfrage eingeht (nicht in die Zählung).
+func TestBookRepository_ListBooksCursor(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	cursor := EncodeBookCursor(SortPriceDesc, models.Book{ID: 7, Price: 12.5})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM books WHERE archived_at IS NULL AND quantity > 0`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE archived_at IS NULL AND quantity > 0 AND (price, id) < ($1::numeric, $2) ORDER BY price DESC, id DESC`)).
+		WithArgs("12.5", 7, 21, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	page, err := repo.ListBooks(context.Background(), BookQuery{InStock: true, Sort: SortPriceDesc, Limit: 20, Cursor: cursor})
+
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+	assert.Empty(t, page.NextCursor)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	t.Run("Cursor einer anderen Sortierung", func(t *testing.T) {
+		_, err := DecodeBookCursor(SortName, cursor)
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+}
+
+// TestBookRepository_SearchBooks prüft, dass die Suche über websearch_to_tsquery
+// läuft und Snippets HTML-sicher ausgeliefert werden.
+func TestBookRepository_SearchBooks(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM books WHERE search_vector @@ websearch_to_tsquery('german', $1) AND archived_at IS NULL`)).
+		WithArgs("hobbit").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	// ts_headline rahmt Treffer mit MarkStart/MarkStop ein, der Text selbst enthält HTML
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{
+		"id", "author", "name", "isbn13", "price", "genre", "description", "descriptionlong", "quantity", "borrowprice", "cover_hash", "created_at", "version", "archived_at", "rank", "snippet",
+	}).AddRow(1, "J.R.R. Tolkien", "Der Hobbit", "9783608938289", 9.99, "Fantasy", "Kurz", "Lang", 1, 0, nil, created, 3, nil, 0.8,
+		"Ein <script>"+MarkStart+"Hobbit"+MarkStop+"</script> zieht aus")
+	mock.ExpectQuery(`ts_rank_cd\(b.search_vector, q.query\)`).
+		WithArgs("hobbit", 20, 0, headlineOptions).
+		WillReturnRows(rows)
+
+	// Autoren und Kategorien wie bei ListBooks
+	mock.ExpectQuery(`FROM book_authors`).
+		WithArgs("{1}").
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}).AddRow(1, 7, "J.R.R. Tolkien"))
+	mock.ExpectQuery(`FROM book_categories`).
+		WithArgs("{1}").
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}))
+
+	page, err := repo.SearchBooks(context.Background(), SearchQuery{Text: "hobbit", Limit: 20})
+
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "Der Hobbit", page.Items[0].Name)
+	assert.Equal(t, "9783608938289", page.Items[0].ISBN13)
+	assert.Equal(t, 3, page.Items[0].Version)
+	assert.Equal(t, []models.AuthorRef{{ID: 7, Name: "J.R.R. Tolkien"}}, page.Items[0].Authors)
+	assert.Equal(t, 0.8, page.Items[0].Rank)
+	assert.Equal(t, "Ein &lt;script&gt;<mark>Hobbit</mark>&lt;/script&gt; zieht aus", page.Items[0].Snippet)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestBookRepository_SuggestBooks prüft, dass die Trigramm-Schwelle nur in der
+// Lese-Transaktion gesenkt wird und Titel wie Autoren zurückkommen.
+func TestBookRepository_SuggestBooks(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`)).
+		WithArgs("0.3").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE $1 <% author AND archived_at IS NULL GROUP BY author`)).
+		WithArgs("Tolkein", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "text", "book_id", "score"}).
+			AddRow("author", "J.R.R. Tolkien", 0, 0.5).
+			AddRow("title", "Tolkiens Welt", 3, 0.4))
+	mock.ExpectCommit()
+
+	suggestions, err := repo.SuggestBooks(context.Background(), "Tolkein", 10)
+
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, SuggestAuthor, suggestions[0].Kind)
+	assert.Equal(t, 3, suggestions[1].BookID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestBookRepository_UpdateBookVersionMismatch prüft, dass ein UPDATE ohne
+// Treffer als veraltete Version gemeldet wird, wenn das Buch noch existiert.
+func TestBookRepository_UpdateBookVersionMismatch(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	book := &models.Book{ID: 5, Name: "Der Hobbit", Author: "J.R.R. Tolkien", Price: 12}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE books`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "quantity"})) // keine Zeile: Version passt nicht
+	mock.ExpectRollback()
+	mock.ExpectQuery(`FROM books WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "author", "name", "isbn13", "price", "genre", "description", "descriptionlong", "quantity", "borrowprice", "cover_hash", "created_at", "version", "archived_at",
+		}).AddRow(5, "J.R.R. Tolkien", "Der Hobbit", nil, 10, "", "", "", 1, 0, nil, time.Now(), 3, nil))
+	mock.ExpectQuery(`FROM book_authors`).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}).AddRow(5, 1, "J.R.R. Tolkien"))
+	mock.ExpectQuery(`FROM book_categories`).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}))
+
+	err := repo.UpdateBook(context.Background(), book, 2)
+
+	assert.True(t, errors.Is(err, apperr.ErrPreconditionFailed))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestBookRepository_GetOrderedBooks prüft, dass Bestellungen dieselben
+// Buchfelder wie der Katalog liefern, samt ISBN, Version und Autoren.
+func TestBookRepository_GetOrderedBooks(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SUM\(ub.quantity\)`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "author", "name", "isbn13", "price", "genre", "description", "descriptionlong", "quantity", "borrowprice", "cover_hash", "created_at", "version", "archived_at", "ordered_quantity",
+		}).AddRow(1, "J.R.R. Tolkien", "Der Hobbit", "9783608938289", 9.99, "Fantasy", "Kurz", "Lang", 1, 0, nil, created, 4, nil, 2))
+	mock.ExpectQuery(`FROM book_authors`).
+		WithArgs("{1}").
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}).AddRow(1, 7, "J.R.R. Tolkien"))
+	mock.ExpectQuery(`FROM book_categories`).
+		WithArgs("{1}").
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}).AddRow(1, 3, "Fantasy"))
+
+	books, err := repo.GetOrderedBooks(context.Background(), 7)
+
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	assert.Equal(t, 2, books[0].OrderedQuantity)
+	assert.Equal(t, "3608938281", books[0].ISBN10)
+	assert.Equal(t, 4, books[0].Version)
+	assert.Equal(t, &created, books[0].CreatedAt)
+	assert.Equal(t, []models.AuthorRef{{ID: 7, Name: "J.R.R. Tolkien"}}, books[0].Authors)
+	assert.Equal(t, []models.CategoryRef{{ID: 3, Name: "Fantasy"}}, books[0].Categories)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestBookRepository_GetBookDetail prüft, dass Buch und Verfügbarkeit aus
+// derselben Zeile kommen und inStock damit immer quantity ist.
+func TestBookRepository_GetBookDetail(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`EXISTS \(SELECT 1 FROM user_favorites`).
+		WithArgs(1, 7).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "author", "name", "isbn13", "price", "genre", "description", "descriptionlong", "quantity", "borrowprice", "cover_hash", "created_at", "version", "archived_at",
+			"on_loan", "next_due", "favorite", "in_cart", "user_on_loan", "purchased",
+		}).AddRow(1, "J.R.R. Tolkien", "Der Hobbit", nil, 9.99, "Fantasy", "Kurz", "Lang", 3, 0, nil, created, 1, nil,
+			2, nil, true, false, false, true))
+	mock.ExpectQuery(`FROM book_authors`).WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}))
+	mock.ExpectQuery(`FROM book_categories`).WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}))
+
+	book, availability, err := repo.GetBookDetail(context.Background(), 1, 7)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, book.Quantity)
+	assert.Equal(t, Availability{InStock: 3, OnLoan: 2, User: &UserBookState{Favorite: true, Purchased: true}}, availability)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestBookRepository_GetOrderedBooksCanceled prüft, dass eine langsame Abfrage
+// abgebrochen wird, sobald der Request-Kontext abläuft, statt die Verbindung
+// weiter zu belegen.
+func TestBookRepository_GetOrderedBooksCanceled(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	// Die Abfrage würde eine Sekunde dauern ...
+	mock.ExpectQuery("FROM books b").
+		WithArgs(7).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// ... der Request wartet aber nur 20ms
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := repo.GetOrderedBooks(ctx, 7)
+
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+// TestBookRepository_UpsertBooksArchived prüft, dass ein Import, der ein
+// inzwischen archiviertes Buch aktualisieren würde, den ganzen Block
+// zurückrollt – auch das zuvor angelegte Buch.
+func TestBookRepository_UpsertBooksArchived(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	momo := &models.Book{Name: "Momo", Author: "Michael Ende", Authors: []models.AuthorRef{{ID: 3}}}
+	hobbit := &models.Book{ID: 5, Name: "Der Hobbit", Author: "J.R.R. Tolkien", Authors: []models.AuthorRef{{ID: 1}}, Quantity: 4}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT name FROM authors`).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Michael Ende"))
+	mock.ExpectQuery(`INSERT INTO books`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "version"}).AddRow(9, time.Now(), 1))
+	mock.ExpectExec(`DELETE FROM book_authors`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO book_authors`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM book_categories`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT name FROM authors`).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("J.R.R. Tolkien"))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE b.id = old.id AND b.archived_at IS NULL`)).
+		WithArgs(5, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 4).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "created_at", "quantity"})) // keine Zeile: archiviert
+	mock.ExpectRollback()
+
+	err := repo.UpsertBooks(context.Background(), 1, []*models.Book{momo, hobbit})
+
+	assert.True(t, errors.Is(err, apperr.ErrConflict))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestBookRepository_ExportBooks prüft, dass der Export in einer lesenden
+// Transaktion läuft und Autoren und Kategorien je Block nachlädt.
+func TestBookRepository_ExportBooks(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WHERE archived_at IS NULL AND id > \$1`).
+		WithArgs(0, exportBatch).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "author", "name", "isbn13", "price", "genre", "description", "descriptionlong", "quantity", "borrowprice", "cover_hash", "created_at", "version", "archived_at",
+		}).AddRow(5, "J.R.R. Tolkien", "Der Hobbit", nil, 10, "", "", "", 4, 1.5, nil, time.Now(), 3, nil))
+	mock.ExpectQuery(`FROM book_authors`).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}).AddRow(5, 1, "J.R.R. Tolkien"))
+	mock.ExpectQuery(`FROM book_categories`).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "id", "name"}))
+	mock.ExpectCommit()
+
+	var got []models.Book
+	err := repo.ExportBooks(context.Background(), func(book *models.Book) error {
+		got = append(got, *book)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, 4, got[0].Quantity)
+	assert.Equal(t, 1.5, got[0].BorrowPrice)
+	assert.Len(t, got[0].Authors, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestBookRepository_GiveBorrowedBookBackMovement prüft, dass die Rückgabe
+// ihre Journalbewegung mit Verweis auf die Ausleihe in derselben Transaktion
+// schreibt.
+func TestBookRepository_GiveBorrowedBookBackMovement(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE borrowed_books SET returned_at`).WithArgs(5, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectExec(`Update books Set quantity = quantity \+ \$1`).WithArgs(1, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_movements`).
+		WithArgs(5, 1, models.MovementReturn, 7, nil, 42, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "quantity_after", "created_at"}).AddRow(11, 3, time.Now()))
+	mock.ExpectCommit()
+
+	err := repo.GiveBorrowedBookBack(context.Background(), 7, 5)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestStockRepository_AdjustStockAbsolute prüft, dass ein absoluter Bestand
+// unter Zeilensperre in ein Delta umgerechnet und als adjustment gebucht wird.
+func TestStockRepository_AdjustStockAbsolute(t *testing.T) {
+	db, mock, _ := setupMockDB(t)
+	defer db.Close()
+	repo := NewStockRepository(db)
+	twelve := 12
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT quantity, reorder_threshold FROM books WHERE id = $1 FOR UPDATE")).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reorder_threshold"}).AddRow(4, 2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE books SET quantity = quantity + $1")).WithArgs(8, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_movements`).
+		WithArgs(5, 8, models.MovementAdjustment, 1, nil, nil, "Lieferung").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "quantity_after", "created_at"}).AddRow(30, 12, time.Now()))
+	mock.ExpectCommit()
+
+	level, err := repo.AdjustStock(context.Background(), StockAdjustment{BookID: 5, UserID: 1, Quantity: &twelve, Note: "Lieferung"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 12, level.Quantity)
+	require.NotNil(t, level.ReorderThreshold)
+	assert.Equal(t, 2, *level.ReorderThreshold)
+	assert.Equal(t, 30, level.Movement.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+type CategoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// subtreeQuery liefert die ID einer Kategorie und aller Unterkategorien; %s
+// ist der Platzhalter für die ID der Kategorie.
+const subtreeQuery = `WITH RECURSIVE sub AS (
+		SELECT id FROM categories WHERE id = %s
+		UNION ALL
+		SELECT c.id FROM categories c JOIN sub ON c.parent_id = sub.id
+	) SELECT id FROM sub`
+
+// categoryFilter schränkt ListBooks auf Bücher der Kategorie oder einer ihrer
+// Unterkategorien ein.
+func categoryFilter(placeholder string) string {
+	return "id IN (SELECT book_id FROM book_categories WHERE category_id IN (" + fmt.Sprintf(subtreeQuery, placeholder) + "))"
+}
+
+// loadCategories hängt an alle Bücher ihre Kategorien, mit einer Abfrage für alle.
+func loadCategories(ctx context.Context, q querier, books []models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	ids := make([]int, len(books))
+	index := make(map[int]int, len(books))
+	for i, b := range books {
+		ids[i] = b.ID
+		index[b.ID] = i
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT bc.book_id, c.id, c.name
+		FROM book_categories bc JOIN categories c ON c.id = bc.category_id
+		WHERE bc.book_id = ANY($1::int[])
+		ORDER BY bc.book_id, bc.position, c.id`, intArray(ids))
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim laden der kategorien", slog.Any("error", err))
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bookID int
+		var ref models.CategoryRef
+		if err := rows.Scan(&bookID, &ref.ID, &ref.Name); err != nil {
+			return err
+		}
+		b := &books[index[bookID]]
+		b.Categories = append(b.Categories, ref)
+	}
+	return rows.Err()
+}
+
+// resolveCategories prüft, dass alle Kategorien des Buchs existieren, setzt
+// ihre Namen und übernimmt den Namen der ersten als Genre. Ohne Kategorien
+// bleibt das Genre, wie es ist.
+func resolveCategories(ctx context.Context, tx *sql.Tx, book *models.Book) error {
+	if len(book.Categories) == 0 {
+		return nil
+	}
+	var ids []int
+	seen := map[int]bool{}
+	for _, ref := range book.Categories {
+		if !seen[ref.ID] {
+			seen[ref.ID] = true
+			ids = append(ids, ref.ID)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, name FROM categories WHERE id = ANY($1::int[])", intArray(ids))
+	if err != nil {
+		return err
+	}
+	names := make(map[int]string, len(ids))
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		names[id] = name
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	refs := make([]models.CategoryRef, len(ids))
+	for i, id := range ids {
+		name, ok := names[id]
+		if !ok {
+			return apperr.Validation("invalid_book", "Kategorie mit ID %d existiert nicht", id)
+		}
+		refs[i] = models.CategoryRef{ID: id, Name: name}
+	}
+	book.Categories = refs
+	book.Genre = refs[0].Name
+	return nil
+}
+
+// linkCategories ersetzt die Kategorien eines Buchs durch book.Categories.
+func linkCategories(ctx context.Context, tx *sql.Tx, book *models.Book) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM book_categories WHERE book_id = $1", book.ID); err != nil {
+		return err
+	}
+	for i, ref := range book.Categories {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO book_categories (book_id, category_id, position) VALUES ($1, $2, $3)", book.ID, ref.ID, i); err != nil {
+			logPgError(ctx, "kategorie konnte nicht verknüpft werden", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// ListCategories liefert alle Kategorien alphabetisch. BookCount zählt die
+// nicht archivierten Bücher der Kategorie und ihrer Unterkategorien; ein Buch
+// in zwei Unterkategorien zählt einmal.
+func (r *CategoryRepository) ListCategories(ctx context.Context) ([]models.Category, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT id AS root, id FROM categories
+			UNION ALL
+			SELECT t.root, c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT c.id, c.parent_id, c.name,
+			(SELECT COUNT(DISTINCT bc.book_id)
+			 FROM tree t
+			 JOIN book_categories bc ON bc.category_id = t.id
+			 JOIN books b ON b.id = bc.book_id AND b.archived_at IS NULL
+			 WHERE t.root = c.id)
+		FROM categories c
+		ORDER BY lower(c.name), c.id`)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler bei der kategorienliste", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var c models.Category
+		var parentID sql.NullInt64
+		if err := rows.Scan(&c.ID, &parentID, &c.Name, &c.BookCount); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			id := int(parentID.Int64)
+			c.ParentID = &id
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func nullParent(parentID *int) sql.NullInt64 {
+	if parentID == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*parentID), Valid: true}
+}
+
+// categoryError übersetzt Constraint-Verletzungen beim Schreiben einer Kategorie.
+func categoryError(err error, category *models.Category) error {
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return apperr.Conflict("category_exists", "Kategorie '%s' existiert an dieser Stelle bereits", category.Name).WithCause(err)
+	case pgForeignKeyViolation:
+		return apperr.Validation("invalid_category", "Oberkategorie mit ID %d existiert nicht", *category.ParentID).WithCause(err)
+	case pgCheckViolation:
+		return apperr.Validation("invalid_category", "Ungültige Kategorie '%s'", category.Name).WithCause(err)
+	}
+	return err
+}
+
+func (r *CategoryRepository) AddCategory(ctx context.Context, category *models.Category) error {
+	err := r.db.QueryRowContext(ctx, "INSERT INTO categories (parent_id, name) VALUES ($1, $2) RETURNING id",
+		nullParent(category.ParentID), category.Name).Scan(&category.ID)
+	if err != nil {
+		logPgError(ctx, "kategorie konnte nicht angelegt werden", err)
+		return categoryError(err, category)
+	}
+	slog.InfoContext(ctx, "kategorie angelegt", slog.Int("category_id", category.ID))
+	return nil
+}
+
+// UpdateCategory benennt eine Kategorie um oder hängt sie um. Unter sich
+// selbst oder eine eigene Unterkategorie darf sie nicht wandern. Bücher mit
+// ihr als Hauptkategorie bekommen den neuen Namen als Genre.
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, category *models.Category) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
+
+	// zwei gleichzeitige Verschiebungen könnten sonst gemeinsam einen Zyklus bilden
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+	if category.ParentID != nil {
+		var cycle bool
+		query := "SELECT EXISTS (" + fmt.Sprintf(subtreeQuery, "$1") + " WHERE id = $2)"
+		if err := tx.QueryRowContext(ctx, query, category.ID, *category.ParentID).Scan(&cycle); err != nil {
+			return err
+		}
+		if cycle {
+			return apperr.Validation("category_cycle", "Kategorie %d kann nicht unter sich selbst oder eine ihrer Unterkategorien", category.ID)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE categories SET parent_id = $2, name = $3 WHERE id = $1",
+		category.ID, nullParent(category.ParentID), category.Name)
+	if err != nil {
+		logPgError(ctx, "kategorie konnte nicht geändert werden", err)
+		return categoryError(err, category)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return apperr.NotFound("category_not_found", "keine Kategorie mit ID %d gefunden", category.ID)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE books b SET genre = $2, version = b.version + 1
+		FROM book_categories bc
+		WHERE bc.book_id = b.id AND bc.category_id = $1 AND bc.position = 0 AND b.genre IS DISTINCT FROM $2`,
+		category.ID, category.Name); err != nil {
+		slog.WarnContext(ctx, "fehler beim aktualisieren der genres", slog.Any("error", err))
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "kategorie geändert", slog.Int("category_id", category.ID))
+	return nil
+}
+
+// DeleteCategory löscht eine leere Kategorie. Unterkategorien und Bücher
+// müssen vorher umgehängt werden.
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return apperr.Conflict("category_in_use", "Kategorie mit ID %d hat noch Unterkategorien oder Bücher", id).WithCause(err)
+	}
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return apperr.NotFound("category_not_found", "keine Kategorie mit ID %d gefunden", id)
+	}
+	slog.InfoContext(ctx, "kategorie gelöscht", slog.Int("category_id", id))
+	return nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres-Fehlercodes, siehe https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+)
+
+// pgErrorCode liefert den SQLSTATE eines Postgres-Fehlers oder "".
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// logPgError loggt Postgres-Fehler mit SQLSTATE und Detail, andere Fehler nur mit Text.
+func logPgError(ctx context.Context, msg string, err error) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		slog.WarnContext(ctx, msg,
+			slog.String("sqlstate", pgErr.Code),
+			slog.String("detail", pgErr.Detail),
+			slog.String("where", pgErr.Where),
+			slog.Any("error", err),
+		)
+		return
+	}
+	slog.WarnContext(ctx, msg, slog.Any("error", err))
+}
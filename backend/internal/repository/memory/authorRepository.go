@@ -0,0 +1,259 @@
+package memory
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type AuthorRepository struct {
+	s *Store
+}
+
+var _ repository.AuthorStore = (*AuthorRepository)(nil)
+
+// findAuthor sucht einen Autor ohne Groß-/Kleinschreibung (wie authors_name_key).
+func (s *Store) findAuthor(name string, exceptID int) (models.Author, bool) {
+	for id, a := range s.authors {
+		if id != exceptID && strings.EqualFold(a.Name, name) {
+			return a, true
+		}
+	}
+	return models.Author{}, false
+}
+
+func (s *Store) addAuthor(author *models.Author) error {
+	author.Name = strings.TrimSpace(author.Name)
+	if utf8.RuneCountInString(author.Name) < 3 {
+		return apperr.Validation("invalid_author", "Autorenname '%s' ist zu kurz", author.Name)
+	}
+	if _, ok := s.findAuthor(author.Name, 0); ok {
+		return apperr.Conflict("author_exists", "Autor '%s' existiert bereits", author.Name)
+	}
+	s.nextAuthorID++
+	author.ID = s.nextAuthorID
+	now := s.now()
+	author.CreatedAt = &now
+	author.BookCount = 0
+	s.authors[author.ID] = *author
+	return nil
+}
+
+// resolveAuthors entspricht der Postgres-Variante: IDs müssen existieren,
+// Namen werden gefunden oder angelegt. Angelegt wird erst, wenn alle IDs
+// geprüft sind, damit ein Fehler keine halben Änderungen hinterlässt.
+func (s *Store) resolveAuthors(book *models.Book) error {
+	for _, ref := range book.Authors {
+		if _, ok := s.authors[ref.ID]; ref.ID != 0 && !ok {
+			return apperr.Validation("invalid_book", "Autor mit ID %d existiert nicht", ref.ID)
+		}
+		if ref.ID == 0 && utf8.RuneCountInString(strings.TrimSpace(ref.Name)) < 3 {
+			return apperr.Validation("invalid_book", "Autorenname '%s' ist zu kurz", ref.Name)
+		}
+	}
+
+	seen := map[int]bool{}
+	var refs []models.AuthorRef
+	for _, ref := range book.Authors {
+		if ref.ID == 0 {
+			a, ok := s.findAuthor(strings.TrimSpace(ref.Name), 0)
+			if !ok {
+				a = models.Author{Name: ref.Name}
+				if err := s.addAuthor(&a); err != nil {
+					return err
+				}
+			}
+			ref.ID = a.ID
+		}
+		ref.Name = s.authors[ref.ID].Name
+		if !seen[ref.ID] {
+			seen[ref.ID] = true
+			refs = append(refs, ref)
+		}
+	}
+	book.Authors = refs
+	if len(refs) > 0 {
+		book.Author = models.JoinAuthors(refs)
+	}
+	return nil
+}
+
+// refreshBookAuthors setzt Namen und books.author der Bücher neu, die einen
+// der Autoren haben.
+func (s *Store) refreshBookAuthors(authorIDs ...int) {
+	for id, b := range s.books {
+		if !slices.ContainsFunc(b.Authors, func(ref models.AuthorRef) bool { return slices.Contains(authorIDs, ref.ID) }) {
+			continue
+		}
+		b.Authors = slices.Clone(b.Authors)
+		for i := range b.Authors {
+			b.Authors[i].Name = s.authors[b.Authors[i].ID].Name
+		}
+		if joined := models.JoinAuthors(b.Authors); joined != b.Author {
+			b.Author = joined
+			b.Version++
+		}
+		s.books[id] = b
+	}
+}
+
+func (s *Store) withBookCount(a models.Author) models.Author {
+	a.BookCount = 0
+	for _, b := range s.books {
+		if b.ArchivedAt == nil && slices.ContainsFunc(b.Authors, func(ref models.AuthorRef) bool { return ref.ID == a.ID }) {
+			a.BookCount++
+		}
+	}
+	return a
+}
+
+func (r *BookRepository) AuthorNames(ctx context.Context, ids []int) (map[int]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	names := make(map[int]string, len(ids))
+	for _, id := range ids {
+		if a, ok := r.s.authors[id]; ok {
+			names[id] = a.Name
+		}
+	}
+	return names, nil
+}
+
+func (r *AuthorRepository) ListAuthors(ctx context.Context, q repository.AuthorQuery) (repository.AuthorPage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var matches []models.Author
+	for _, a := range r.s.authors {
+		if strings.Contains(strings.ToLower(a.Name), strings.ToLower(q.Name)) {
+			matches = append(matches, r.s.withBookCount(a))
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if c := strings.Compare(strings.ToLower(matches[i].Name), strings.ToLower(matches[j].Name)); c != 0 {
+			return c < 0
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	page := repository.AuthorPage{Items: []models.Author{}, Total: len(matches), Limit: q.Limit, Offset: q.Offset}
+	if q.Offset < len(matches) {
+		matches = matches[q.Offset:]
+		page.Items = append(page.Items, matches[:min(q.Limit, len(matches))]...)
+	}
+	return page, nil
+}
+
+func (r *AuthorRepository) GetAuthor(ctx context.Context, id int) (*models.Author, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	a, ok := r.s.authors[id]
+	if !ok {
+		return nil, nil
+	}
+	a = r.s.withBookCount(a)
+	return &a, nil
+}
+
+func (r *AuthorRepository) GetAuthorBooks(ctx context.Context, id int) ([]models.Book, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	books := []models.Book{}
+	for _, bookID := range r.s.sortedBookIDs() {
+		b := r.s.books[bookID]
+		if b.ArchivedAt == nil && slices.ContainsFunc(b.Authors, func(ref models.AuthorRef) bool { return ref.ID == id }) {
+			created := r.s.bookAdded[bookID]
+			b.CreatedAt = &created
+			books = append(books, b)
+		}
+	}
+	sort.SliceStable(books, func(i, j int) bool { return books[i].Name < books[j].Name })
+	return books, nil
+}
+
+func (r *AuthorRepository) AddAuthor(ctx context.Context, author *models.Author) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.addAuthor(author)
+}
+
+func (r *AuthorRepository) UpdateAuthor(ctx context.Context, author *models.Author) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	current, ok := r.s.authors[author.ID]
+	if !ok {
+		return apperr.NotFound("author_not_found", "kein Autor mit ID %d gefunden", author.ID)
+	}
+	if _, ok := r.s.findAuthor(author.Name, author.ID); ok {
+		return apperr.Conflict("author_exists", "Autor '%s' existiert bereits", author.Name)
+	}
+	current.Name, current.Bio = author.Name, author.Bio
+	r.s.authors[author.ID] = current
+	r.s.refreshBookAuthors(author.ID)
+	return nil
+}
+
+func (r *AuthorRepository) DeleteAuthor(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.authors[id]; !ok {
+		return apperr.NotFound("author_not_found", "kein Autor mit ID %d gefunden", id)
+	}
+	// auch archivierte Bücher zählen, wie beim Fremdschlüssel in Postgres
+	for _, b := range r.s.books {
+		if slices.ContainsFunc(b.Authors, func(ref models.AuthorRef) bool { return ref.ID == id }) {
+			return apperr.Conflict("author_has_books", "Autor mit ID %d hat noch Bücher", id)
+		}
+	}
+	delete(r.s.authors, id)
+	return nil
+}
+
+func (r *AuthorRepository) MergeAuthors(ctx context.Context, targetID int, duplicateIDs []int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, id := range append([]int{targetID}, duplicateIDs...) {
+		if _, ok := r.s.authors[id]; !ok {
+			return apperr.NotFound("author_not_found", "kein Autor mit ID %d gefunden", id)
+		}
+	}
+
+	for id, b := range r.s.books {
+		var refs []models.AuthorRef
+		changed, hasTarget := false, false
+		for _, ref := range b.Authors {
+			if slices.Contains(duplicateIDs, ref.ID) {
+				ref.ID, changed = targetID, true
+			}
+			// der Autor steht an der vordersten Position, an der er oder eine Dublette stand
+			if ref.ID == targetID {
+				if hasTarget {
+					continue
+				}
+				hasTarget = true
+			}
+			refs = append(refs, ref)
+		}
+		if changed {
+			b.Authors = refs
+			r.s.books[id] = b
+		}
+	}
+	for _, id := range duplicateIDs {
+		delete(r.s.authors, id)
+	}
+	r.s.refreshBookAuthors(targetID)
+	return nil
+}
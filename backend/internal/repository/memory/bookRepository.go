@@ -0,0 +1,897 @@
+package memory
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/covers"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+type BookRepository struct {
+	s *Store
+}
+
+var _ repository.BookStorage = (*BookRepository)(nil)
+
+// ListBooks filtert, sortiert und blättert wie die SQL-Variante; Name und
+// Genre werden dabei ohne Groß-/Kleinschreibung verglichen.
+func (r *BookRepository) ListBooks(ctx context.Context, q repository.BookQuery) (repository.BookPage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var after *models.Book
+	if q.Cursor != "" {
+		b, err := repository.DecodeBookCursor(q.Sort, q.Cursor)
+		if err != nil {
+			return repository.BookPage{}, err
+		}
+		after = &b
+	}
+
+	var subtree map[int]bool
+	if q.Category != 0 {
+		subtree = r.s.subtree(q.Category)
+	}
+
+	var matches []models.Book
+	for _, id := range r.s.sortedBookIDs() {
+		b := r.s.books[id]
+		if matchesQuery(b, q, subtree) {
+			created := r.s.bookAdded[id]
+			b.CreatedAt = &created
+			matches = append(matches, b)
+		}
+	}
+
+	less := bookLess(q.Sort)
+	sort.SliceStable(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+
+	page := repository.BookPage{Items: []models.Book{}, Total: len(matches), Limit: q.Limit, Offset: q.Offset}
+	if after != nil {
+		// erstes Buch, das in der Sortierung hinter dem Cursor liegt
+		start := sort.Search(len(matches), func(i int) bool { return less(*after, matches[i]) })
+		matches = matches[start:]
+	}
+	if q.Offset >= len(matches) {
+		return page, nil
+	}
+	matches = matches[q.Offset:]
+	if len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+		page.NextCursor = repository.EncodeBookCursor(q.Sort, matches[q.Limit-1])
+	}
+	page.Items = append(page.Items, matches...)
+	return page, nil
+}
+
+// Gewichte wie die Standardgewichte von ts_rank_cd für A, B, C und D.
+var searchWeights = [4]float64{1.0, 0.4, 0.2, 0.1}
+
+// SearchBooks nähert die Postgres-Volltextsuche an: jedes Wort der Suche muss
+// (ohne Groß-/Kleinschreibung) als Teilstring in Titel, Autor oder
+// Beschreibung vorkommen. Stammformen und Suchoperatoren kennt sie nicht.
+func (r *BookRepository) SearchBooks(ctx context.Context, q repository.SearchQuery) (repository.SearchPage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	terms := searchTerms(q.Text)
+	var hits []repository.BookHit
+	for _, id := range r.s.sortedBookIDs() {
+		b := r.s.books[id]
+		if b.ArchivedAt != nil {
+			continue
+		}
+		fields := [4]string{b.Name, b.Author, b.Description, b.Descriptionlong}
+		rank, matched := 0.0, len(terms) > 0
+		for _, term := range terms {
+			found := false
+			for i, f := range fields {
+				if strings.Contains(strings.ToLower(f), term) {
+					rank += searchWeights[i]
+					found = true
+				}
+			}
+			matched = matched && found
+		}
+		if matched {
+			created := r.s.bookAdded[id]
+			b.CreatedAt = &created
+			text := strings.TrimSpace(b.Description + " " + b.Descriptionlong)
+			hits = append(hits, repository.BookHit{Book: b, Rank: rank, Snippet: repository.SafeSnippet(snippet(text, terms))})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Rank > hits[j].Rank })
+
+	page := repository.SearchPage{Items: []repository.BookHit{}, Total: len(hits), Limit: q.Limit, Offset: q.Offset}
+	if q.Offset >= len(hits) {
+		return page, nil
+	}
+	hits = hits[q.Offset:]
+	if len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	page.Items = append(page.Items, hits...)
+	return page, nil
+}
+
+// SuggestBooks schlägt Titel und Autoren vor, deren Trigramm-Ähnlichkeit zu
+// prefix mindestens repository.SuggestThreshold beträgt.
+func (r *BookRepository) SuggestBooks(ctx context.Context, prefix string, limit int) ([]repository.Suggestion, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	suggestions := []repository.Suggestion{}
+	authors := map[string]int{} // Autor -> Index in suggestions
+	for _, id := range r.s.sortedBookIDs() {
+		b := r.s.books[id]
+		if b.ArchivedAt != nil {
+			continue
+		}
+		if score := wordSimilarity(prefix, b.Name); score >= repository.SuggestThreshold {
+			suggestions = append(suggestions, repository.Suggestion{Kind: repository.SuggestTitle, Text: b.Name, BookID: b.ID, Score: score})
+		}
+		if score := wordSimilarity(prefix, b.Author); score >= repository.SuggestThreshold {
+			if i, ok := authors[b.Author]; ok {
+				suggestions[i].Score = max(suggestions[i].Score, score)
+				continue
+			}
+			authors[b.Author] = len(suggestions)
+			suggestions = append(suggestions, repository.Suggestion{Kind: repository.SuggestAuthor, Text: b.Author, Score: score})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Text < suggestions[j].Text
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+func searchTerms(text string) []string {
+	return words(strings.ToLower(text))
+}
+
+// snippet schneidet rund um den ersten Treffer einen Ausschnitt von höchstens
+// 30 Wörtern aus und rahmt alle Treffer mit MarkStart/MarkStop ein.
+func snippet(text string, terms []string) string {
+	tokens := strings.Fields(text)
+	contains := func(w string) bool {
+		w = strings.ToLower(w)
+		for _, t := range terms {
+			if strings.Contains(w, t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	first := slices.IndexFunc(tokens, contains)
+	start := max(first-10, 0)
+	end := min(start+30, len(tokens))
+	out := make([]string, 0, end-start)
+	for _, w := range tokens[start:end] {
+		if contains(w) {
+			w = repository.MarkStart + w + repository.MarkStop
+		}
+		out = append(out, w)
+	}
+	return strings.Join(out, " ")
+}
+
+// matchesQuery prüft die Filter; subtree enthält bei q.Category die IDs der
+// Kategorie und aller Unterkategorien.
+func matchesQuery(b models.Book, q repository.BookQuery, subtree map[int]bool) bool {
+	switch {
+	case q.Archived != (b.ArchivedAt != nil),
+		q.Genre != "" && !strings.EqualFold(b.Genre, q.Genre),
+		q.Category != 0 && !slices.ContainsFunc(b.Categories, func(ref models.CategoryRef) bool { return subtree[ref.ID] }),
+		q.Author != "" && !strings.Contains(strings.ToLower(b.Author), strings.ToLower(q.Author)),
+		q.MinPrice != nil && b.Price < *q.MinPrice,
+		q.MaxPrice != nil && b.Price > *q.MaxPrice,
+		q.InStock && b.Quantity <= 0,
+		q.Borrowable && (b.BorrowPrice <= 0 || b.Quantity <= 0):
+		return false
+	}
+	return true
+}
+
+// bookLess ordnet nach Sortierschlüssel und bei Gleichstand nach ID – in
+// derselben Richtung, wie ORDER BY <spalte>, id im BookRepository.
+func bookLess(sortBy string) func(a, b models.Book) bool {
+	desc := sortBy == repository.SortNameDesc || sortBy == repository.SortPriceDesc || sortBy == repository.SortNewest
+	compare := func(a, b models.Book) int {
+		switch sortBy {
+		case repository.SortPrice, repository.SortPriceDesc:
+			return cmp.Compare(a.Price, b.Price)
+		case repository.SortNewest:
+			return a.CreatedAt.Compare(*b.CreatedAt)
+		default:
+			return strings.Compare(a.Name, b.Name)
+		}
+	}
+	return func(a, b models.Book) bool {
+		c := compare(a, b)
+		if c == 0 {
+			c = a.ID - b.ID
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}
+}
+
+func (r *BookRepository) GetBookByISBN(ctx context.Context, isbn13 string) (*models.Book, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.findBook(func(b models.Book) bool { return b.ISBN13 == isbn13 }), nil
+}
+
+// FindDuplicate entspricht der Postgres-Variante: bei ISBN gleiche ISBN,
+// sonst gleicher Titel und Autor ohne Groß-/Kleinschreibung.
+func (r *BookRepository) FindDuplicate(ctx context.Context, book *models.Book) (*models.Book, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.findBook(func(b models.Book) bool {
+		if b.ID == book.ID {
+			return false
+		}
+		if book.ISBN13 != "" {
+			return b.ISBN13 == book.ISBN13
+		}
+		return strings.EqualFold(b.Name, book.Name) && strings.EqualFold(b.Author, book.Author)
+	}), nil
+}
+
+// findBook liefert das Buch mit der kleinsten ID, auf das match zutrifft.
+func (s *Store) findBook(match func(models.Book) bool) *models.Book {
+	for _, id := range s.sortedBookIDs() {
+		if b := s.books[id]; match(b) {
+			created := s.bookAdded[id]
+			b.CreatedAt = &created
+			return &b
+		}
+	}
+	return nil
+}
+
+// isbnTaken bildet den Unique-Index auf books.isbn13 nach.
+func (s *Store) isbnTaken(book *models.Book) error {
+	if book.ISBN13 == "" {
+		return nil
+	}
+	for id, b := range s.books {
+		if id != book.ID && b.ISBN13 == book.ISBN13 {
+			return apperr.Conflict("book_exists", "Buch mit ISBN %s existiert bereits", book.ISBN13)
+		}
+	}
+	return nil
+}
+
+func (r *BookRepository) Add(ctx context.Context, book *models.Book) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	book.ID = 0
+	if err := r.s.isbnTaken(book); err != nil {
+		return err
+	}
+	// Kategorien zuerst: sie legen nichts an, ein Fehler dort lässt den Store unverändert
+	if err := r.s.resolveCategories(book); err != nil {
+		return err
+	}
+	if err := r.s.resolveAuthors(book); err != nil {
+		return err
+	}
+
+	r.s.nextBookID++
+	book.ID = r.s.nextBookID
+	now := r.s.now()
+	book.CreatedAt = &now
+	book.Version = 1
+	book.ArchivedAt = nil
+	book.CoverURL, book.CoverHash = "", ""
+	r.s.books[book.ID] = catalogFields(*book)
+	r.s.bookAdded[book.ID] = now
+	if book.Quantity != 0 {
+		r.s.recordMovement(models.StockMovement{BookID: book.ID, Delta: book.Quantity, Reason: models.MovementAdjustment, Note: "Anlage"})
+	}
+	return nil
+}
+
+// UpsertBooks entspricht der Postgres-Variante. Alle Bücher werden vorher
+// geprüft, damit ein Fehler wie ein Rollback nichts hinterlässt.
+func (r *BookRepository) UpsertBooks(ctx context.Context, userID int, books []*models.Book) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	isbns := map[string]int{}
+	for i, book := range books {
+		if book.ID != 0 {
+			if current, ok := r.s.books[book.ID]; !ok || current.ArchivedAt != nil {
+				return apperr.Conflict("book_archived", "Buch mit ID %d wird nicht mehr angeboten", book.ID)
+			}
+		}
+		if err := r.s.isbnTaken(book); err != nil {
+			return err
+		}
+		if j, ok := isbns[book.ISBN13]; ok && book.ISBN13 != "" {
+			return apperr.Conflict("book_exists", "ISBN %s kommt im Import doppelt vor (Buch %d und %d)", book.ISBN13, j+1, i+1)
+		}
+		isbns[book.ISBN13] = i
+		if err := r.s.resolveCategories(book); err != nil {
+			return err
+		}
+	}
+
+	for _, book := range books {
+		if err := r.s.resolveAuthors(book); err != nil {
+			return err
+		}
+		oldQuantity := 0
+		if book.ID == 0 {
+			r.s.nextBookID++
+			book.ID = r.s.nextBookID
+			now := r.s.now()
+			r.s.bookAdded[book.ID] = now
+			book.Version = 1
+			book.CoverURL, book.CoverHash = "", ""
+		} else {
+			current := r.s.books[book.ID]
+			oldQuantity = current.Quantity
+			book.Version = current.Version + 1
+			book.CoverURL, book.CoverHash = current.CoverURL, current.CoverHash
+		}
+		book.ArchivedAt = nil
+		created := r.s.bookAdded[book.ID]
+		book.CreatedAt = &created
+		r.s.books[book.ID] = catalogFields(*book)
+		if delta := book.Quantity - oldQuantity; delta != 0 {
+			r.s.recordMovement(models.StockMovement{BookID: book.ID, Delta: delta, Reason: models.MovementImport, UserID: optionalID(userID)})
+		}
+	}
+	return nil
+}
+
+// ExportBooks arbeitet auf einer Kopie, damit fn ohne Sperre laufen kann.
+func (r *BookRepository) ExportBooks(ctx context.Context, fn func(book *models.Book) error) error {
+	r.s.mu.Lock()
+	var books []models.Book
+	for id, b := range r.s.books {
+		if b.ArchivedAt == nil {
+			b = catalogFields(b)
+			created := r.s.bookAdded[id]
+			b.CreatedAt = &created
+			books = append(books, b)
+		}
+	}
+	r.s.mu.Unlock()
+
+	slices.SortFunc(books, func(a, b models.Book) int { return a.ID - b.ID })
+	for i := range books {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&books[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *BookRepository) GetBookByID(ctx context.Context, id int) (*models.Book, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	b, ok := r.s.books[id]
+	if !ok {
+		return nil, nil
+	}
+	created := r.s.bookAdded[id]
+	b.CreatedAt = &created
+	return &b, nil
+}
+
+func (r *BookRepository) GetBookDetail(ctx context.Context, bookID, userID int) (*models.Book, repository.Availability, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.books[bookID]; !ok {
+		return nil, repository.Availability{}, apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", bookID)
+	}
+	book := r.s.book(bookID)
+	now := r.s.now()
+	a := repository.Availability{InStock: book.Quantity}
+	var state repository.UserBookState
+	for _, l := range r.s.loans {
+		if l.bookID != bookID || l.returnedAt != nil {
+			continue
+		}
+		a.OnLoan++
+		if l.userID == userID {
+			state.OnLoan = true
+		}
+		if l.dueAt.After(now) && (a.NextDueAt == nil || l.dueAt.Before(*a.NextDueAt)) {
+			due := l.dueAt
+			a.NextDueAt = &due
+		}
+	}
+	if userID == 0 {
+		return &book, a, nil
+	}
+
+	key := userBook{userID, bookID}
+	_, state.Favorite = r.s.favorites[key]
+	_, state.Purchased = r.s.purchases[key]
+	if e, ok := r.s.cart[key]; ok && e.removedAt == nil {
+		state.InCart = e.reservationExpiresAt == nil || e.reservationExpiresAt.After(now)
+	}
+	a.User = &state
+	return &book, a, nil
+}
+
+func (r *BookRepository) UpdateBook(ctx context.Context, book *models.Book, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	current, ok := r.s.books[book.ID]
+	if !ok {
+		return apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", book.ID)
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return apperr.PreconditionFailed("version_mismatch", "Buch mit ID %d wurde inzwischen geändert (aktuelle Version %d)", book.ID, current.Version)
+	}
+	if err := r.s.isbnTaken(book); err != nil {
+		return err
+	}
+	if err := r.s.resolveCategories(book); err != nil {
+		return err
+	}
+	if err := r.s.resolveAuthors(book); err != nil {
+		return err
+	}
+
+	book.Version = current.Version + 1
+	book.Quantity = current.Quantity
+	book.ArchivedAt = current.ArchivedAt
+	book.CoverURL, book.CoverHash = current.CoverURL, current.CoverHash
+	created := r.s.bookAdded[book.ID]
+	book.CreatedAt = &created
+	r.s.books[book.ID] = catalogFields(*book)
+	return nil
+}
+
+func (r *BookRepository) SetCover(ctx context.Context, id int, hash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	book, err := r.s.lookupBook(id)
+	if err != nil {
+		return err
+	}
+	book.CoverHash, book.CoverURL = hash, ""
+	if hash != "" {
+		book.CoverURL = covers.URL(id, hash, covers.DefaultSize)
+	}
+	book.Version++
+	r.s.books[id] = book
+	return nil
+}
+
+func (r *BookRepository) CoverHash(ctx context.Context, id int) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	book, err := r.s.lookupBook(id)
+	return book.CoverHash, err
+}
+
+// catalogFields entfernt die Felder, die nur in speziellen Views gefüllt werden.
+func catalogFields(b models.Book) models.Book {
+	b.DueAt = ""
+	b.ReservationExpiresAt = ""
+	b.OrderedQuantity = 0
+	b.CreatedAt = nil
+	b.Authors = slices.Clone(b.Authors)
+	b.Categories = slices.Clone(b.Categories)
+	return b
+}
+
+// Archive nimmt ein Buch aus dem Katalog; Käufe, Ausleihen und Favoriten
+// bleiben erhalten, offene Warenkorb-Einträge werden entfernt.
+func (r *BookRepository) Archive(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	book, err := r.s.lookupBook(id)
+	if err != nil {
+		return err
+	}
+	if book.ArchivedAt != nil {
+		return nil
+	}
+
+	now := r.s.now()
+	book.ArchivedAt = &now
+	book.Version++
+	r.s.books[id] = book
+	for key, entry := range r.s.cart {
+		if key.bookID == id && entry.removedAt == nil {
+			entry.removedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *BookRepository) Restore(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	book, err := r.s.lookupBook(id)
+	if err != nil {
+		return err
+	}
+	if book.ArchivedAt == nil {
+		return nil
+	}
+
+	book.ArchivedAt = nil
+	book.Version++
+	r.s.books[id] = book
+	return nil
+}
+
+func (s *Store) lookupUser(userID int) (models.User, error) {
+	u, ok := s.users[userID]
+	if !ok {
+		return models.User{}, apperr.NotFound("user_not_found", "user mit ID %d existiert nicht", userID)
+	}
+	return u, nil
+}
+
+func (s *Store) lookupBook(bookID int) (models.Book, error) {
+	b, ok := s.books[bookID]
+	if !ok {
+		return models.Book{}, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookID)
+	}
+	return b, nil
+}
+
+// lookupOffered ist lookupBook für Käufe, Ausleihen und Warenkorb:
+// archivierte Bücher werden abgelehnt.
+func (s *Store) lookupOffered(bookID int) (models.Book, error) {
+	b, err := s.lookupBook(bookID)
+	if err == nil && b.ArchivedAt != nil {
+		return models.Book{}, apperr.Conflict("book_archived", "Buch mit ID %d wird nicht mehr angeboten", bookID)
+	}
+	return b, err
+}
+
+// recordPurchase bucht einen Kauf und liefert die ID des Eintrags.
+func (s *Store) recordPurchase(userID, bookID, quantity int) int {
+	key := userBook{userID, bookID}
+	if p, ok := s.purchases[key]; ok {
+		p.quantity += quantity
+		return p.id
+	}
+	s.nextPurchaseID++
+	s.purchases[key] = &purchase{id: s.nextPurchaseID, quantity: quantity, purchasedAt: s.now()}
+	return s.nextPurchaseID
+}
+
+func (r *BookRepository) BuyBook(ctx context.Context, userID, bookID int) (float64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.s.lookupUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	book, err := r.s.lookupOffered(bookID)
+	if err != nil {
+		return 0, err
+	}
+
+	if book.Quantity < 1 {
+		return 0, apperr.OutOfStock("out_of_stock", "buch ist nicht mehr verfügbar")
+	}
+	if user.Balance < book.Price {
+		return 0, apperr.InsufficientBalance("insufficient_balance", "nicht genügend Guthaben: %.2f benötigt, %.2f verfügbar", book.Price, user.Balance)
+	}
+
+	user.Balance -= book.Price
+	book.Quantity--
+	r.s.users[userID] = user
+	r.s.books[bookID] = book
+	purchaseID := r.s.recordPurchase(userID, bookID, 1)
+	r.s.recordMovement(models.StockMovement{BookID: bookID, Delta: -1, Reason: models.MovementSale, UserID: &userID, PurchaseID: &purchaseID})
+	return book.Price, nil
+}
+
+func (r *BookRepository) BuyBooks(ctx context.Context, userID int, purchases []repository.Purchase) (float64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.s.lookupUser(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// erst alles prüfen, dann buchen – entspricht dem Rollback der Transaktion
+	var totalprice float64
+	for _, p := range purchases {
+		book, err := r.s.lookupOffered(p.BookId)
+		if err != nil {
+			return 0, err
+		}
+		if book.Quantity < p.Quantity {
+			return 0, apperr.OutOfStock("out_of_stock", "nicht genug Bestand für BuchID %d", p.BookId)
+		}
+		totalprice += book.Price * float64(p.Quantity)
+	}
+
+	if user.Balance < totalprice {
+		return 0, apperr.InsufficientBalance("insufficient_balance", "nicht genügend Guthaben: %.2f benötigt, %.2f verfügbar", totalprice, user.Balance)
+	}
+
+	user.Balance -= totalprice
+	r.s.users[userID] = user
+	for _, p := range purchases {
+		book := r.s.books[p.BookId]
+		book.Quantity -= p.Quantity
+		r.s.books[p.BookId] = book
+		purchaseID := r.s.recordPurchase(userID, p.BookId, p.Quantity)
+		r.s.recordMovement(models.StockMovement{BookID: p.BookId, Delta: -p.Quantity, Reason: models.MovementSale, UserID: &userID, PurchaseID: &purchaseID})
+	}
+	return totalprice, nil
+}
+
+func (r *BookRepository) GetOrderedBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	type ordered struct {
+		book models.Book
+		at   time.Time
+	}
+	var list []ordered
+	for key, p := range r.s.purchases {
+		if key.userID != userId {
+			continue
+		}
+		b := r.s.book(key.bookID)
+		b.OrderedQuantity = p.quantity
+		list = append(list, ordered{book: b, at: p.purchasedAt})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].at.Equal(list[j].at) {
+			return list[i].book.ID < list[j].book.ID
+		}
+		return list[i].at.After(list[j].at)
+	})
+
+	var books []models.Book
+	for _, o := range list {
+		books = append(books, o.book)
+	}
+	return books, nil
+}
+
+func (r *BookRepository) BorrowBook(ctx context.Context, userId, bookId, days int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.s.lookupUser(userId)
+	if err != nil {
+		return err
+	}
+	book, err := r.s.lookupOffered(bookId)
+	if err != nil {
+		return err
+	}
+
+	if book.Quantity < 1 {
+		return apperr.OutOfStock("out_of_stock", "buch ist nicht mehr verfügbar")
+	}
+	if user.Balance < book.BorrowPrice {
+		return apperr.InsufficientBalance("insufficient_balance", "nicht genügend Guthaben: %.2f benötigt, %.2f verfügbar", book.BorrowPrice, user.Balance)
+	}
+
+	user.Balance -= book.BorrowPrice
+	book.Quantity--
+	r.s.users[userId] = user
+	r.s.books[bookId] = book
+
+	now := r.s.now()
+	r.s.nextLoanID++
+	r.s.loans = append(r.s.loans, &loan{
+		id:         r.s.nextLoanID,
+		userID:     userId,
+		bookID:     bookId,
+		borrowedAt: now,
+		dueAt:      now.Add(loanDuration),
+	})
+	loanID := r.s.nextLoanID
+	r.s.recordMovement(models.StockMovement{BookID: bookId, Delta: -1, Reason: models.MovementLoan, UserID: &userId, LoanID: &loanID})
+	return nil
+}
+
+func (r *BookRepository) GiveBorrowedBookBack(ctx context.Context, userId, bookId int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var open *loan
+	for _, l := range r.s.loans {
+		if l.userID == userId && l.bookID == bookId && l.returnedAt == nil {
+			if open == nil || l.dueAt.Before(open.dueAt) {
+				open = l
+			}
+		}
+	}
+	if open == nil {
+		return apperr.NotFound("loan_not_found", "keine offene Ausleihe für Buch %d gefunden", bookId)
+	}
+
+	now := r.s.now()
+	open.returnedAt = &now
+	book := r.s.books[bookId]
+	book.Quantity++
+	r.s.books[bookId] = book
+	r.s.recordMovement(models.StockMovement{BookID: bookId, Delta: 1, Reason: models.MovementReturn, UserID: &userId, LoanID: &open.id})
+	return nil
+}
+
+func (r *BookRepository) GetBorrowedBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var books []models.Book
+	for _, l := range r.s.loans {
+		if l.userID != userId || l.returnedAt != nil {
+			continue
+		}
+		b := r.s.book(l.bookID)
+		b.DueAt = l.dueAt.Format("2006-01-02T15:04:05")
+		books = append(books, b)
+	}
+	return books, nil
+}
+
+func (r *BookRepository) AddToCart(ctx context.Context, userId, bookId int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, err := r.s.lookupOffered(bookId); err != nil {
+		return err
+	}
+
+	expires := r.s.now().Add(cartReservationTTL)
+	key := userBook{userId, bookId}
+	if e, ok := r.s.cart[key]; ok {
+		e.reservationExpiresAt = &expires
+		e.removedAt = nil
+		return nil
+	}
+	r.s.nextCartID++
+	r.s.cart[key] = &cartEntry{id: r.s.nextCartID, reservationExpiresAt: &expires}
+	return nil
+}
+
+func (r *BookRepository) GetCartBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.now()
+	type entry struct {
+		id   int
+		book models.Book
+	}
+	var entries []entry
+	for key, e := range r.s.cart {
+		if key.userID != userId || e.removedAt != nil {
+			continue
+		}
+		if e.reservationExpiresAt != nil && !e.reservationExpiresAt.After(now) {
+			continue
+		}
+		b := r.s.book(key.bookID)
+		if e.reservationExpiresAt != nil {
+			b.ReservationExpiresAt = e.reservationExpiresAt.Format(time.RFC3339)
+		}
+		entries = append(entries, entry{id: e.id, book: b})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+
+	var books []models.Book
+	for _, e := range entries {
+		books = append(books, e.book)
+	}
+	return books, nil
+}
+
+func (r *BookRepository) RemoveFromCart(ctx context.Context, userId, bookId int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.cart, userBook{userId, bookId})
+	return nil
+}
+
+func (r *BookRepository) ExpireCartReservations(ctx context.Context) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.now()
+	var expired int64
+	for _, e := range r.s.cart {
+		if e.removedAt == nil && e.reservationExpiresAt != nil && !e.reservationExpiresAt.After(now) {
+			removed := now
+			e.removedAt = &removed
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func (r *BookRepository) AddToFavorites(ctx context.Context, userId, bookId int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, err := r.s.lookupBook(bookId); err != nil {
+		return err
+	}
+	key := userBook{userId, bookId}
+	if _, ok := r.s.favorites[key]; !ok {
+		r.s.favorites[key] = r.s.now()
+	}
+	return nil
+}
+
+func (r *BookRepository) GetFavoriteBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	type fav struct {
+		book models.Book
+		at   time.Time
+	}
+	var favs []fav
+	for key, at := range r.s.favorites {
+		if key.userID == userId {
+			favs = append(favs, fav{book: r.s.book(key.bookID), at: at})
+		}
+	}
+	sort.SliceStable(favs, func(i, j int) bool {
+		if favs[i].at.Equal(favs[j].at) {
+			return favs[i].book.ID > favs[j].book.ID
+		}
+		return favs[i].at.After(favs[j].at)
+	})
+
+	var books []models.Book
+	for _, f := range favs {
+		books = append(books, f.book)
+	}
+	return books, nil
+}
+
+func (r *BookRepository) DeleteFavorite(ctx context.Context, userId, bookId int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.favorites, userBook{userId, bookId})
+	return nil
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type CategoryRepository struct {
+	s *Store
+}
+
+var _ repository.CategoryStore = (*CategoryRepository)(nil)
+
+// subtree liefert die ID der Kategorie und aller Unterkategorien.
+func (s *Store) subtree(id int) map[int]bool {
+	ids := map[int]bool{id: true}
+	for changed := true; changed; {
+		changed = false
+		for _, c := range s.categories {
+			if c.ParentID != nil && ids[*c.ParentID] && !ids[c.ID] {
+				ids[c.ID] = true
+				changed = true
+			}
+		}
+	}
+	return ids
+}
+
+// cloneID kopiert eine optionale ID, damit Aufrufer den Store nicht über den
+// Zeiger verändern.
+func cloneID(id *int) *int {
+	if id == nil {
+		return nil
+	}
+	c := *id
+	return &c
+}
+
+// resolveCategories entspricht der Postgres-Variante: alle IDs müssen
+// existieren, die erste Kategorie bestimmt das Genre.
+func (s *Store) resolveCategories(book *models.Book) error {
+	var refs []models.CategoryRef
+	for _, ref := range book.Categories {
+		c, ok := s.categories[ref.ID]
+		if !ok {
+			return apperr.Validation("invalid_book", "Kategorie mit ID %d existiert nicht", ref.ID)
+		}
+		if !slices.ContainsFunc(refs, func(r models.CategoryRef) bool { return r.ID == c.ID }) {
+			refs = append(refs, models.CategoryRef{ID: c.ID, Name: c.Name})
+		}
+	}
+	book.Categories = refs
+	if len(refs) > 0 {
+		book.Genre = refs[0].Name
+	}
+	return nil
+}
+
+// checkCategory prüft Oberkategorie und Eindeutigkeit unter Geschwistern wie
+// die Constraints in Postgres.
+func (s *Store) checkCategory(category *models.Category) error {
+	if category.ParentID != nil {
+		if _, ok := s.categories[*category.ParentID]; !ok {
+			return apperr.Validation("invalid_category", "Oberkategorie mit ID %d existiert nicht", *category.ParentID)
+		}
+	}
+	for _, c := range s.categories {
+		sameParent := (c.ParentID == nil && category.ParentID == nil) ||
+			(c.ParentID != nil && category.ParentID != nil && *c.ParentID == *category.ParentID)
+		if c.ID != category.ID && sameParent && strings.EqualFold(c.Name, category.Name) {
+			return apperr.Conflict("category_exists", "Kategorie '%s' existiert an dieser Stelle bereits", category.Name)
+		}
+	}
+	return nil
+}
+
+func (r *CategoryRepository) ListCategories(ctx context.Context) ([]models.Category, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	categories := []models.Category{}
+	for _, c := range r.s.categories {
+		subtree := r.s.subtree(c.ID)
+		c.BookCount = 0
+		for _, b := range r.s.books {
+			if b.ArchivedAt == nil && slices.ContainsFunc(b.Categories, func(ref models.CategoryRef) bool { return subtree[ref.ID] }) {
+				c.BookCount++
+			}
+		}
+		c.ParentID = cloneID(c.ParentID)
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if c := strings.Compare(strings.ToLower(categories[i].Name), strings.ToLower(categories[j].Name)); c != 0 {
+			return c < 0
+		}
+		return categories[i].ID < categories[j].ID
+	})
+	return categories, nil
+}
+
+func (r *CategoryRepository) AddCategory(ctx context.Context, category *models.Category) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	category.ID = 0
+	if err := r.s.checkCategory(category); err != nil {
+		return err
+	}
+	r.s.nextCategoryID++
+	category.ID = r.s.nextCategoryID
+	category.BookCount = 0
+	r.s.categories[category.ID] = models.Category{ID: category.ID, ParentID: cloneID(category.ParentID), Name: category.Name}
+	return nil
+}
+
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, category *models.Category) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.categories[category.ID]; !ok {
+		return apperr.NotFound("category_not_found", "keine Kategorie mit ID %d gefunden", category.ID)
+	}
+	if category.ParentID != nil && r.s.subtree(category.ID)[*category.ParentID] {
+		return apperr.Validation("category_cycle", "Kategorie %d kann nicht unter sich selbst oder eine ihrer Unterkategorien", category.ID)
+	}
+	if err := r.s.checkCategory(category); err != nil {
+		return err
+	}
+	r.s.categories[category.ID] = models.Category{ID: category.ID, ParentID: cloneID(category.ParentID), Name: category.Name}
+
+	for id, b := range r.s.books {
+		i := slices.IndexFunc(b.Categories, func(ref models.CategoryRef) bool { return ref.ID == category.ID })
+		if i < 0 {
+			continue
+		}
+		b.Categories = slices.Clone(b.Categories)
+		b.Categories[i].Name = category.Name
+		if i == 0 && b.Genre != category.Name {
+			b.Genre = category.Name
+			b.Version++
+		}
+		r.s.books[id] = b
+	}
+	return nil
+}
+
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.categories[id]; !ok {
+		return apperr.NotFound("category_not_found", "keine Kategorie mit ID %d gefunden", id)
+	}
+	inUse := false
+	for _, c := range r.s.categories {
+		inUse = inUse || (c.ParentID != nil && *c.ParentID == id)
+	}
+	// auch archivierte Bücher zählen, wie beim Fremdschlüssel in Postgres
+	for _, b := range r.s.books {
+		inUse = inUse || slices.ContainsFunc(b.Categories, func(ref models.CategoryRef) bool { return ref.ID == id })
+	}
+	if inUse {
+		return apperr.Conflict("category_in_use", "Kategorie mit ID %d hat noch Unterkategorien oder Bücher", id)
+	}
+	delete(r.s.categories, id)
+	return nil
+}
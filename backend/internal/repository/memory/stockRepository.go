@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"sort"
+	"time"
+)
+
+var _ repository.StockStore = (*StockRepository)(nil)
+
+type StockRepository struct {
+	s *Store
+}
+
+func optionalID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// recordMovement entspricht der Postgres-Variante: nach der Änderung am Buch
+// aufrufen, QuantityAfter ist dessen aktueller Bestand. Aufrufer hält s.mu.
+func (s *Store) recordMovement(m models.StockMovement) models.StockMovement {
+	s.nextMovementID++
+	m.ID = s.nextMovementID
+	m.QuantityAfter = s.books[m.BookID].Quantity
+	m.CreatedAt = s.now()
+	s.movements = append(s.movements, m)
+	return m
+}
+
+func (r *StockRepository) ListMovements(ctx context.Context, q repository.MovementQuery) (repository.MovementPage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.books[q.BookID]; !ok {
+		return repository.MovementPage{}, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", q.BookID)
+	}
+	var all []models.StockMovement
+	for i := len(r.s.movements) - 1; i >= 0; i-- {
+		if m := r.s.movements[i]; m.BookID == q.BookID {
+			all = append(all, m)
+		}
+	}
+	page := repository.MovementPage{Items: []models.StockMovement{}, Total: len(all), Limit: q.Limit, Offset: q.Offset}
+	if q.Offset < len(all) {
+		page.Items = append(page.Items, all[q.Offset:min(q.Offset+q.Limit, len(all))]...)
+	}
+	return page, nil
+}
+
+func (r *StockRepository) ReconcileStock(ctx context.Context) (repository.StockReconciliation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	sums := map[int]int{}
+	for _, m := range r.s.movements {
+		sums[m.BookID] += m.Delta
+	}
+	result := repository.StockReconciliation{Checked: len(r.s.books), Mismatches: []repository.StockMismatch{}}
+	for _, id := range r.s.sortedBookIDs() {
+		if b := r.s.books[id]; b.Quantity != sums[id] {
+			result.Mismatches = append(result.Mismatches, repository.StockMismatch{BookID: id, Name: b.Name, Quantity: b.Quantity, LedgerSum: sums[id]})
+		}
+	}
+	return result, nil
+}
+
+// stockLevel liest Bestand und Meldebestand. Aufrufer hält s.mu.
+func (s *Store) stockLevel(bookID int) (repository.StockLevel, error) {
+	book, ok := s.books[bookID]
+	if !ok {
+		return repository.StockLevel{}, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookID)
+	}
+	level := repository.StockLevel{BookID: bookID, Quantity: book.Quantity}
+	if threshold, ok := s.thresholds[bookID]; ok {
+		level.ReorderThreshold = &threshold
+	}
+	return level, nil
+}
+
+func (r *StockRepository) GetStockLevel(ctx context.Context, bookID int) (repository.StockLevel, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.stockLevel(bookID)
+}
+
+func (r *StockRepository) AdjustStock(ctx context.Context, adj repository.StockAdjustment) (repository.StockLevel, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	level, err := r.s.stockLevel(adj.BookID)
+	if err != nil {
+		return repository.StockLevel{}, err
+	}
+	delta := 0
+	if adj.Delta != nil {
+		delta = *adj.Delta
+	} else if adj.Quantity != nil {
+		delta = *adj.Quantity - level.Quantity
+	}
+	if level.Quantity+delta < 0 {
+		return repository.StockLevel{}, apperr.OutOfStock("out_of_stock", "bestand von buch %d ist %d und kann nicht um %d sinken", adj.BookID, level.Quantity, -delta)
+	}
+	if delta == 0 {
+		return level, nil
+	}
+
+	book := r.s.books[adj.BookID]
+	book.Quantity += delta
+	r.s.books[adj.BookID] = book
+	m := r.s.recordMovement(models.StockMovement{BookID: adj.BookID, Delta: delta, Reason: models.MovementAdjustment, UserID: optionalID(adj.UserID), Note: adj.Note})
+	level.Quantity, level.Movement = book.Quantity, &m
+	return level, nil
+}
+
+func (r *StockRepository) SetReorderThreshold(ctx context.Context, bookID int, threshold *int) (repository.StockLevel, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.books[bookID]; !ok {
+		return repository.StockLevel{}, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookID)
+	}
+	if threshold == nil {
+		delete(r.s.thresholds, bookID)
+	} else {
+		r.s.thresholds[bookID] = *threshold
+	}
+	return r.s.stockLevel(bookID)
+}
+
+func (r *StockRepository) LowStock(ctx context.Context, since time.Time) ([]repository.LowStockItem, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	items := []repository.LowStockItem{}
+	for _, id := range r.s.sortedBookIDs() {
+		book := r.s.books[id]
+		threshold, ok := r.s.thresholds[id]
+		if !ok || book.ArchivedAt != nil || book.Quantity > threshold {
+			continue
+		}
+		item := repository.LowStockItem{BookID: id, Name: book.Name, Quantity: book.Quantity, ReorderThreshold: threshold}
+		for _, m := range r.s.movements {
+			if m.BookID == id && m.Reason == models.MovementSale && !m.CreatedAt.Before(since) {
+				item.Sold -= m.Delta
+			}
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Quantity-items[i].ReorderThreshold < items[j].Quantity-items[j].ReorderThreshold
+	})
+	return items, nil
+}
@@ -0,0 +1,166 @@
+// Package memory ist eine In-Memory-Implementierung der Storage-Interfaces aus
+// repository. Sie bildet die Semantik der Postgres-Repositories nach
+// (Bestandsprüfung, Guthabenabzug, Ablauf von Warenkorb-Reservierungen),
+// damit Services ohne Datenbank getestet und gestartet werden können.
+package memory
+
+import (
+	"bookbazaar-backend/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Gleiche Fristen wie in den SQL-Statements des BookRepository.
+const (
+	cartReservationTTL = 5 * time.Minute
+	loanDuration       = 10 * time.Minute
+)
+
+type userBook struct {
+	userID, bookID int
+}
+
+type purchase struct {
+	id          int
+	quantity    int
+	purchasedAt time.Time
+}
+
+type loan struct {
+	id         int
+	userID     int
+	bookID     int
+	borrowedAt time.Time
+	dueAt      time.Time
+	returnedAt *time.Time
+}
+
+type cartEntry struct {
+	id                   int
+	reservationExpiresAt *time.Time
+	removedAt            *time.Time
+}
+
+type refreshToken struct {
+	userID     int
+	expiresAt  time.Time
+	revoked    bool
+	lastUsedAt *time.Time
+}
+
+// Store hält alle Tabellen. Book- und UserRepository teilen sich einen Store,
+// weil z.B. ein Kauf Bestand und Guthaben atomar ändert.
+type Store struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	nextBookID, nextUserID, nextLoanID, nextCartID, nextAuthorID, nextCategoryID, nextPurchaseID, nextMovementID int
+
+	books      map[int]models.Book
+	bookAdded  map[int]time.Time // books.created_at
+	thresholds map[int]int       // books.reorder_threshold, nur gesetzte
+	authors    map[int]models.Author
+	categories map[int]models.Category
+	users      map[int]models.User
+	purchases  map[userBook]*purchase
+	loans      []*loan
+	movements  []models.StockMovement
+	cart       map[userBook]*cartEntry
+	favorites  map[userBook]time.Time
+	tokens     map[string]*refreshToken
+}
+
+func NewStore() *Store {
+	return &Store{
+		now:        time.Now,
+		books:      map[int]models.Book{},
+		bookAdded:  map[int]time.Time{},
+		thresholds: map[int]int{},
+		authors:    map[int]models.Author{},
+		categories: map[int]models.Category{},
+		users:      map[int]models.User{},
+		purchases:  map[userBook]*purchase{},
+		cart:       map[userBook]*cartEntry{},
+		favorites:  map[userBook]time.Time{},
+		tokens:     map[string]*refreshToken{},
+	}
+}
+
+// SetClock ersetzt die Uhr, z.B. um in Tests Reservierungen ablaufen zu lassen.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// SetBalance setzt das Guthaben eines Users. Ein Gegenstück in Postgres gibt
+// es nicht (dort wird das Guthaben direkt in der Tabelle gepflegt).
+func (s *Store) SetBalance(userID int, balance float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[userID]; ok {
+		u.Balance = balance
+		s.users[userID] = u
+	}
+}
+
+// SetQuantity setzt den Bestand am Journal vorbei, z.B. um den
+// Bestandsabgleich zu testen.
+func (s *Store) SetQuantity(bookID, quantity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.books[bookID]; ok {
+		b.Quantity = quantity
+		s.books[bookID] = b
+	}
+}
+
+// SetRole setzt die Rolle eines Users, z.B. um lokal einen Admin anzulegen.
+func (s *Store) SetRole(userID int, role string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[userID]; ok {
+		u.Role = role
+		s.users[userID] = u
+	}
+}
+
+func (s *Store) Books() *BookRepository {
+	return &BookRepository{s: s}
+}
+
+func (s *Store) Authors() *AuthorRepository {
+	return &AuthorRepository{s: s}
+}
+
+func (s *Store) Categories() *CategoryRepository {
+	return &CategoryRepository{s: s}
+}
+
+func (s *Store) Stock() *StockRepository {
+	return &StockRepository{s: s}
+}
+
+func (s *Store) Users() *UserRepository {
+	return &UserRepository{s: s}
+}
+
+// book liefert ein Buch mit created_at, wie es die Postgres-Abfragen tun.
+// Aufrufer hält s.mu.
+func (s *Store) book(id int) models.Book {
+	b := s.books[id]
+	created := s.bookAdded[id]
+	b.CreatedAt = &created
+	return b
+}
+
+// sortedBookIDs liefert die Buch-IDs aufsteigend, damit Listen stabil sind.
+func (s *Store) sortedBookIDs() []int {
+	ids := make([]int, 0, len(s.books))
+	for id := range s.books {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
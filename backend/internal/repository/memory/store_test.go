@@ -0,0 +1,335 @@
+package memory
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupStore legt einen Store mit einem User (Guthaben 20) und einem Buch
+// (Preis 9.99, Leihpreis 1.99, Bestand 2) an.
+func setupStore(t *testing.T) (*Store, *BookRepository, int, int) {
+	ctx := context.Background()
+	store := NewStore()
+	books, users := store.Books(), store.Users()
+
+	user := &models.User{Name: "Malek", Lastname: "Test", Username: "malek1", Email: "m@example.com", Password: "geheim123"}
+	require.NoError(t, users.AddUser(ctx, user))
+	store.SetBalance(user.ID, 20)
+
+	book := &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", Price: 9.99, BorrowPrice: 1.99, Quantity: 2}
+	require.NoError(t, books.Add(ctx, book))
+
+	return store, books, user.ID, book.ID
+}
+
+func TestBuyBook(t *testing.T) {
+	ctx := context.Background()
+	t.Run("zieht Guthaben ab und reduziert Bestand", func(t *testing.T) {
+		store, books, userID, bookID := setupStore(t)
+
+		total, err := books.BuyBook(ctx, userID, bookID)
+		require.NoError(t, err)
+		assert.InDelta(t, 9.99, total, 0.001)
+
+		user, _ := store.Users().GetUserByUserId(ctx, userID)
+		assert.InDelta(t, 10.01, user.Balance, 0.001)
+		assert.Equal(t, 1, store.books[bookID].Quantity)
+
+		ordered, _ := books.GetOrderedBooks(ctx, userID)
+		require.Len(t, ordered, 1)
+		assert.Equal(t, 1, ordered[0].OrderedQuantity)
+	})
+
+	t.Run("nicht genug Guthaben", func(t *testing.T) {
+		store, books, userID, bookID := setupStore(t)
+		store.SetBalance(userID, 5)
+
+		_, err := books.BuyBook(ctx, userID, bookID)
+
+		assert.True(t, errors.Is(err, apperr.ErrInsufficientBalance))
+	})
+
+	t.Run("ausverkauft", func(t *testing.T) {
+		store, books, userID, bookID := setupStore(t)
+		store.SetBalance(userID, 100)
+		_, err := books.BuyBook(ctx, userID, bookID)
+		require.NoError(t, err)
+		_, err = books.BuyBook(ctx, userID, bookID)
+		require.NoError(t, err)
+
+		_, err = books.BuyBook(ctx, userID, bookID)
+
+		assert.True(t, errors.Is(err, apperr.ErrOutOfStock))
+	})
+
+	t.Run("unbekanntes Buch", func(t *testing.T) {
+		_, books, userID, _ := setupStore(t)
+
+		_, err := books.BuyBook(ctx, userID, 999)
+
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	})
+}
+
+func TestBuyBooksIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	store, books, userID, bookID := setupStore(t)
+	other := &models.Book{Name: "Silmarillion", Author: "J.R.R. Tolkien", Price: 5, Quantity: 1}
+	require.NoError(t, books.Add(ctx, other))
+
+	_, err := books.BuyBooks(ctx, userID, []repository.Purchase{
+		{BookId: bookID, Quantity: 1},
+		{BookId: other.ID, Quantity: 2}, // nur 1 auf Lager
+	})
+
+	assert.True(t, errors.Is(err, apperr.ErrOutOfStock))
+	user, _ := store.Users().GetUserByUserId(ctx, userID)
+	assert.Equal(t, 20.0, user.Balance, "bei Fehler darf nichts abgebucht werden")
+	assert.Equal(t, 2, store.books[bookID].Quantity)
+}
+
+func TestBorrowAndReturn(t *testing.T) {
+	ctx := context.Background()
+	store, books, userID, bookID := setupStore(t)
+
+	require.NoError(t, books.BorrowBook(ctx, userID, bookID, 7))
+	borrowed, _ := books.GetBorrowedBooks(ctx, userID)
+	require.Len(t, borrowed, 1)
+	assert.NotEmpty(t, borrowed[0].DueAt)
+
+	require.NoError(t, books.GiveBorrowedBookBack(ctx, userID, bookID))
+	assert.Equal(t, 2, store.books[bookID].Quantity)
+
+	err := books.GiveBorrowedBookBack(ctx, userID, bookID)
+	assert.True(t, errors.Is(err, apperr.ErrNotFound), "zweite Rückgabe darf den Bestand nicht erhöhen")
+}
+
+func TestCartExpiry(t *testing.T) {
+	ctx := context.Background()
+	store, books, userID, bookID := setupStore(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.SetClock(func() time.Time { return now })
+
+	require.NoError(t, books.AddToCart(ctx, userID, bookID))
+	cart, _ := books.GetCartBooks(ctx, userID)
+	require.Len(t, cart, 1)
+
+	now = now.Add(cartReservationTTL + time.Second)
+
+	cart, _ = books.GetCartBooks(ctx, userID)
+	assert.Empty(t, cart, "abgelaufene Reservierungen werden nicht mehr angezeigt")
+
+	expired, err := books.ExpireCartReservations(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+
+	// erneutes Hinzufügen erneuert die Reservierung
+	require.NoError(t, books.AddToCart(ctx, userID, bookID))
+	cart, _ = books.GetCartBooks(ctx, userID)
+	assert.Len(t, cart, 1)
+}
+
+func TestArchiveBook(t *testing.T) {
+	ctx := context.Background()
+	store, books, userID, bookID := setupStore(t)
+	_, err := books.BuyBook(ctx, userID, bookID)
+	require.NoError(t, err)
+	require.NoError(t, books.AddToCart(ctx, userID, bookID))
+
+	require.NoError(t, books.Archive(ctx, bookID))
+	require.NoError(t, books.Archive(ctx, bookID), "erneutes Archivieren ist ein No-op")
+
+	t.Run("aus dem Katalog verschwunden", func(t *testing.T) {
+		page, err := books.ListBooks(ctx, repository.BookQuery{Sort: repository.SortName, Limit: 10})
+		require.NoError(t, err)
+		assert.Zero(t, page.Total)
+
+		archived, err := books.ListBooks(ctx, repository.BookQuery{Archived: true, Sort: repository.SortName, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, archived.Items, 1)
+		assert.NotNil(t, archived.Items[0].ArchivedAt)
+	})
+
+	t.Run("Kaufhistorie bleibt erhalten", func(t *testing.T) {
+		ordered, err := books.GetOrderedBooks(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, ordered, 1)
+		assert.NotNil(t, ordered[0].ArchivedAt)
+	})
+
+	t.Run("Kauf, Ausleihe und Warenkorb gesperrt", func(t *testing.T) {
+		store.SetBalance(userID, 100)
+		_, err := books.BuyBook(ctx, userID, bookID)
+		assert.True(t, errors.Is(err, apperr.ErrConflict))
+		assert.True(t, errors.Is(books.BorrowBook(ctx, userID, bookID, 7), apperr.ErrConflict))
+		assert.True(t, errors.Is(books.AddToCart(ctx, userID, bookID), apperr.ErrConflict))
+
+		cart, _ := books.GetCartBooks(ctx, userID)
+		assert.Empty(t, cart, "offene Warenkorb-Einträge werden beim Archivieren entfernt")
+	})
+
+	t.Run("wiederherstellen", func(t *testing.T) {
+		require.NoError(t, books.Restore(ctx, bookID))
+
+		page, err := books.ListBooks(ctx, repository.BookQuery{Sort: repository.SortName, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 1, page.Total)
+		require.NoError(t, books.AddToCart(ctx, userID, bookID))
+	})
+
+	t.Run("unbekanntes Buch", func(t *testing.T) {
+		assert.True(t, errors.Is(books.Archive(ctx, 999), apperr.ErrNotFound))
+		assert.True(t, errors.Is(books.Restore(ctx, 999), apperr.ErrNotFound))
+	})
+}
+
+func TestListBooks(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	books := store.Books()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.SetClock(func() time.Time { return now })
+
+	for _, b := range []models.Book{
+		{Name: "Der Hobbit", Author: "J.R.R. Tolkien", Genre: "Fantasy", Price: 9.99, BorrowPrice: 1.99, Quantity: 2},
+		{Name: "Silmarillion", Author: "J.R.R. Tolkien", Genre: "fantasy", Price: 14.5, Quantity: 0},
+		{Name: "Dune", Author: "Frank Herbert", Genre: "SciFi", Price: 12, BorrowPrice: 2.5, Quantity: 1},
+		{Name: "Emma", Author: "Jane Austen", Genre: "Roman", Price: 9.99, BorrowPrice: 1, Quantity: 0},
+	} {
+		require.NoError(t, books.Add(ctx, &b))
+		now = now.Add(time.Minute)
+	}
+	names := func(page repository.BookPage) []string {
+		var out []string
+		for _, b := range page.Items {
+			out = append(out, b.Name)
+		}
+		return out
+	}
+	price := func(p float64) *float64 { return &p }
+
+	t.Run("Filter", func(t *testing.T) {
+		page, err := books.ListBooks(ctx, repository.BookQuery{Genre: "FANTASY", Author: "tolkien", Sort: repository.SortName, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Der Hobbit", "Silmarillion"}, names(page))
+
+		page, err = books.ListBooks(ctx, repository.BookQuery{MinPrice: price(10), MaxPrice: price(14.5), Sort: repository.SortName, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Dune", "Silmarillion"}, names(page))
+
+		page, err = books.ListBooks(ctx, repository.BookQuery{InStock: true, Sort: repository.SortName, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Der Hobbit", "Dune"}, names(page))
+
+		page, err = books.ListBooks(ctx, repository.BookQuery{Borrowable: true, Sort: repository.SortName, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.Total, "Emma hat einen Leihpreis, ist aber nicht auf Lager")
+	})
+
+	t.Run("Sortierung", func(t *testing.T) {
+		page, err := books.ListBooks(ctx, repository.BookQuery{Sort: repository.SortPriceDesc, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Silmarillion", "Dune", "Emma", "Der Hobbit"}, names(page), "gleicher Preis: höhere ID zuerst")
+
+		page, err = books.ListBooks(ctx, repository.BookQuery{Sort: repository.SortNewest, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Emma", "Dune", "Silmarillion", "Der Hobbit"}, names(page))
+	})
+
+	t.Run("Cursor blättert lückenlos", func(t *testing.T) {
+		var all []string
+		q := repository.BookQuery{Sort: repository.SortPrice, Limit: 3}
+		for {
+			page, err := books.ListBooks(ctx, q)
+			require.NoError(t, err)
+			assert.Equal(t, 4, page.Total)
+			all = append(all, names(page)...)
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+		assert.Equal(t, []string{"Der Hobbit", "Emma", "Dune", "Silmarillion"}, all)
+	})
+
+	t.Run("Offset hinter dem Ende", func(t *testing.T) {
+		page, err := books.ListBooks(ctx, repository.BookQuery{Sort: repository.SortName, Limit: 10, Offset: 10})
+		require.NoError(t, err)
+		assert.Empty(t, page.Items)
+		assert.Equal(t, 4, page.Total)
+	})
+}
+
+func TestSearchBooks(t *testing.T) {
+	ctx := context.Background()
+	books := NewStore().Books()
+	for _, b := range []models.Book{
+		{Name: "Der Hobbit", Author: "J.R.R. Tolkien", Description: "Bilbo <b>Beutlin</b> verlässt das Auenland."},
+		{Name: "Die Gefährten", Author: "J.R.R. Tolkien", Description: "Frodo erbt den Ring von Bilbo."},
+		{Name: "Dune", Author: "Frank Herbert", Description: "Wüstenplanet Arrakis."},
+	} {
+		require.NoError(t, books.Add(ctx, &b))
+	}
+
+	t.Run("Wörter über mehrere Felder", func(t *testing.T) {
+		page, err := books.SearchBooks(ctx, repository.SearchQuery{Text: "hobbit", Limit: 10})
+		require.NoError(t, err)
+		require.Equal(t, 1, page.Total)
+
+		page, err = books.SearchBooks(ctx, repository.SearchQuery{Text: "Bilbo Tolkien", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 2)
+		assert.Equal(t, "Der Hobbit", page.Items[0].Name, "gleicher Rang: stabile Reihenfolge nach ID")
+	})
+
+	t.Run("alle Wörter müssen vorkommen", func(t *testing.T) {
+		page, err := books.SearchBooks(ctx, repository.SearchQuery{Text: "bilbo arrakis", Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, page.Items)
+	})
+
+	t.Run("Snippet ist escaped und markiert", func(t *testing.T) {
+		page, err := books.SearchBooks(ctx, repository.SearchQuery{Text: "auenland", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, "Bilbo &lt;b&gt;Beutlin&lt;/b&gt; verlässt das <mark>Auenland.</mark>", page.Items[0].Snippet)
+	})
+}
+
+func TestSuggestBooks(t *testing.T) {
+	ctx := context.Background()
+	books := NewStore().Books()
+	for _, b := range []models.Book{
+		{Name: "Der Hobbit", Author: "J.R.R. Tolkien"},
+		{Name: "Die Gefährten", Author: "J.R.R. Tolkien"},
+		{Name: "Hobbit Kochbuch", Author: "Anna Küche"},
+	} {
+		require.NoError(t, books.Add(ctx, &b))
+	}
+
+	t.Run("Tippfehler im Autor", func(t *testing.T) {
+		suggestions, err := books.SuggestBooks(ctx, "Tolkein", 10)
+
+		require.NoError(t, err)
+		require.Len(t, suggestions, 1, "Autoren werden zusammengefasst")
+		assert.Equal(t, repository.SuggestAuthor, suggestions[0].Kind)
+		assert.Equal(t, "J.R.R. Tolkien", suggestions[0].Text)
+	})
+
+	t.Run("Präfix eines Titels", func(t *testing.T) {
+		suggestions, err := books.SuggestBooks(ctx, "hobb", 1)
+
+		require.NoError(t, err)
+		require.Len(t, suggestions, 1)
+		assert.Equal(t, repository.SuggestTitle, suggestions[0].Kind)
+		assert.NotZero(t, suggestions[0].BookID)
+	})
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"strings"
+	"unicode"
+)
+
+// words zerlegt s in Wörter aus Buchstaben und Ziffern.
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// trigrams zerlegt s wie pg_trgm: klein geschrieben, je Wort aus Buchstaben
+// und Ziffern, vorne mit zwei und hinten mit einem Leerzeichen aufgefüllt.
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range words(strings.ToLower(s)) {
+		runes := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// wordSimilarity nähert word_similarity(needle, text) an: der Anteil der
+// Trigramme von needle, die irgendwo in text vorkommen.
+func wordSimilarity(needle, text string) float64 {
+	want := trigrams(needle)
+	if len(want) == 0 {
+		return 0
+	}
+	have := trigrams(text)
+	shared := 0
+	for t := range want {
+		if have[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(want))
+}
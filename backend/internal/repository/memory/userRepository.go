@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type UserRepository struct {
+	s *Store
+}
+
+var _ repository.UserStorage = (*UserRepository)(nil)
+
+func (r *UserRepository) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ids := make([]int, 0, len(r.s.users))
+	for id := range r.s.users {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var users []models.User
+	for _, id := range ids {
+		u := r.s.users[id]
+		// wie das SELECT in Postgres: ohne Guthaben und Rolle
+		u.Balance = 0
+		u.Role = ""
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func (r *UserRepository) GetUserByUserName(ctx context.Context, username string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *UserRepository) GetUserByUserId(ctx context.Context, userId int) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, err := r.s.lookupUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *UserRepository) AddUser(ctx context.Context, user *models.User) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.users {
+		if u.Username == user.Username {
+			return apperr.Conflict("username_taken", "username existiert bereits")
+		}
+	}
+
+	r.s.nextUserID++
+	user.ID = r.s.nextUserID
+	user.Created = r.s.now()
+
+	stored := *user
+	stored.Password = string(hashedPassword)
+	// Rolle und Guthaben kommen wie in Postgres aus den Spalten-Defaults
+	stored.Role = "user"
+	stored.Balance = 0
+	r.s.users[user.ID] = stored
+	return nil
+}
+
+func (r *UserRepository) ValidateUserCredentials(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := r.GetUserByUserName(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperr.Unauthorized("invalid_credentials", "Benutzername oder Passwort falsch")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, apperr.Unauthorized("invalid_credentials", "Benutzername oder Passwort falsch").WithCause(err)
+	}
+	return user, nil
+}
+
+func (r *UserRepository) CreateRefreshToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, err := r.s.lookupUser(userID); err != nil {
+		return err
+	}
+	if _, ok := r.s.tokens[tokenHash]; ok {
+		return apperr.Conflict("duplicate_refresh_token", "refresh token existiert bereits")
+	}
+	r.s.tokens[tokenHash] = &refreshToken{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *UserRepository) GetUserIDByRefreshToken(ctx context.Context, tokenHash string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tokens[tokenHash]
+	if !ok || t.revoked || !t.expiresAt.After(r.s.now()) {
+		return 0, apperr.Unauthorized("invalid_refresh_token", "Refresh Token ungültig oder abgelaufen")
+	}
+	return t.userID, nil
+}
+
+func (r *UserRepository) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if t, ok := r.s.tokens[tokenHash]; ok {
+		t.revoked = true
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateRefreshTokenLastUsed(ctx context.Context, tokenHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if t, ok := r.s.tokens[tokenHash]; ok {
+		now := r.s.now()
+		t.lastUsedAt = &now
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type MovementQuery struct {
+	BookID int
+	Limit  int
+	Offset int
+}
+
+// MovementPage listet die Bewegungen eines Buchs, neueste zuerst.
+type MovementPage struct {
+	Items  []models.StockMovement `json:"items"`
+	Total  int                    `json:"total"`
+	Limit  int                    `json:"limit"`
+	Offset int                    `json:"offset"`
+}
+
+// StockMismatch ist ein Buch, dessen Bestand nicht der Summe seiner
+// Journaleinträge entspricht.
+type StockMismatch struct {
+	BookID    int    `json:"bookId"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	LedgerSum int    `json:"ledgerSum"`
+}
+
+type StockReconciliation struct {
+	Checked    int             `json:"checked"` // geprüfte Bücher, archivierte eingeschlossen
+	Mismatches []StockMismatch `json:"mismatches"`
+}
+
+// StockLevel ist der Bestand eines Buchs mit seinem Meldebestand. Movement
+// ist die Buchung einer Bestandskorrektur; nil, wenn sich nichts änderte.
+type StockLevel struct {
+	BookID           int                   `json:"bookId"`
+	Quantity         int                   `json:"quantity"`
+	ReorderThreshold *int                  `json:"reorderThreshold"`
+	Movement         *models.StockMovement `json:"movement,omitempty"`
+}
+
+// StockAdjustment ist eine Korrektur durch einen Admin: entweder ein neuer
+// absoluter Bestand (Quantity) oder eine Änderung (Delta), nie beides.
+type StockAdjustment struct {
+	BookID   int
+	UserID   int
+	Quantity *int
+	Delta    *int
+	Note     string
+}
+
+// LowStockItem ist ein Buch am oder unter seinem Meldebestand. Sold sind die
+// seit dem Stichtag gekauften Exemplare; PerDay und DaysLeft rechnet der Service.
+type LowStockItem struct {
+	BookID           int      `json:"bookId"`
+	Name             string   `json:"name"`
+	Quantity         int      `json:"quantity"`
+	ReorderThreshold int      `json:"reorderThreshold"`
+	Sold             int      `json:"sold"`
+	PerDay           float64  `json:"perDay"`
+	DaysLeft         *float64 `json:"daysLeft"` // nil ohne Verkäufe im Zeitraum
+}
+
+type StockRepository struct {
+	db *sql.DB
+}
+
+func NewStockRepository(db *sql.DB) *StockRepository {
+	return &StockRepository{db: db}
+}
+
+// optionalID bindet 0 als NULL, z.B. für Bewegungen ohne User.
+func optionalID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// recordMovement schreibt eine Bewegung ins Journal und setzt ID, Zeitpunkt
+// und QuantityAfter. Muss nach der Änderung von books.quantity in derselben
+// Transaktion laufen: quantity_after ist der Bestand, den die Transaktion
+// danach sieht.
+func recordMovement(ctx context.Context, tx *sql.Tx, m *models.StockMovement) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO stock_movements (book_id, delta, reason, user_id, purchase_id, loan_id, note, quantity_after)
+		SELECT id, $2, $3, $4, $5, $6, $7, quantity FROM books WHERE id = $1
+		RETURNING id, quantity_after, created_at`,
+		m.BookID, m.Delta, m.Reason, m.UserID, m.PurchaseID, m.LoanID, m.Note).Scan(&m.ID, &m.QuantityAfter, &m.CreatedAt)
+	if err != nil {
+		logPgError(ctx, "bestandsbewegung konnte nicht gespeichert werden", err)
+	}
+	return err
+}
+
+func (r *StockRepository) ListMovements(ctx context.Context, q MovementQuery) (MovementPage, error) {
+	page := MovementPage{Items: []models.StockMovement{}, Limit: q.Limit, Offset: q.Offset}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM books WHERE id = $1)", q.BookID).Scan(&exists); err != nil {
+		return MovementPage{}, err
+	}
+	if !exists {
+		return MovementPage{}, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", q.BookID)
+	}
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM stock_movements WHERE book_id = $1", q.BookID).Scan(&page.Total); err != nil {
+		return MovementPage{}, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, book_id, delta, reason, user_id, purchase_id, loan_id, note, quantity_after, created_at
+		FROM stock_movements
+		WHERE book_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3`, q.BookID, q.Limit, q.Offset)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim laden der bestandsbewegungen", slog.Int("book_id", q.BookID), slog.Any("error", err))
+		return MovementPage{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m models.StockMovement
+		var userID, purchaseID, loanID sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.BookID, &m.Delta, &m.Reason, &userID, &purchaseID, &loanID, &m.Note, &m.QuantityAfter, &m.CreatedAt); err != nil {
+			return MovementPage{}, err
+		}
+		m.UserID, m.PurchaseID, m.LoanID = nullID(userID), nullID(purchaseID), nullID(loanID)
+		page.Items = append(page.Items, m)
+	}
+	return page, rows.Err()
+}
+
+func nullID(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	id := int(v.Int64)
+	return &id
+}
+
+// ReconcileStock vergleicht books.quantity mit der Summe des Journals. Beide
+// Abfragen sehen denselben Stand (REPEATABLE READ).
+func (r *StockRepository) ReconcileStock(ctx context.Context) (StockReconciliation, error) {
+	result := StockReconciliation{Mismatches: []StockMismatch{}}
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return result, fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM books").Scan(&result.Checked); err != nil {
+		return result, err
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT b.id, b.name, b.quantity, COALESCE(SUM(m.delta), 0) AS ledger_sum
+		FROM books b
+		LEFT JOIN stock_movements m ON m.book_id = b.id
+		GROUP BY b.id
+		HAVING b.quantity <> COALESCE(SUM(m.delta), 0)
+		ORDER BY b.id`)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim bestandsabgleich", slog.Any("error", err))
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m StockMismatch
+		if err := rows.Scan(&m.BookID, &m.Name, &m.Quantity, &m.LedgerSum); err != nil {
+			return result, err
+		}
+		result.Mismatches = append(result.Mismatches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	return result, tx.Commit()
+}
+
+func (r *StockRepository) GetStockLevel(ctx context.Context, bookID int) (StockLevel, error) {
+	level := StockLevel{BookID: bookID}
+	var threshold sql.NullInt64
+	err := r.db.QueryRowContext(ctx, "SELECT quantity, reorder_threshold FROM books WHERE id = $1", bookID).Scan(&level.Quantity, &threshold)
+	if err == sql.ErrNoRows {
+		return StockLevel{}, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookID)
+	}
+	if err != nil {
+		return StockLevel{}, err
+	}
+	level.ReorderThreshold = nullID(threshold)
+	return level, nil
+}
+
+// AdjustStock bucht eine Korrektur als adjustment mit dem Admin und der
+// Begründung. Ein absoluter Bestand wird unter Zeilensperre in ein Delta
+// umgerechnet; ist es 0, wird nichts gebucht. Wie Käufe ändert die Korrektur
+// die Version des Buchs nicht. Archivierte Bücher dürfen korrigiert werden,
+// z.B. um Restbestand auszubuchen.
+func (r *StockRepository) AdjustStock(ctx context.Context, adj StockAdjustment) (StockLevel, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StockLevel{}, fmt.Errorf("datenbank nicht erreichbar: %w", err)
+	}
+	defer tx.Rollback()
+
+	level := StockLevel{BookID: adj.BookID}
+	var threshold sql.NullInt64
+	err = tx.QueryRowContext(ctx, "SELECT quantity, reorder_threshold FROM books WHERE id = $1 FOR UPDATE", adj.BookID).Scan(&level.Quantity, &threshold)
+	if err == sql.ErrNoRows {
+		return StockLevel{}, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", adj.BookID)
+	}
+	if err != nil {
+		return StockLevel{}, err
+	}
+	level.ReorderThreshold = nullID(threshold)
+
+	delta, err := adjustmentDelta(adj, level.Quantity)
+	if err != nil {
+		return StockLevel{}, err
+	}
+	if delta == 0 {
+		return level, nil
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE books SET quantity = quantity + $1 WHERE id = $2", delta, adj.BookID); err != nil {
+		slog.WarnContext(ctx, "fehler beim korrigieren des bestands", slog.Int("book_id", adj.BookID), slog.Any("error", err))
+		return StockLevel{}, err
+	}
+	m := &models.StockMovement{BookID: adj.BookID, Delta: delta, Reason: models.MovementAdjustment, UserID: optionalID(adj.UserID), Note: adj.Note}
+	if err := recordMovement(ctx, tx, m); err != nil {
+		return StockLevel{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return StockLevel{}, err
+	}
+	level.Quantity, level.Movement = m.QuantityAfter, m
+	slog.InfoContext(ctx, "bestand korrigiert", slog.Int("book_id", adj.BookID), slog.Int("delta", delta))
+	return level, nil
+}
+
+// adjustmentDelta rechnet eine Korrektur gegen den aktuellen Bestand in ein
+// Delta um. Unter 0 darf der Bestand nicht fallen.
+func adjustmentDelta(adj StockAdjustment, current int) (int, error) {
+	delta := 0
+	if adj.Delta != nil {
+		delta = *adj.Delta
+	} else if adj.Quantity != nil {
+		delta = *adj.Quantity - current
+	}
+	if current+delta < 0 {
+		return 0, apperr.OutOfStock("out_of_stock", "bestand von buch %d ist %d und kann nicht um %d sinken", adj.BookID, current, -delta)
+	}
+	return delta, nil
+}
+
+// SetReorderThreshold setzt den Meldebestand; nil schaltet die Meldung ab.
+func (r *StockRepository) SetReorderThreshold(ctx context.Context, bookID int, threshold *int) (StockLevel, error) {
+	level := StockLevel{BookID: bookID, ReorderThreshold: threshold}
+	err := r.db.QueryRowContext(ctx, "UPDATE books SET reorder_threshold = $2 WHERE id = $1 RETURNING quantity", bookID, threshold).Scan(&level.Quantity)
+	if err == sql.ErrNoRows {
+		return StockLevel{}, apperr.NotFound("book_not_found", "buch mit ID %d existiert nicht", bookID)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim setzen des meldebestands", slog.Int("book_id", bookID), slog.Any("error", err))
+		return StockLevel{}, err
+	}
+	return level, nil
+}
+
+// LowStock listet angebotene Bücher am oder unter ihrem Meldebestand, die
+// knappsten zuerst, mit den seit since verkauften Exemplaren laut Journal.
+func (r *StockRepository) LowStock(ctx context.Context, since time.Time) ([]LowStockItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT b.id, b.name, b.quantity, b.reorder_threshold, COALESCE(-SUM(m.delta), 0) AS sold
+		FROM books b
+		LEFT JOIN stock_movements m ON m.book_id = b.id AND m.reason = 'sale' AND m.created_at >= $1
+		WHERE b.archived_at IS NULL AND b.reorder_threshold IS NOT NULL AND b.quantity <= b.reorder_threshold
+		GROUP BY b.id
+		ORDER BY b.quantity - b.reorder_threshold, b.id`, since)
+	if err != nil {
+		slog.WarnContext(ctx, "fehler beim laden des meldebestands", slog.Any("error", err))
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LowStockItem{}
+	for rows.Next() {
+		var item LowStockItem
+		if err := rows.Scan(&item.BookID, &item.Name, &item.Quantity, &item.ReorderThreshold, &item.Sold); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
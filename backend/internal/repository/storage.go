@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"bookbazaar-backend/internal/models"
+	"context"
+	"time"
+)
+
+// Die Interfaces beschreiben, was die Services von der Persistenz brauchen.
+// Implementiert werden sie von den Postgres-Repositories in diesem Paket und
+// vom In-Memory-Store in repository/memory (Tests, lokale Entwicklung).
+
+type CatalogStore interface {
+	ListBooks(ctx context.Context, q BookQuery) (BookPage, error)
+	SearchBooks(ctx context.Context, q SearchQuery) (SearchPage, error)
+	SuggestBooks(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+	GetBookByISBN(ctx context.Context, isbn13 string) (*models.Book, error)
+	FindDuplicate(ctx context.Context, book *models.Book) (*models.Book, error)
+	GetBookByID(ctx context.Context, id int) (*models.Book, error)
+	GetBookDetail(ctx context.Context, id, userID int) (*models.Book, Availability, error)
+	Add(ctx context.Context, book *models.Book) error
+	UpsertBooks(ctx context.Context, userID int, books []*models.Book) error
+	ExportBooks(ctx context.Context, fn func(book *models.Book) error) error
+	UpdateBook(ctx context.Context, book *models.Book, expectedVersion int) error
+	Archive(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	SetCover(ctx context.Context, id int, hash string) error
+	CoverHash(ctx context.Context, id int) (string, error)
+	AuthorNames(ctx context.Context, ids []int) (map[int]string, error)
+}
+
+// AuthorStore verwaltet Autoren. Umbenennen und Zusammenführen halten
+// books.author der betroffenen Bücher aktuell.
+type AuthorStore interface {
+	ListAuthors(ctx context.Context, q AuthorQuery) (AuthorPage, error)
+	GetAuthor(ctx context.Context, id int) (*models.Author, error)
+	GetAuthorBooks(ctx context.Context, id int) ([]models.Book, error)
+	AddAuthor(ctx context.Context, author *models.Author) error
+	UpdateAuthor(ctx context.Context, author *models.Author) error
+	DeleteAuthor(ctx context.Context, id int) error
+	MergeAuthors(ctx context.Context, targetID int, duplicateIDs []int) error
+}
+
+// CategoryStore verwaltet den Kategorienbaum.
+type CategoryStore interface {
+	ListCategories(ctx context.Context) ([]models.Category, error)
+	AddCategory(ctx context.Context, category *models.Category) error
+	UpdateCategory(ctx context.Context, category *models.Category) error
+	DeleteCategory(ctx context.Context, id int) error
+}
+
+// StockStore liest das Bestandsjournal und pflegt Korrekturen und
+// Meldebestand. Geschrieben wird das Journal von den Methoden, die den
+// Bestand ändern, jeweils in derselben Transaktion.
+type StockStore interface {
+	ListMovements(ctx context.Context, q MovementQuery) (MovementPage, error)
+	ReconcileStock(ctx context.Context) (StockReconciliation, error)
+	GetStockLevel(ctx context.Context, bookID int) (StockLevel, error)
+	AdjustStock(ctx context.Context, adj StockAdjustment) (StockLevel, error)
+	SetReorderThreshold(ctx context.Context, bookID int, threshold *int) (StockLevel, error)
+	LowStock(ctx context.Context, since time.Time) ([]LowStockItem, error)
+}
+
+// BuyBook und BuyBooks liefern den abgebuchten Gesamtbetrag.
+type OrderStore interface {
+	BuyBook(ctx context.Context, userID, bookID int) (float64, error)
+	BuyBooks(ctx context.Context, userID int, purchases []Purchase) (float64, error)
+	GetOrderedBooks(ctx context.Context, userId int) ([]models.Book, error)
+}
+
+type LoanStore interface {
+	BorrowBook(ctx context.Context, userId, bookId, days int) error
+	GiveBorrowedBookBack(ctx context.Context, userId, bookId int) error
+	GetBorrowedBooks(ctx context.Context, userId int) ([]models.Book, error)
+}
+
+type CartStore interface {
+	AddToCart(ctx context.Context, userId, bookId int) error
+	GetCartBooks(ctx context.Context, userId int) ([]models.Book, error)
+	RemoveFromCart(ctx context.Context, userId, bookId int) error
+	ExpireCartReservations(ctx context.Context) (int64, error)
+}
+
+type FavoriteStore interface {
+	AddToFavorites(ctx context.Context, userId, bookId int) error
+	GetFavoriteBooks(ctx context.Context, userId int) ([]models.Book, error)
+	DeleteFavorite(ctx context.Context, userId, bookId int) error
+}
+
+type UserStore interface {
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	GetUserByUserName(ctx context.Context, username string) (*models.User, error)
+	GetUserByUserId(ctx context.Context, userId int) (*models.User, error)
+	AddUser(ctx context.Context, user *models.User) error
+	ValidateUserCredentials(ctx context.Context, username, password string) (*models.User, error)
+}
+
+type TokenStore interface {
+	CreateRefreshToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	GetUserIDByRefreshToken(ctx context.Context, tokenHash string) (int, error)
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	UpdateRefreshTokenLastUsed(ctx context.Context, tokenHash string) error
+}
+
+// BookStorage fasst alles zusammen, was am Buch hängt (Katalog, Käufe,
+// Ausleihen, Warenkorb, Favoriten) – so wie BookRepository es anbietet.
+type BookStorage interface {
+	CatalogStore
+	OrderStore
+	LoanStore
+	CartStore
+	FavoriteStore
+}
+
+// UserStorage fasst Benutzerkonten und Refresh-Tokens zusammen.
+type UserStorage interface {
+	UserStore
+	TokenStore
+}
+
+var (
+	_ BookStorage   = (*BookRepository)(nil)
+	_ UserStorage   = (*UserRepository)(nil)
+	_ AuthorStore   = (*AuthorRepository)(nil)
+	_ CategoryStore = (*CategoryRepository)(nil)
+	_ StockStore    = (*StockRepository)(nil)
+)
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/logging"
 	"bookbazaar-backend/internal/models"
+	"context"
 	"database/sql"
-	"log"
+	"log/slog"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -17,12 +20,11 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) GetAllUsers() ([]models.User, error) {
-	log.Println("Repository.GetAllUsers wurde aufgerufen")
-	rows, err := r.db.Query("SELECT id, name, lastname, username, email, created, password FROM users")
+func (r *UserRepository) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name, lastname, username, email, created, password FROM users")
 
 	if err != nil {
-		log.Println("Fehler bei UserQuery", err)
+		slog.WarnContext(ctx, "fehler bei userquery", slog.Any("error", err))
 		return nil, err
 	}
 	defer rows.Close()
@@ -32,7 +34,7 @@ func (r *UserRepository) GetAllUsers() ([]models.User, error) {
 	for rows.Next() {
 		var user models.User
 		if err := rows.Scan(&user.ID, &user.Name, &user.Lastname, &user.Username, &user.Email, &user.Created, &user.Password); err != nil {
-			log.Println("Fehler beim Scan", err)
+			slog.WarnContext(ctx, "fehler beim scan", slog.Any("error", err))
 			return nil, err
 		}
 		users = append(users, user)
@@ -40,13 +42,13 @@ func (r *UserRepository) GetAllUsers() ([]models.User, error) {
 	return users, nil
 }
 
-func (r *UserRepository) GetUserByUserName(username string) (*models.User, error) {
+func (r *UserRepository) GetUserByUserName(ctx context.Context, username string) (*models.User, error) {
 
 	var user models.User
 
 	query := `SELECT id, name, lastname, username, email, created, password, role FROM users WHERE username=$1`
 
-	err := r.db.QueryRow(query, username).Scan(
+	err := r.db.QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Lastname,
@@ -67,21 +69,22 @@ func (r *UserRepository) GetUserByUserName(username string) (*models.User, error
 	return &user, err
 }
 
-func (r *UserRepository) GetUserByUserId(userId int) (*models.User, error) {
+func (r *UserRepository) GetUserByUserId(ctx context.Context, userId int) (*models.User, error) {
 	var user models.User
 
 	query := `SELECT id, name, lastname, username, email, created, password, balance, role FROM users Where id=$1`
 
-	err := r.db.QueryRow(query, userId).Scan(&user.ID, &user.Name, &user.Lastname, &user.Username, &user.Email, &user.Created, &user.Password, &user.Balance, &user.Role)
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(&user.ID, &user.Name, &user.Lastname, &user.Username, &user.Email, &user.Created, &user.Password, &user.Balance, &user.Role)
+	if err == sql.ErrNoRows {
+		return nil, apperr.NotFound("user_not_found", "user mit ID %d existiert nicht", userId)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepository) AddUser(user *models.User) error {
-	log.Println("Repository.AddUser wurde aufgerufen")
-
+func (r *UserRepository) AddUser(ctx context.Context, user *models.User) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 
 	if err != nil {
@@ -90,56 +93,65 @@ func (r *UserRepository) AddUser(user *models.User) error {
 
 	query := `INSERT INTO users ( name, lastname, username, email, password) VALUES($1, $2, $3, $4, $5) RETURNING created, id`
 
-	err = r.db.QueryRow(query, user.Name, user.Lastname, user.Username, user.Email, string(hashedPassword)).Scan(&user.Created, &user.ID)
+	err = r.db.QueryRowContext(ctx, query, user.Name, user.Lastname, user.Username, user.Email, string(hashedPassword)).Scan(&user.Created, &user.ID)
 
 	if err != nil {
-		log.Println("Fehler beim Insert", err)
+		slog.WarnContext(ctx, "fehler beim insert", slog.Any("error", err))
+		if pgErrorCode(err) == pgUniqueViolation {
+			return apperr.Conflict("username_taken", "username existiert bereits").WithCause(err)
+		}
 		return err
 	}
-	log.Print("Neuer User", user.Name)
+	slog.InfoContext(ctx, "user angelegt", slog.Int(logging.KeyUserID, user.ID))
 	return nil
 }
 
-func (r *UserRepository) ValidateUserCredentials(username, password string) (*models.User, error) {
-	user, err := r.GetUserByUserName(username)
+func (r *UserRepository) ValidateUserCredentials(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := r.GetUserByUserName(ctx, username)
 	if err != nil {
 		return nil, err
 	}
+	if user == nil {
+		return nil, apperr.Unauthorized("invalid_credentials", "Benutzername oder Passwort falsch")
+	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		log.Println("Fehler beim Validieren des Passwortes")
-		return nil, err
+		slog.InfoContext(ctx, "login mit falschem passwort", slog.String("username", username))
+		return nil, apperr.Unauthorized("invalid_credentials", "Benutzername oder Passwort falsch").WithCause(err)
 	}
 
 	return user, nil
 }
 
-func (r *UserRepository) CreateRefreshToken(userID int, tokenHash string, expiresAt time.Time) error {
-	_, err := r.db.Exec(`
+func (r *UserRepository) CreateRefreshToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
 		VALUES ($1, $2, $3)
 	`, userID, tokenHash, expiresAt)
 	return err
 }
 
-func (r *UserRepository) GetUserIDByRefreshToken(tokenHash string) (int, error) {
+func (r *UserRepository) GetUserIDByRefreshToken(ctx context.Context, tokenHash string) (int, error) {
 	var userID int
-	err := r.db.QueryRow(`
+	err := r.db.QueryRowContext(ctx, `
 		SELECT user_id
 		FROM refresh_tokens
 		WHERE token_hash = $1
 		  AND revoked = false
 		  AND expires_at > now()
 	`, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, apperr.Unauthorized("invalid_refresh_token", "Refresh Token ungültig oder abgelaufen")
+	}
 	if err != nil {
 		return 0, err
 	}
 	return userID, nil
 }
 
-func (r *UserRepository) RevokeRefreshToken(tokenHash string) error {
-	_, err := r.db.Exec(`
+func (r *UserRepository) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE refresh_tokens
 		SET revoked = true
 		WHERE token_hash = $1
@@ -147,8 +159,8 @@ func (r *UserRepository) RevokeRefreshToken(tokenHash string) error {
 	return err
 }
 
-func (r *UserRepository) UpdateRefreshTokenLastUsed(tokenHash string) error {
-	_, err := r.db.Exec(`
+func (r *UserRepository) UpdateRefreshTokenLastUsed(ctx context.Context, tokenHash string) error {
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE refresh_tokens
 		SET last_used_at = now()
 		WHERE token_hash = $1
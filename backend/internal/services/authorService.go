@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type AuthorService interface {
+	ListAuthors(ctx context.Context, q repository.AuthorQuery) (repository.AuthorPage, error)
+	GetAuthor(ctx context.Context, id int) (*AuthorDetail, error)
+	CreateAuthor(ctx context.Context, author *models.Author) (*models.Author, error)
+	UpdateAuthor(ctx context.Context, id int, author models.Author) (*models.Author, error)
+	DeleteAuthor(ctx context.Context, id int) error
+	MergeAuthors(ctx context.Context, targetID int, duplicateIDs []int) (*models.Author, error)
+}
+
+// AuthorDetail ist die Autorenseite: der Autor und seine Bücher im Katalog.
+type AuthorDetail struct {
+	models.Author
+	Books []models.Book `json:"books"`
+}
+
+type DefaultAuthorService struct {
+	repo repository.AuthorStore
+}
+
+func NewAuthorService(r repository.AuthorStore) AuthorService {
+	return &DefaultAuthorService{repo: r}
+}
+
+func (s *DefaultAuthorService) ListAuthors(ctx context.Context, q repository.AuthorQuery) (repository.AuthorPage, error) {
+	q.Name = strings.TrimSpace(q.Name)
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+
+	var invalid queryErrors
+	invalid.page(q.Limit, q.Offset)
+	if err := invalid.err(); err != nil {
+		return repository.AuthorPage{}, err
+	}
+	return s.repo.ListAuthors(ctx, q)
+}
+
+func (s *DefaultAuthorService) getAuthor(ctx context.Context, id int) (*models.Author, error) {
+	author, err := s.repo.GetAuthor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if author == nil {
+		return nil, apperr.NotFound("author_not_found", "kein Autor mit ID %d gefunden", id)
+	}
+	return author, nil
+}
+
+func (s *DefaultAuthorService) GetAuthor(ctx context.Context, id int) (*AuthorDetail, error) {
+	author, err := s.getAuthor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	books, err := s.repo.GetAuthorBooks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthorDetail{Author: *author, Books: books}, nil
+}
+
+func checkAuthor(author *models.Author) error {
+	author.Name = strings.TrimSpace(author.Name)
+	author.Bio = strings.TrimSpace(author.Bio)
+	if err := validator.New().Struct(author); err != nil {
+		return apperr.FromValidator("invalid_author", err)
+	}
+	return nil
+}
+
+func (s *DefaultAuthorService) CreateAuthor(ctx context.Context, author *models.Author) (*models.Author, error) {
+	author.ID = 0
+	if err := checkAuthor(author); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddAuthor(ctx, author); err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+// UpdateAuthor ersetzt Name und Bio. Ein neuer Name steht danach auch im
+// Feld author aller Bücher des Autors.
+func (s *DefaultAuthorService) UpdateAuthor(ctx context.Context, id int, author models.Author) (*models.Author, error) {
+	author.ID = id
+	if err := checkAuthor(&author); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateAuthor(ctx, &author); err != nil {
+		return nil, err
+	}
+	return s.getAuthor(ctx, id)
+}
+
+func (s *DefaultAuthorService) DeleteAuthor(ctx context.Context, id int) error {
+	return s.repo.DeleteAuthor(ctx, id)
+}
+
+// MergeAuthors führt Dubletten (etwa "J. R. R. Tolkien" und "J.R.R. Tolkien")
+// in targetID zusammen und liefert den verbleibenden Autor.
+func (s *DefaultAuthorService) MergeAuthors(ctx context.Context, targetID int, duplicateIDs []int) (*models.Author, error) {
+	var dups []int
+	seen := map[int]bool{}
+	for _, id := range duplicateIDs {
+		if id == targetID {
+			return nil, invalidField("invalid_merge", "duplicateIds", "Autor %d kann nicht mit sich selbst zusammengeführt werden", id)
+		}
+		if id < 1 {
+			return nil, invalidField("invalid_merge", "duplicateIds", "ungültige Autor-ID %d", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			dups = append(dups, id)
+		}
+	}
+	if len(dups) == 0 {
+		return nil, invalidField("invalid_merge", "duplicateIds", "mindestens eine Dublette angeben")
+	}
+
+	if err := s.repo.MergeAuthors(ctx, targetID, dups); err != nil {
+		return nil, err
+	}
+	return s.getAuthor(ctx, targetID)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"bookbazaar-backend/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthors(t *testing.T) {
+	ctx := context.Background()
+	var store *memory.Store
+	setup := func(t *testing.T) (BookService, AuthorService) {
+		store = memory.NewStore()
+		return NewBookService(store.Books(), store.Users(), nil), NewAuthorService(store.Authors())
+	}
+
+	t.Run("author ohne Liste wird zum einzigen Autor", func(t *testing.T) {
+		books, authors := setup(t)
+
+		book, err := books.Create(ctx, &models.Book{Name: "Der Hobbit", Author: " J.R.R. Tolkien "})
+
+		require.NoError(t, err)
+		require.Len(t, book.Authors, 1)
+		assert.Equal(t, "J.R.R. Tolkien", book.Author)
+		detail, err := authors.GetAuthor(ctx, book.Authors[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, detail.BookCount)
+		require.Len(t, detail.Books, 1)
+		assert.Equal(t, book.ID, detail.Books[0].ID)
+	})
+
+	t.Run("Mitautoren per ID und Name", func(t *testing.T) {
+		books, authors := setup(t)
+		pratchett, err := authors.CreateAuthor(ctx, &models.Author{Name: "Terry Pratchett"})
+		require.NoError(t, err)
+
+		book, err := books.Create(ctx, &models.Book{Name: "Ein gutes Omen", Authors: []models.AuthorRef{
+			{ID: pratchett.ID}, {Name: "Neil Gaiman"},
+		}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Terry Pratchett, Neil Gaiman", book.Author)
+		page, err := authors.ListAuthors(ctx, repository.AuthorQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.Total)
+	})
+
+	t.Run("unbekannte Autor-ID", func(t *testing.T) {
+		books, _ := setup(t)
+
+		_, err := books.Create(ctx, &models.Book{Name: "Der Hobbit", Authors: []models.AuthorRef{{ID: 42}}})
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+
+	t.Run("Umbenennen ändert author der Bücher", func(t *testing.T) {
+		books, authors := setup(t)
+		book, err := books.Create(ctx, &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkin"})
+		require.NoError(t, err)
+
+		_, err = authors.UpdateAuthor(ctx, book.Authors[0].ID, models.Author{Name: "J.R.R. Tolkien"})
+		require.NoError(t, err)
+
+		updated, err := store.Books().GetBookByID(ctx, book.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "J.R.R. Tolkien", updated.Author)
+		assert.Equal(t, book.Version+1, updated.Version)
+	})
+
+	t.Run("Zusammenführen", func(t *testing.T) {
+		books, authors := setup(t)
+		hobbit, err := books.Create(ctx, &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien"})
+		require.NoError(t, err)
+		silmarillion, err := books.Create(ctx, &models.Book{Name: "Das Silmarillion", Author: "J. R. R. Tolkien"})
+		require.NoError(t, err)
+		target, dup := hobbit.Authors[0].ID, silmarillion.Authors[0].ID
+
+		merged, err := authors.MergeAuthors(ctx, target, []int{dup, dup})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, merged.BookCount)
+		updated, err := store.Books().GetBookByID(ctx, silmarillion.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "J.R.R. Tolkien", updated.Author)
+		_, err = authors.GetAuthor(ctx, dup)
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	})
+
+	t.Run("Zusammenführen mit sich selbst", func(t *testing.T) {
+		_, authors := setup(t)
+		author, err := authors.CreateAuthor(ctx, &models.Author{Name: "Terry Pratchett"})
+		require.NoError(t, err)
+
+		_, err = authors.MergeAuthors(ctx, author.ID, []int{author.ID})
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+
+	t.Run("Autor mit Büchern wird nicht gelöscht", func(t *testing.T) {
+		books, authors := setup(t)
+		book, err := books.Create(ctx, &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien"})
+		require.NoError(t, err)
+
+		err = authors.DeleteAuthor(ctx, book.Authors[0].ID)
+
+		assert.True(t, errors.Is(err, apperr.ErrConflict))
+	})
+
+	t.Run("doppelter Name", func(t *testing.T) {
+		_, authors := setup(t)
+		_, err := authors.CreateAuthor(ctx, &models.Author{Name: "Terry Pratchett"})
+		require.NoError(t, err)
+
+		_, err = authors.CreateAuthor(ctx, &models.Author{Name: "terry pratchett"})
+
+		assert.True(t, errors.Is(err, apperr.ErrConflict))
+	})
+}
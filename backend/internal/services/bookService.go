@@ -1,11 +1,16 @@
 package services
 
 import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/isbn"
+	"bookbazaar-backend/internal/metrics"
 	"bookbazaar-backend/internal/models"
 	"bookbazaar-backend/internal/repository"
-	"errors"
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -16,34 +21,166 @@ type Purchase struct {
 }
 
 type BookService interface {
-	GetAll() ([]models.Book, error)
-	Create(book *models.Book) (*models.Book, error)
-	Delete(id int) error
-	BuyBook(userId, bookId int) error
-	BuyBooks(userId int, purchases []Purchase) error
-	BorrowBook(userId, bookId, days int) error
-	GetBorrowedBooks(userId int) ([]models.Book, error)
-	GiveBorrowedBookBack(userId, bookId int) error
-	GetCartBooks(userId int) ([]models.Book, error)
-	AddToCart(userId, bookId int) error
-	RemoveFromCart(userId, bookId int) error
-	AddToFavorites(userId, bookId int) error
-	GetFavoriteBooks(userId int) ([]models.Book, error)
-	DeleteFavorite(userId, bookId int) error
-	GetOrderedBooks(userId int) ([]models.Book, error)
+	ListBooks(ctx context.Context, q repository.BookQuery) (repository.BookPage, error)
+	SearchBooks(ctx context.Context, q repository.SearchQuery) (repository.SearchPage, error)
+	SuggestBooks(ctx context.Context, prefix string, limit int) ([]repository.Suggestion, error)
+	GetBookByISBN(ctx context.Context, raw string) (*models.Book, error)
+	GetBook(ctx context.Context, id, userID int) (*BookDetail, error)
+	Create(ctx context.Context, book *models.Book) (*models.Book, error)
+	UpdateBook(ctx context.Context, id int, book models.Book, ifMatch int) (*models.Book, error)
+	PatchBook(ctx context.Context, id int, patch BookPatch, ifMatch int) (*models.Book, error)
+	Archive(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) (*models.Book, error)
+	BuyBook(ctx context.Context, userId, bookId int) error
+	BuyBooks(ctx context.Context, userId int, purchases []Purchase) error
+	BorrowBook(ctx context.Context, userId, bookId, days int) error
+	GetBorrowedBooks(ctx context.Context, userId int) ([]models.Book, error)
+	GiveBorrowedBookBack(ctx context.Context, userId, bookId int) error
+	GetCartBooks(ctx context.Context, userId int) ([]models.Book, error)
+	AddToCart(ctx context.Context, userId, bookId int) error
+	RemoveFromCart(ctx context.Context, userId, bookId int) error
+	AddToFavorites(ctx context.Context, userId, bookId int) error
+	GetFavoriteBooks(ctx context.Context, userId int) ([]models.Book, error)
+	DeleteFavorite(ctx context.Context, userId, bookId int) error
+	GetOrderedBooks(ctx context.Context, userId int) ([]models.Book, error)
+}
+
+// BookDetail ist die Detailseite eines Buchs mit seiner Verfügbarkeit.
+type BookDetail struct {
+	models.Book
+	Availability repository.Availability `json:"availability"`
 }
 
 type DefaultBookService struct {
-	repo     *repository.BookRepository
-	userRepo *repository.UserRepository
+	repo     repository.BookStorage
+	userRepo repository.UserStore
+	metrics  *metrics.Metrics
 }
 
-func NewBookService(r *repository.BookRepository, ur *repository.UserRepository) BookService {
-	return &DefaultBookService{repo: r, userRepo: ur}
+// NewBookService baut den Service; m darf nil sein (keine Metriken).
+func NewBookService(r repository.BookStorage, ur repository.UserStore, m *metrics.Metrics) BookService {
+	return &DefaultBookService{repo: r, userRepo: ur, metrics: m}
+}
+
+// Seitengrößen des Katalogs.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// MaxSearchLength begrenzt die Länge einer Volltextsuche.
+const MaxSearchLength = 200
+
+// Grenzen der Autovervollständigung. Unter zwei Zeichen liefern Trigramme
+// keine brauchbaren Treffer.
+const (
+	MinSuggestPrefix   = 2
+	MaxSuggestPrefix   = 100
+	DefaultSuggestions = 10
+	MaxSuggestions     = 20
+)
+
+// queryErrors sammelt ungültige Query-Parameter für einen invalid_query-Fehler.
+type queryErrors []apperr.FieldError
+
+func (q *queryErrors) add(field, msg string) {
+	*q = append(*q, apperr.FieldError{Field: field, Message: msg})
 }
 
-func (s *DefaultBookService) GetAll() ([]models.Book, error) {
-	return s.repo.GetAll()
+// page prüft die Seitenangaben, die alle Listen gemeinsam haben.
+func (q *queryErrors) page(limit, offset int) {
+	if limit < 1 || limit > MaxPageSize {
+		q.add("limit", fmt.Sprintf("muss zwischen 1 und %d liegen", MaxPageSize))
+	}
+	if offset < 0 {
+		q.add("offset", "darf nicht negativ sein")
+	}
+}
+
+func (q queryErrors) err() error {
+	if len(q) == 0 {
+		return nil
+	}
+	e := apperr.Validation("invalid_query", "Ungültige Abfrage: %s %s", q[0].Field, q[0].Message)
+	e.Fields = q
+	return e
+}
+
+// ListBooks prüft die Abfrage, setzt Standardwerte (Sortierung nach Name,
+// DefaultPageSize) und liefert eine Seite des Katalogs.
+func (s *DefaultBookService) ListBooks(ctx context.Context, q repository.BookQuery) (repository.BookPage, error) {
+	if q.Sort == "" {
+		q.Sort = repository.SortName
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+
+	var invalid queryErrors
+	if !repository.ValidBookSort(q.Sort) {
+		invalid.add("sort", "muss name, -name, price, -price oder newest sein")
+	}
+	invalid.page(q.Limit, q.Offset)
+	if q.Cursor != "" && q.Offset > 0 {
+		invalid.add("cursor", "kann nicht zusammen mit offset verwendet werden")
+	}
+	if q.Category < 0 {
+		invalid.add("category", "muss eine Kategorie-ID sein")
+	}
+	if q.MinPrice != nil && *q.MinPrice < 0 {
+		invalid.add("minPrice", "darf nicht negativ sein")
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		invalid.add("maxPrice", "muss größer oder gleich minPrice sein")
+	}
+	if err := invalid.err(); err != nil {
+		return repository.BookPage{}, err
+	}
+
+	return s.repo.ListBooks(ctx, q)
+}
+
+// SearchBooks sucht im Volltext von Titel, Autor und Beschreibungen.
+func (s *DefaultBookService) SearchBooks(ctx context.Context, q repository.SearchQuery) (repository.SearchPage, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+
+	var invalid queryErrors
+	switch {
+	case q.Text == "":
+		invalid.add("q", "ist erforderlich")
+	case utf8.RuneCountInString(q.Text) > MaxSearchLength:
+		invalid.add("q", fmt.Sprintf("darf höchstens %d Zeichen lang sein", MaxSearchLength))
+	}
+	invalid.page(q.Limit, q.Offset)
+	if err := invalid.err(); err != nil {
+		return repository.SearchPage{}, err
+	}
+
+	return s.repo.SearchBooks(ctx, q)
+}
+
+// SuggestBooks liefert Titel- und Autorvorschläge für das Suchfeld.
+func (s *DefaultBookService) SuggestBooks(ctx context.Context, prefix string, limit int) ([]repository.Suggestion, error) {
+	prefix = strings.TrimSpace(prefix)
+	if limit == 0 {
+		limit = DefaultSuggestions
+	}
+
+	var invalid queryErrors
+	if n := utf8.RuneCountInString(prefix); n < MinSuggestPrefix || n > MaxSuggestPrefix {
+		invalid.add("prefix", fmt.Sprintf("muss zwischen %d und %d Zeichen lang sein", MinSuggestPrefix, MaxSuggestPrefix))
+	}
+	if limit < 1 || limit > MaxSuggestions {
+		invalid.add("limit", fmt.Sprintf("muss zwischen 1 und %d liegen", MaxSuggestions))
+	}
+	if err := invalid.err(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.SuggestBooks(ctx, prefix, limit)
 }
 
 func validateBook(Book *models.Book) error {
@@ -51,73 +188,322 @@ func validateBook(Book *models.Book) error {
 	return validate.Struct(Book)
 }
 
-// Buch hinzufügen
-func (s *DefaultBookService) Create(book *models.Book) (*models.Book, error) {
+// invalidField baut einen Validierungsfehler für genau ein Feld.
+func invalidField(code, field, format string, args ...any) error {
+	e := apperr.Validation(code, format, args...)
+	e.Fields = []apperr.FieldError{{Field: field, Message: e.Message}}
+	return e
+}
+
+// prepareBook bringt ein Buch vor dem Speichern in Form und prüft es; das
+// sind die Regeln für Anlegen, Ändern und Import.
+func prepareBook(ctx context.Context, repo repository.CatalogStore, book *models.Book) error {
+	if err := prepareAuthors(ctx, repo, book); err != nil {
+		return err
+	}
+	return checkBook(book)
+}
+
+// prepareAuthors gleicht author und authors ab: ohne Autorenliste wird das
+// Feld author zum einzigen Autor (wie vor der Autorentabelle), sonst wird
+// author aus der Liste gebildet. Verwiesene Autoren müssen existieren.
+func prepareAuthors(ctx context.Context, repo repository.CatalogStore, book *models.Book) error {
+	if len(book.Authors) == 0 {
+		if name := strings.TrimSpace(book.Author); name != "" {
+			book.Authors = []models.AuthorRef{{Name: name}}
+		}
+		return nil
+	}
+
+	var ids []int
+	for _, ref := range book.Authors {
+		if ref.ID != 0 {
+			ids = append(ids, ref.ID)
+		}
+	}
+	names, err := repo.AuthorNames(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i, ref := range book.Authors {
+		if ref.ID != 0 {
+			name, ok := names[ref.ID]
+			if !ok {
+				return invalidField("invalid_book", "authors", "Autor mit ID %d existiert nicht", ref.ID)
+			}
+			book.Authors[i].Name = name
+			continue
+		}
+		book.Authors[i].Name = strings.TrimSpace(ref.Name)
+		if utf8.RuneCountInString(book.Authors[i].Name) < 3 {
+			return invalidField("invalid_book", "authors", "autorenname muss mindestens 3 Zeichen enthalten")
+		}
+	}
+	book.Author = models.JoinAuthors(book.Authors)
+	return nil
+}
+
+// checkBook prüft ein Buch vor dem Anlegen oder Ändern.
+func checkBook(book *models.Book) error {
 	// Prüfe explizite Business Logic Validierungen zuerst
 	if len(book.Name) < 3 {
-		return nil, errors.New("buchname muss mindestens 3 Zeichen enthalten")
+		return invalidField("invalid_book", "name", "buchname muss mindestens 3 Zeichen enthalten")
 	}
 
 	if len(book.Author) < 3 {
-		return nil, errors.New("autorenname muss mindestens 3 Zeichen enthalten")
+		return invalidField("invalid_book", "author", "autorenname muss mindestens 3 Zeichen enthalten")
+	}
+
+	if err := normalizeISBN(book); err != nil {
+		return err
 	}
 
 	// Dann struct-validation (falls weitere Tags hinzugefügt werden)
 	if err := validateBook(book); err != nil {
-		return nil, err
+		return apperr.FromValidator("invalid_book", err)
+	}
+	return nil
+}
+
+// normalizeISBN bringt isbn13 und isbn10 in die gespeicherte Form: ISBN-13
+// ohne Bindestriche, ISBN-10 daraus abgeleitet. Beide Felder nehmen jede
+// Schreibweise an; wer beide angibt, muss dasselbe Buch meinen.
+func normalizeISBN(book *models.Book) error {
+	var isbn13 string
+	for _, f := range []struct{ name, raw string }{{"isbn13", book.ISBN13}, {"isbn10", book.ISBN10}} {
+		if strings.TrimSpace(f.raw) == "" {
+			continue
+		}
+		n, err := isbn.Normalize(f.raw)
+		if err != nil {
+			return invalidField("invalid_book", f.name, "%s '%s' ist keine gültige ISBN (Prüfziffer oder Länge falsch)", f.name, f.raw)
+		}
+		if isbn13 != "" && n != isbn13 {
+			return invalidField("invalid_book", f.name, "isbn10 '%s' gehört nicht zur isbn13 '%s'", f.raw, isbn13)
+		}
+		isbn13 = n
 	}
+	book.ISBN13 = isbn13
+	book.ISBN10, _ = isbn.To10(isbn13)
+	return nil
+}
 
-	existingBook, err := s.repo.GetBookByName(book.Name)
+// checkDuplicate lehnt ein Buch ab, das es schon gibt: mit ISBN über die
+// ISBN, sonst über Titel und Autor.
+func (s *DefaultBookService) checkDuplicate(ctx context.Context, book *models.Book) error {
+	existing, err := s.repo.FindDuplicate(ctx, book)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+	hint := ""
+	if existing.ArchivedAt != nil {
+		hint = " (archiviert, kann wiederhergestellt werden)"
+	}
+	if book.ISBN13 != "" {
+		return apperr.Conflict("book_exists", "Buch mit ISBN %s existiert bereits als ID %d%s", book.ISBN13, existing.ID, hint)
+	}
+	return apperr.Conflict("book_exists", "Buch '%s' von '%s' existiert bereits als ID %d%s", book.Name, book.Author, existing.ID, hint)
+}
+
+// GetBookByISBN sucht ein Buch über eine ISBN-10 oder ISBN-13 in beliebiger
+// Schreibweise.
+func (s *DefaultBookService) GetBookByISBN(ctx context.Context, raw string) (*models.Book, error) {
+	isbn13, err := isbn.Normalize(raw)
+	if err != nil {
+		return nil, invalidField("invalid_isbn", "isbn", "'%s' ist keine gültige ISBN-10 oder ISBN-13", raw)
+	}
+	book, err := s.repo.GetBookByISBN(ctx, isbn13)
+	if err != nil {
+		return nil, err
+	}
+	if book == nil {
+		return nil, apperr.NotFound("book_not_found", "kein Buch mit ISBN %s gefunden", isbn13)
+	}
+	return book, nil
+}
 
+// GetBook liefert ein Buch (auch archiviert, z.B. aus der Bestellhistorie
+// verlinkt) mit Verfügbarkeit; userID 0 ohne den Stand eines Users.
+func (s *DefaultBookService) GetBook(ctx context.Context, id, userID int) (*BookDetail, error) {
+	book, availability, err := s.repo.GetBookDetail(ctx, id, userID)
 	if err != nil {
 		return nil, err
 	}
+	return &BookDetail{Book: *book, Availability: availability}, nil
+}
+
+// Buch hinzufügen
+func (s *DefaultBookService) Create(ctx context.Context, book *models.Book) (*models.Book, error) {
+	if err := prepareBook(ctx, s.repo, book); err != nil {
+		return nil, err
+	}
+
+	book.ID = 0
+	if err := s.checkDuplicate(ctx, book); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Add(ctx, book); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// BookPatch enthält die Felder eines PATCH /api/books/:id; nil heißt
+// unverändert. Den Bestand ändert ein Update nicht. Ein neues author ohne
+// authors ersetzt die Autoren durch diesen einen; ein neues genre ohne
+// categories nimmt das Buch aus dem Kategorienbaum.
+type BookPatch struct {
+	Author          *string               `json:"author"`
+	Authors         *[]models.AuthorRef   `json:"authors"`
+	Name            *string               `json:"name"`
+	Price           *float64              `json:"price"`
+	Genre           *string               `json:"genre"`
+	Categories      *[]models.CategoryRef `json:"categories"`
+	Description     *string               `json:"description"`
+	Descriptionlong *string               `json:"descriptionLong"`
+	BorrowPrice     *float64              `json:"borrowprice"`
+	ISBN13          *string               `json:"isbn13"` // "" entfernt die ISBN
+	ISBN10          *string               `json:"isbn10"`
+}
+
+func (p BookPatch) apply(book *models.Book) {
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	set(&book.Author, p.Author)
+	if p.Authors != nil {
+		book.Authors = *p.Authors
+	} else if p.Author != nil {
+		book.Authors = nil
+	}
+	set(&book.Name, p.Name)
+	set(&book.Genre, p.Genre)
+	if p.Categories != nil {
+		book.Categories = *p.Categories
+	} else if p.Genre != nil {
+		book.Categories = nil
+	}
+	set(&book.Description, p.Description)
+	set(&book.Descriptionlong, p.Descriptionlong)
+	if p.Price != nil {
+		book.Price = *p.Price
+	}
+	if p.BorrowPrice != nil {
+		book.BorrowPrice = *p.BorrowPrice
+	}
+	// eine neue ISBN ersetzt beide Formen der alten
+	if p.ISBN13 != nil || p.ISBN10 != nil {
+		book.ISBN13, book.ISBN10 = "", ""
+		set(&book.ISBN13, p.ISBN13)
+		set(&book.ISBN10, p.ISBN10)
+	}
+}
+
+// UpdateBook ersetzt die Katalogfelder eines Buchs (PUT). ifMatch ist die
+// Version aus If-Match; 0 steht für "*" und überschreibt ohne Prüfung.
+func (s *DefaultBookService) UpdateBook(ctx context.Context, id int, book models.Book, ifMatch int) (*models.Book, error) {
+	book.ID = id
+	if err := prepareBook(ctx, s.repo, &book); err != nil {
+		return nil, err
+	}
+	if current, err := s.repo.GetBookByID(ctx, id); err != nil {
+		return nil, err
+	} else if current == nil {
+		return nil, apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", id)
+	}
+	if err := s.checkDuplicate(ctx, &book); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateBook(ctx, &book, ifMatch); err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
 
-	if existingBook != nil {
-		return nil, fmt.Errorf("Buch '%s' von '%s' existiert bereits", book.Name, book.Author)
+// PatchBook ändert einzelne Felder (PATCH). Auch bei If-Match "*" wird gegen
+// die gelesene Version geschrieben, damit eine parallele Änderung zwischen
+// Lesen und Schreiben nicht verloren geht.
+func (s *DefaultBookService) PatchBook(ctx context.Context, id int, patch BookPatch, ifMatch int) (*models.Book, error) {
+	book, err := s.repo.GetBookByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if book == nil {
+		return nil, apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", id)
+	}
+	if ifMatch != 0 && ifMatch != book.Version {
+		return nil, apperr.PreconditionFailed("version_mismatch", "Buch mit ID %d wurde inzwischen geändert (aktuelle Version %d)", id, book.Version)
 	}
 
-	if err := s.repo.Add(book); err != nil {
+	patch.apply(book)
+	if err := prepareBook(ctx, s.repo, book); err != nil {
+		return nil, err
+	}
+	if err := s.checkDuplicate(ctx, book); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateBook(ctx, book, book.Version); err != nil {
 		return nil, err
 	}
 	return book, nil
 }
 
 // Buch löschen
-func (s *DefaultBookService) Delete(id int) error {
-	return s.repo.Delete(id)
+func (s *DefaultBookService) Archive(ctx context.Context, id int) error {
+	return s.repo.Archive(ctx, id)
+}
+
+// Restore nimmt ein archiviertes Buch wieder in den Katalog auf und liefert
+// es in der neuen Version.
+func (s *DefaultBookService) Restore(ctx context.Context, id int) (*models.Book, error) {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetBookByID(ctx, id)
 }
 
-func (s *DefaultBookService) BuyBook(userId, bookId int) error {
-	user, err := s.userRepo.GetUserByUserId(userId)
+func (s *DefaultBookService) BuyBook(ctx context.Context, userId, bookId int) error {
+	user, err := s.userRepo.GetUserByUserId(ctx, userId)
 
 	if err != nil {
 		return err
 	}
 
 	if user.Balance <= 0 {
-		log.Println("user hat zu wenig Geld um ein Buch zu kaufen")
-		return err
+		slog.InfoContext(ctx, "kauf abgelehnt: kein guthaben", slog.Float64("balance", user.Balance))
+		return apperr.InsufficientBalance("insufficient_balance", "kein Guthaben vorhanden")
 	}
 
-	err = s.repo.BuyBook(userId, bookId)
+	total, err := s.repo.BuyBook(ctx, userId, bookId)
 	if err != nil {
-		log.Println("service Fehler beim Kauf eines Buches", err)
-		return fmt.Errorf("fehler beim Kauf: %w", err)
+		slog.WarnContext(ctx, "kauf fehlgeschlagen", slog.Int("book_id", bookId), slog.Any("error", err))
+		return err
 	}
+	s.metrics.BooksSold(1, total)
 	return nil
 }
 
-func (s *DefaultBookService) BuyBooks(userID int, purchases []Purchase) error {
-	user, err := s.userRepo.GetUserByUserId(userID)
+func (s *DefaultBookService) BuyBooks(ctx context.Context, userID int, purchases []Purchase) error {
+	user, err := s.userRepo.GetUserByUserId(ctx, userID)
 
 	if err != nil {
 		return err
 	}
 
 	if user.Balance <= 0 {
-		log.Println("user hat zu wenig Geld um alle Bücher aus dem Warenkorb zu kaufen")
-		return err
+		slog.InfoContext(ctx, "warenkorb-kauf abgelehnt: kein guthaben", slog.Float64("balance", user.Balance))
+		return apperr.InsufficientBalance("insufficient_balance", "kein Guthaben vorhanden")
+	}
+	for _, p := range purchases {
+		if p.Quantity < 1 {
+			return invalidField("invalid_purchase", "quantity", "menge für Buch %d muss mindestens 1 sein", p.BookId)
+		}
 	}
 	repoPurchases := make([]repository.Purchase, len(purchases))
 	for i, p := range purchases {
@@ -127,108 +513,117 @@ func (s *DefaultBookService) BuyBooks(userID int, purchases []Purchase) error {
 		}
 	}
 
-	err = s.repo.BuyBooks(userID, repoPurchases)
+	total, err := s.repo.BuyBooks(ctx, userID, repoPurchases)
 	if err != nil {
-		log.Println("service Fehler beim Kauf aller Bücher")
-		return fmt.Errorf("fehler beim Kauf: %w", err)
+		slog.WarnContext(ctx, "warenkorb-kauf fehlgeschlagen", slog.Int("positions", len(purchases)), slog.Any("error", err))
+		return err
+	}
+
+	quantity := 0
+	for _, p := range purchases {
+		quantity += p.Quantity
 	}
+	s.metrics.BooksSold(quantity, total)
 	return nil
 }
 
-func (s *DefaultBookService) BorrowBook(userId, bookId, days int) error {
-	user, err := s.userRepo.GetUserByUserId(userId)
+func (s *DefaultBookService) BorrowBook(ctx context.Context, userId, bookId, days int) error {
+	user, err := s.userRepo.GetUserByUserId(ctx, userId)
 
 	if err != nil {
 		return err
 	}
 
 	if user.Balance <= 0 {
-		log.Println("User hat zu wenig Geld um sich ein Buch auszuleihen")
-		return err
+		slog.InfoContext(ctx, "ausleihe abgelehnt: kein guthaben", slog.Float64("balance", user.Balance))
+		return apperr.InsufficientBalance("insufficient_balance", "kein Guthaben vorhanden")
 	}
 
-	err = s.repo.BorrowBook(userId, bookId, days)
+	err = s.repo.BorrowBook(ctx, userId, bookId, days)
 	if err != nil {
-		log.Println("service Fehler beim leihen eines Buches")
+		slog.WarnContext(ctx, "ausleihe fehlgeschlagen", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
+	s.metrics.LoanCreated()
 	return nil
 }
 
-func (s *DefaultBookService) GetBorrowedBooks(userId int) ([]models.Book, error) {
+func (s *DefaultBookService) GetBorrowedBooks(ctx context.Context, userId int) ([]models.Book, error) {
 
-	books, err := s.repo.GetBorrowedBooks(userId)
+	books, err := s.repo.GetBorrowedBooks(ctx, userId)
 	if err != nil {
-		log.Println("service Fehler beim getten der geliehenen Bücher", err)
+		slog.WarnContext(ctx, "ausgeliehene bücher konnten nicht geladen werden", slog.Any("error", err))
 		return nil, err
 	}
 
 	return books, nil
 }
 
-func (s *DefaultBookService) GiveBorrowedBookBack(userId, bookId int) error {
-	err := s.repo.GiveBorrowedBookBack(userId, bookId)
+func (s *DefaultBookService) GiveBorrowedBookBack(ctx context.Context, userId, bookId int) error {
+	err := s.repo.GiveBorrowedBookBack(ctx, userId, bookId)
 	if err != nil {
-		log.Print("service Fehler beim Buch zurückgeben", err)
+		slog.WarnContext(ctx, "rückgabe fehlgeschlagen", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
+	s.metrics.LoanReturned()
 	return nil
 }
 
-func (s *DefaultBookService) GetCartBooks(userId int) ([]models.Book, error) {
-	books, err := s.repo.GetCartBooks(userId)
+func (s *DefaultBookService) GetCartBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	books, err := s.repo.GetCartBooks(ctx, userId)
 	if err != nil {
-		log.Println("service Fehler beim getten der Bücher im Warenkorb", err)
+		slog.WarnContext(ctx, "warenkorb konnte nicht geladen werden", slog.Any("error", err))
 		return nil, err
 	}
 	return books, nil
 }
 
-func (s *DefaultBookService) AddToCart(userId, bookId int) error {
-	err := s.repo.AddToCart(userId, bookId)
+func (s *DefaultBookService) AddToCart(ctx context.Context, userId, bookId int) error {
+	err := s.repo.AddToCart(ctx, userId, bookId)
 	if err != nil {
-		log.Println("service Fehler beim hinzufügen der Bücher im Warenkorb", err)
+		slog.WarnContext(ctx, "buch konnte nicht in den warenkorb gelegt werden", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
+	s.metrics.CartAdded()
 
 	return nil
 }
 
-func (s *DefaultBookService) RemoveFromCart(userId, bookId int) error {
-	err := s.repo.RemoveFromCart(userId, bookId)
+func (s *DefaultBookService) RemoveFromCart(ctx context.Context, userId, bookId int) error {
+	err := s.repo.RemoveFromCart(ctx, userId, bookId)
 	if err != nil {
-		log.Println("service Fehler beim entfernen der Bücher aus dem Warenkorb", err)
+		slog.WarnContext(ctx, "buch konnte nicht aus dem warenkorb entfernt werden", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
 	return nil
 }
-func (s *DefaultBookService) AddToFavorites(userId, bookId int) error {
-	err := s.repo.AddToFavorites(userId, bookId)
+func (s *DefaultBookService) AddToFavorites(ctx context.Context, userId, bookId int) error {
+	err := s.repo.AddToFavorites(ctx, userId, bookId)
 	if err != nil {
-		log.Println("service Fehler beim hinzufügen eines Buches in die Favoriten")
+		slog.WarnContext(ctx, "favorit konnte nicht hinzugefügt werden", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
 	return nil
 }
 
-func (s *DefaultBookService) GetFavoriteBooks(userId int) ([]models.Book, error) {
-	return s.repo.GetFavoriteBooks(userId)
+func (s *DefaultBookService) GetFavoriteBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	return s.repo.GetFavoriteBooks(ctx, userId)
 }
 
-func (s *DefaultBookService) DeleteFavorite(userId, bookId int) error {
-	err := s.repo.DeleteFavorite(userId, bookId)
+func (s *DefaultBookService) DeleteFavorite(ctx context.Context, userId, bookId int) error {
+	err := s.repo.DeleteFavorite(ctx, userId, bookId)
 	if err != nil {
-		log.Println("service Fehler beim Löschen eines Favoriten")
+		slog.WarnContext(ctx, "favorit konnte nicht gelöscht werden", slog.Int("book_id", bookId), slog.Any("error", err))
 		return err
 	}
 	return nil
 }
 
-func (s *DefaultBookService) GetOrderedBooks(userId int) ([]models.Book, error) {
-	books, err := s.repo.GetOrderedBooks(userId)
+func (s *DefaultBookService) GetOrderedBooks(ctx context.Context, userId int) ([]models.Book, error) {
+	books, err := s.repo.GetOrderedBooks(ctx, userId)
 
 	if err != nil {
-		log.Println("service Fehler beim getten der gekauften Bücher")
+		slog.WarnContext(ctx, "gekaufte bücher konnten nicht geladen werden", slog.Any("error", err))
 		return nil, err
 	}
 
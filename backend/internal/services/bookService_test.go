@@ -1,10 +1,17 @@
 package services
 
 import (
+	"bookbazaar-backend/internal/apperr"
 	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"bookbazaar-backend/internal/repository/memory"
+	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestValidateBook testet die validateBook Funktion
@@ -81,6 +88,7 @@ func TestValidateBook(t *testing.T) {
 // TestCreateBookValidations testet die Business Logic der Create-Methode
 // Diese Tests prüfen die Validierungsregeln ohne Datenbankzugriffe
 func TestCreateBookValidations(t *testing.T) {
+	ctx := context.Background()
 	// Hier erstellen wir einen Mock für die Repositories
 	// Da wir nur die Validierungslogik testen wollen, verwenden wir nil
 	// In echten Tests würdest du Mocks verwenden
@@ -101,7 +109,7 @@ func TestCreateBookValidations(t *testing.T) {
 		}
 
 		// Act
-		result, err := service.Create(shortNameBook)
+		result, err := service.Create(ctx, shortNameBook)
 
 		// Assert
 		assert.Error(t, err, "Ein Buch mit zu kurzem Namen sollte einen Fehler zurückgeben")
@@ -121,7 +129,7 @@ func TestCreateBookValidations(t *testing.T) {
 		}
 
 		// Act
-		result, err := service.Create(shortAuthorBook)
+		result, err := service.Create(ctx, shortAuthorBook)
 
 		// Assert
 		assert.Error(t, err, "Ein Buch mit zu kurzem Autorennamen sollte einen Fehler zurückgeben")
@@ -130,3 +138,338 @@ func TestCreateBookValidations(t *testing.T) {
 	})
 
 }
+
+// TestListBooksValidation prüft Standardwerte und ungültige Filter von ListBooks.
+func TestListBooksValidation(t *testing.T) {
+	ctx := context.Background()
+	service := NewBookService(memory.NewStore().Books(), nil, nil)
+	price := func(p float64) *float64 { return &p }
+
+	t.Run("Standardwerte", func(t *testing.T) {
+		page, err := service.ListBooks(ctx, repository.BookQuery{})
+
+		require.NoError(t, err)
+		assert.Equal(t, DefaultPageSize, page.Limit)
+		assert.NotNil(t, page.Items, "leere Seite wird als [] ausgeliefert")
+	})
+
+	tests := []struct {
+		name  string
+		query repository.BookQuery
+		field string
+	}{
+		{"unbekannte Sortierung", repository.BookQuery{Sort: "rating"}, "sort"},
+		{"Limit zu groß", repository.BookQuery{Limit: MaxPageSize + 1}, "limit"},
+		{"negativer Offset", repository.BookQuery{Offset: -1}, "offset"},
+		{"Cursor und Offset", repository.BookQuery{Cursor: "abc", Offset: 20}, "cursor"},
+		{"Preisspanne vertauscht", repository.BookQuery{MinPrice: price(20), MaxPrice: price(10)}, "maxPrice"},
+		{"kaputter Cursor", repository.BookQuery{Cursor: "kein-cursor"}, "cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ListBooks(ctx, tt.query)
+
+			require.True(t, errors.Is(err, apperr.ErrValidation))
+			e, _ := apperr.As(err)
+			require.NotEmpty(t, e.Fields)
+			assert.Equal(t, tt.field, e.Fields[0].Field)
+		})
+	}
+}
+
+func TestSearchBooksValidation(t *testing.T) {
+	ctx := context.Background()
+	service := NewBookService(memory.NewStore().Books(), nil, nil)
+
+	tests := []struct {
+		name  string
+		query repository.SearchQuery
+		field string
+	}{
+		{"leere Suche", repository.SearchQuery{Text: "   "}, "q"},
+		{"zu lange Suche", repository.SearchQuery{Text: strings.Repeat("a", MaxSearchLength+1)}, "q"},
+		{"Limit zu groß", repository.SearchQuery{Text: "hobbit", Limit: MaxPageSize + 1}, "limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.SearchBooks(ctx, tt.query)
+
+			require.True(t, errors.Is(err, apperr.ErrValidation))
+			e, _ := apperr.As(err)
+			assert.Equal(t, tt.field, e.Fields[0].Field)
+		})
+	}
+}
+
+func TestSuggestBooksValidation(t *testing.T) {
+	ctx := context.Background()
+	service := NewBookService(memory.NewStore().Books(), nil, nil)
+
+	t.Run("zu kurzes Präfix", func(t *testing.T) {
+		_, err := service.SuggestBooks(ctx, " h ", 0)
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+
+	t.Run("leere Liste statt null", func(t *testing.T) {
+		suggestions, err := service.SuggestBooks(ctx, "hobbit", 0)
+
+		require.NoError(t, err)
+		assert.NotNil(t, suggestions)
+	})
+}
+
+func TestUpdateBook(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (BookService, *models.Book) {
+		store := memory.NewStore()
+		service := NewBookService(store.Books(), store.Users(), nil)
+		book, err := service.Create(ctx, &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", Price: 10, Quantity: 3})
+		require.NoError(t, err)
+		require.Equal(t, 1, book.Version)
+		return service, book
+	}
+
+	t.Run("PUT erhöht die Version und lässt den Bestand", func(t *testing.T) {
+		service, book := setup(t)
+
+		updated, err := service.UpdateBook(ctx, book.ID, models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", Price: 12, Quantity: 99}, 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, updated.Version)
+		assert.Equal(t, 12.0, updated.Price)
+		assert.Equal(t, 3, updated.Quantity)
+	})
+
+	t.Run("veraltete Version", func(t *testing.T) {
+		service, book := setup(t)
+		price := 11.0
+		_, err := service.PatchBook(ctx, book.ID, BookPatch{Price: &price}, 1)
+		require.NoError(t, err)
+
+		_, err = service.UpdateBook(ctx, book.ID, models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", Price: 12}, 1)
+		assert.True(t, errors.Is(err, apperr.ErrPreconditionFailed))
+
+		_, err = service.PatchBook(ctx, book.ID, BookPatch{Price: &price}, 1)
+		assert.True(t, errors.Is(err, apperr.ErrPreconditionFailed))
+	})
+
+	t.Run("PATCH ändert nur mitgeschickte Felder", func(t *testing.T) {
+		service, book := setup(t)
+		long := "Ein Abenteuer in Mittelerde."
+
+		updated, err := service.PatchBook(ctx, book.ID, BookPatch{Descriptionlong: &long}, 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, long, updated.Descriptionlong)
+		assert.Equal(t, 10.0, updated.Price)
+		assert.Equal(t, 2, updated.Version)
+	})
+
+	t.Run("PATCH wird validiert", func(t *testing.T) {
+		service, book := setup(t)
+		name := "ab"
+
+		_, err := service.PatchBook(ctx, book.ID, BookPatch{Name: &name}, 1)
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+
+	t.Run("unbekanntes Buch", func(t *testing.T) {
+		service, _ := setup(t)
+
+		_, err := service.UpdateBook(ctx, 999, models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien"}, 0)
+
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	})
+}
+
+func TestBookISBN(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (BookService, *models.Book) {
+		service := NewBookService(memory.NewStore().Books(), nil, nil)
+		book, err := service.Create(ctx, &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", ISBN10: "3-608-93828-1"})
+		require.NoError(t, err)
+		return service, book
+	}
+
+	t.Run("ISBN-10 wird als ISBN-13 gespeichert", func(t *testing.T) {
+		_, book := setup(t)
+
+		assert.Equal(t, "9783608938289", book.ISBN13)
+		assert.Equal(t, "3608938281", book.ISBN10)
+	})
+
+	t.Run("Suche über beide Formen", func(t *testing.T) {
+		service, book := setup(t)
+
+		for _, raw := range []string{"978-3-608-93828-9", "3608938281"} {
+			found, err := service.GetBookByISBN(ctx, raw)
+			require.NoError(t, err, raw)
+			assert.Equal(t, book.ID, found.ID)
+		}
+
+		_, err := service.GetBookByISBN(ctx, "3608938282")
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+		_, err = service.GetBookByISBN(ctx, "9780804429573")
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	})
+
+	t.Run("Dubletten über die ISBN, nicht über den Titel", func(t *testing.T) {
+		service, _ := setup(t)
+
+		_, err := service.Create(ctx, &models.Book{Name: "Anderer Titel", Author: "Jemand Anders", ISBN13: "9783608938289"})
+		assert.True(t, errors.Is(err, apperr.ErrConflict))
+
+		// andere Ausgabe desselben Titels
+		_, err = service.Create(ctx, &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", ISBN13: "978-0-8044-2957-3"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ohne ISBN über Titel und Autor", func(t *testing.T) {
+		service, _ := setup(t)
+
+		_, err := service.Create(ctx, &models.Book{Name: "Momo", Author: "Michael Ende"})
+		require.NoError(t, err)
+		_, err = service.Create(ctx, &models.Book{Name: "momo", Author: "MICHAEL ENDE"})
+		assert.True(t, errors.Is(err, apperr.ErrConflict))
+	})
+
+	t.Run("ISBN-10 und ISBN-13 müssen zusammenpassen", func(t *testing.T) {
+		service, _ := setup(t)
+
+		_, err := service.Create(ctx, &models.Book{Name: "Momo", Author: "Michael Ende", ISBN13: "9780804429573", ISBN10: "3608938281"})
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+
+	t.Run("PATCH ersetzt und entfernt die ISBN", func(t *testing.T) {
+		service, book := setup(t)
+		other, empty := "0-8044-2957-X", ""
+
+		updated, err := service.PatchBook(ctx, book.ID, BookPatch{ISBN10: &other}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "9780804429573", updated.ISBN13)
+
+		updated, err = service.PatchBook(ctx, book.ID, BookPatch{ISBN13: &empty}, 0)
+		require.NoError(t, err)
+		assert.Empty(t, updated.ISBN13)
+		assert.Empty(t, updated.ISBN10)
+	})
+}
+
+// TestBuyBooksWithMemoryStore prüft den Kaufablauf gegen den In-Memory-Store,
+// also inklusive Bestands- und Guthabenprüfung, aber ohne Datenbank.
+func TestBuyBooksWithMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (BookService, *memory.Store, int, int) {
+		store := memory.NewStore()
+		user := &models.User{Name: "Malek", Lastname: "Test", Username: "malek1", Email: "m@example.com", Password: "geheim123"}
+		require.NoError(t, store.Users().AddUser(ctx, user))
+
+		service := NewBookService(store.Books(), store.Users(), nil)
+		book, err := service.Create(ctx, &models.Book{
+			Name: "Der Hobbit", Author: "J.R.R. Tolkien", Genre: "Fantasy",
+			Description: "Test Beschreibung", Price: 10, BorrowPrice: 2, Quantity: 3,
+		})
+		require.NoError(t, err)
+		return service, store, user.ID, book.ID
+	}
+
+	t.Run("ohne Guthaben", func(t *testing.T) {
+		service, _, userID, bookID := setup(t)
+
+		err := service.BuyBooks(ctx, userID, []Purchase{{BookId: bookID, Quantity: 1}})
+
+		assert.True(t, errors.Is(err, apperr.ErrInsufficientBalance))
+	})
+
+	t.Run("Kauf reduziert Bestand", func(t *testing.T) {
+		service, store, userID, bookID := setup(t)
+		store.SetBalance(userID, 50)
+
+		require.NoError(t, service.BuyBooks(ctx, userID, []Purchase{{BookId: bookID, Quantity: 2}}))
+
+		page, err := service.ListBooks(ctx, repository.BookQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, page.Items[0].Quantity)
+		ordered, err := service.GetOrderedBooks(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, ordered[0].OrderedQuantity)
+	})
+
+	t.Run("doppeltes Buch", func(t *testing.T) {
+		service, _, _, _ := setup(t)
+
+		_, err := service.Create(ctx, &models.Book{
+			Name: "Der Hobbit", Author: "J.R.R. Tolkien", Genre: "Fantasy",
+			Description: "Nochmal", Price: 10,
+		})
+
+		assert.True(t, errors.Is(err, apperr.ErrConflict))
+	})
+}
+
+func TestGetBook(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	service := NewBookService(store.Books(), store.Users(), nil)
+	var userIDs []int
+	for _, name := range []string{"leser1", "leser2"} {
+		user := &models.User{Name: "Malek", Lastname: "Test", Username: name, Email: name + "@example.com", Password: "geheim123"}
+		require.NoError(t, store.Users().AddUser(ctx, user))
+		store.SetBalance(user.ID, 50)
+		userIDs = append(userIDs, user.ID)
+	}
+	reader, other := userIDs[0], userIDs[1]
+	book, err := service.Create(ctx, &models.Book{
+		Name: "Der Hobbit", Author: "J.R.R. Tolkien", Genre: "Fantasy",
+		Description: "Test Beschreibung", Price: 10, BorrowPrice: 2, Quantity: 5,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, service.BorrowBook(ctx, reader, book.ID, 7))
+	require.NoError(t, service.BorrowBook(ctx, other, book.ID, 3))
+	require.NoError(t, service.AddToFavorites(ctx, reader, book.ID))
+	require.NoError(t, service.AddToCart(ctx, reader, book.ID))
+	require.NoError(t, service.BuyBook(ctx, other, book.ID))
+
+	t.Run("Verfügbarkeit und Stand des Users", func(t *testing.T) {
+		detail, err := service.GetBook(ctx, book.ID, reader)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Der Hobbit", detail.Name)
+		assert.Equal(t, 2, detail.Availability.InStock)
+		assert.Equal(t, 2, detail.Availability.OnLoan)
+		require.NotNil(t, detail.Availability.NextDueAt)
+		assert.Equal(t, &repository.UserBookState{Favorite: true, InCart: true, OnLoan: true}, detail.Availability.User)
+	})
+
+	t.Run("anderer User", func(t *testing.T) {
+		detail, err := service.GetBook(ctx, book.ID, other)
+
+		require.NoError(t, err)
+		assert.Equal(t, &repository.UserBookState{OnLoan: true, Purchased: true}, detail.Availability.User)
+	})
+
+	t.Run("ohne User", func(t *testing.T) {
+		detail, err := service.GetBook(ctx, book.ID, 0)
+
+		require.NoError(t, err)
+		assert.Nil(t, detail.Availability.User)
+	})
+
+	t.Run("archiviert", func(t *testing.T) {
+		require.NoError(t, service.Archive(ctx, book.ID))
+
+		detail, err := service.GetBook(ctx, book.ID, reader)
+
+		require.NoError(t, err)
+		assert.NotNil(t, detail.ArchivedAt)
+	})
+
+	t.Run("unbekannt", func(t *testing.T) {
+		_, err := service.GetBook(ctx, 999, reader)
+
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	})
+}
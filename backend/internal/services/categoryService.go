@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type CategoryService interface {
+	Tree(ctx context.Context) ([]CategoryNode, error)
+	CreateCategory(ctx context.Context, category *models.Category) (*models.Category, error)
+	UpdateCategory(ctx context.Context, id int, category models.Category) (*models.Category, error)
+	DeleteCategory(ctx context.Context, id int) error
+}
+
+// CategoryNode ist eine Kategorie im Baum; Kinder sind alphabetisch sortiert.
+type CategoryNode struct {
+	models.Category
+	Children []CategoryNode `json:"children"`
+}
+
+type DefaultCategoryService struct {
+	repo repository.CategoryStore
+}
+
+func NewCategoryService(r repository.CategoryStore) CategoryService {
+	return &DefaultCategoryService{repo: r}
+}
+
+// Tree baut aus der flachen Liste den Baum für Navigationsmenüs.
+func (s *DefaultCategoryService) Tree(ctx context.Context) ([]CategoryNode, error) {
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[int][]models.Category{} // Oberkategorie -> Kinder, 0 = oberste Ebene
+	for _, c := range categories {
+		parent := 0
+		if c.ParentID != nil {
+			parent = *c.ParentID
+		}
+		children[parent] = append(children[parent], c)
+	}
+	var build func(parent int) []CategoryNode
+	build = func(parent int) []CategoryNode {
+		nodes := []CategoryNode{}
+		for _, c := range children[parent] {
+			nodes = append(nodes, CategoryNode{Category: c, Children: build(c.ID)})
+		}
+		return nodes
+	}
+	return build(0), nil
+}
+
+func checkCategory(category *models.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if err := validator.New().Struct(category); err != nil {
+		return apperr.FromValidator("invalid_category", err)
+	}
+	if category.ParentID != nil && *category.ParentID < 1 {
+		return invalidField("invalid_category", "parentId", "ungültige Oberkategorie %d", *category.ParentID)
+	}
+	return nil
+}
+
+func (s *DefaultCategoryService) CreateCategory(ctx context.Context, category *models.Category) (*models.Category, error) {
+	category.ID = 0
+	if err := checkCategory(category); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddCategory(ctx, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// UpdateCategory benennt um und hängt um (parentId null = oberste Ebene).
+func (s *DefaultCategoryService) UpdateCategory(ctx context.Context, id int, category models.Category) (*models.Category, error) {
+	category.ID = id
+	if err := checkCategory(&category); err != nil {
+		return nil, err
+	}
+	if category.ParentID != nil && *category.ParentID == id {
+		return nil, invalidField("category_cycle", "parentId", "Kategorie %d kann nicht unter sich selbst", id)
+	}
+	if err := s.repo.UpdateCategory(ctx, &category); err != nil {
+		return nil, err
+	}
+
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, apperr.NotFound("category_not_found", "keine Kategorie mit ID %d gefunden", id)
+}
+
+func (s *DefaultCategoryService) DeleteCategory(ctx context.Context, id int) error {
+	return s.repo.DeleteCategory(ctx, id)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"bookbazaar-backend/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategories(t *testing.T) {
+	ctx := context.Background()
+	// Belletristik > Fantasy > High Fantasy, daneben Sachbuch
+	type tree struct {
+		books                                     BookService
+		categories                                CategoryService
+		fiction, fantasy, highFantasy, nonFiction *models.Category
+	}
+	setup := func(t *testing.T) tree {
+		store := memory.NewStore()
+		tr := tree{books: NewBookService(store.Books(), store.Users(), nil), categories: NewCategoryService(store.Categories())}
+		add := func(name string, parent *models.Category) *models.Category {
+			c := &models.Category{Name: name}
+			if parent != nil {
+				c.ParentID = &parent.ID
+			}
+			c, err := tr.categories.CreateCategory(ctx, c)
+			require.NoError(t, err)
+			return c
+		}
+		tr.fiction = add("Belletristik", nil)
+		tr.fantasy = add("Fantasy", tr.fiction)
+		tr.highFantasy = add("High Fantasy", tr.fantasy)
+		tr.nonFiction = add("Sachbuch", nil)
+		return tr
+	}
+	addBook := func(t *testing.T, tr tree, name string, categories ...*models.Category) *models.Book {
+		book := &models.Book{Name: name, Author: "J.R.R. Tolkien"}
+		for _, c := range categories {
+			book.Categories = append(book.Categories, models.CategoryRef{ID: c.ID})
+		}
+		book, err := tr.books.Create(ctx, book)
+		require.NoError(t, err)
+		return book
+	}
+
+	t.Run("erste Kategorie wird zum Genre", func(t *testing.T) {
+		tr := setup(t)
+
+		book := addBook(t, tr, "Der Hobbit", tr.highFantasy, tr.fiction)
+
+		assert.Equal(t, "High Fantasy", book.Genre)
+		assert.Equal(t, []models.CategoryRef{{ID: tr.highFantasy.ID, Name: "High Fantasy"}, {ID: tr.fiction.ID, Name: "Belletristik"}}, book.Categories)
+	})
+
+	t.Run("unbekannte Kategorie", func(t *testing.T) {
+		tr := setup(t)
+
+		_, err := tr.books.Create(ctx, &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", Categories: []models.CategoryRef{{ID: 99}}})
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+
+	t.Run("Filter auf Oberkategorie enthält Unterkategorien", func(t *testing.T) {
+		tr := setup(t)
+		addBook(t, tr, "Der Hobbit", tr.highFantasy)
+		addBook(t, tr, "Die unendliche Geschichte", tr.fantasy)
+		addBook(t, tr, "Kurze Geschichte der Zeit", tr.nonFiction)
+
+		page, err := tr.books.ListBooks(ctx, repository.BookQuery{Category: tr.fiction.ID})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.Total)
+		page, err = tr.books.ListBooks(ctx, repository.BookQuery{Category: tr.highFantasy.ID})
+		require.NoError(t, err)
+		assert.Equal(t, 1, page.Total)
+	})
+
+	t.Run("Baum mit Buchanzahl", func(t *testing.T) {
+		tr := setup(t)
+		// in zwei Kategorien desselben Zweigs zählt das Buch oben nur einmal
+		addBook(t, tr, "Der Hobbit", tr.highFantasy, tr.fantasy)
+		addBook(t, tr, "Kurze Geschichte der Zeit", tr.nonFiction)
+
+		roots, err := tr.categories.Tree(ctx)
+
+		require.NoError(t, err)
+		require.Len(t, roots, 2)
+		assert.Equal(t, "Belletristik", roots[0].Name)
+		assert.Equal(t, 1, roots[0].BookCount)
+		require.Len(t, roots[0].Children, 1)
+		assert.Equal(t, 1, roots[0].Children[0].BookCount)
+		require.Len(t, roots[0].Children[0].Children, 1)
+		assert.Equal(t, "High Fantasy", roots[0].Children[0].Children[0].Name)
+		assert.Empty(t, roots[1].Children)
+	})
+
+	t.Run("kein Zyklus beim Umhängen", func(t *testing.T) {
+		tr := setup(t)
+
+		_, err := tr.categories.UpdateCategory(ctx, tr.fiction.ID, models.Category{Name: "Belletristik", ParentID: &tr.highFantasy.ID})
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+
+	t.Run("Umbenennen ändert das Genre", func(t *testing.T) {
+		tr := setup(t)
+		book := addBook(t, tr, "Der Hobbit", tr.highFantasy)
+
+		updated, err := tr.categories.UpdateCategory(ctx, tr.highFantasy.ID, models.Category{Name: "Epische Fantasy", ParentID: &tr.fantasy.ID})
+		require.NoError(t, err)
+		assert.Equal(t, "Epische Fantasy", updated.Name)
+
+		page, err := tr.books.ListBooks(ctx, repository.BookQuery{Category: tr.fantasy.ID})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, "Epische Fantasy", page.Items[0].Genre)
+		assert.Equal(t, book.Version+1, page.Items[0].Version)
+	})
+
+	t.Run("gleicher Name unter derselben Oberkategorie", func(t *testing.T) {
+		tr := setup(t)
+
+		_, err := tr.categories.CreateCategory(ctx, &models.Category{Name: "fantasy", ParentID: &tr.fiction.ID})
+		assert.True(t, errors.Is(err, apperr.ErrConflict))
+
+		_, err = tr.categories.CreateCategory(ctx, &models.Category{Name: "Fantasy", ParentID: &tr.nonFiction.ID})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Kategorie mit Unterkategorien oder Büchern wird nicht gelöscht", func(t *testing.T) {
+		tr := setup(t)
+		addBook(t, tr, "Kurze Geschichte der Zeit", tr.nonFiction)
+
+		assert.True(t, errors.Is(tr.categories.DeleteCategory(ctx, tr.fantasy.ID), apperr.ErrConflict))
+		assert.True(t, errors.Is(tr.categories.DeleteCategory(ctx, tr.nonFiction.ID), apperr.ErrConflict))
+		assert.NoError(t, tr.categories.DeleteCategory(ctx, tr.highFantasy.ID))
+	})
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/blob"
+	"bookbazaar-backend/internal/covers"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+type CoverService interface {
+	SetCover(ctx context.Context, bookID int, r io.Reader) (*models.Book, error)
+	OpenCover(ctx context.Context, bookID int, hash, size string) (io.ReadCloser, blob.Info, error)
+}
+
+type DefaultCoverService struct {
+	repo     repository.CatalogStore
+	blobs    blob.Store
+	maxBytes int
+}
+
+// NewCoverService baut den Service; maxBytes begrenzt die Größe eines Uploads.
+func NewCoverService(r repository.CatalogStore, b blob.Store, maxBytes int) CoverService {
+	return &DefaultCoverService{repo: r, blobs: b, maxBytes: maxBytes}
+}
+
+// SetCover prüft das hochgeladene Bild, legt alle Größen im Blob-Speicher ab
+// und hängt das Cover an das Buch. Das vorherige Cover wird danach gelöscht.
+func (s *DefaultCoverService) SetCover(ctx context.Context, bookID int, r io.Reader) (*models.Book, error) {
+	book, err := s.repo.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book == nil {
+		return nil, apperr.NotFound("book_not_found", "kein Buch mit ID %d gefunden", bookID)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(s.maxBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > s.maxBytes {
+		return nil, apperr.TooLarge("cover_too_large", "Cover darf höchstens %d Bytes groß sein", s.maxBytes)
+	}
+
+	cover, err := covers.Process(data)
+	if err != nil {
+		return nil, err
+	}
+	if cover.Hash == book.CoverHash {
+		return book, nil
+	}
+
+	for _, size := range covers.Sizes {
+		if err := s.blobs.Put(ctx, covers.Key(bookID, cover.Hash, size.Name), bytes.NewReader(cover.Images[size.Name])); err != nil {
+			s.deleteCover(ctx, bookID, cover.Hash)
+			return nil, err
+		}
+	}
+	if err := s.repo.SetCover(ctx, bookID, cover.Hash); err != nil {
+		s.deleteCover(ctx, bookID, cover.Hash)
+		return nil, err
+	}
+	if book.CoverHash != "" {
+		s.deleteCover(ctx, bookID, book.CoverHash)
+	}
+	slog.InfoContext(ctx, "cover gespeichert", slog.Int("book_id", bookID), slog.String("hash", cover.Hash))
+
+	return s.repo.GetBookByID(ctx, bookID)
+}
+
+// deleteCover räumt alle Größen eines Covers weg. Fehler werden nur geloggt:
+// verwaiste Dateien stören niemanden, ein fehlgeschlagener Upload schon.
+func (s *DefaultCoverService) deleteCover(ctx context.Context, bookID int, hash string) {
+	for _, size := range covers.Sizes {
+		if err := s.blobs.Delete(ctx, covers.Key(bookID, hash, size.Name)); err != nil {
+			slog.WarnContext(ctx, "cover konnte nicht gelöscht werden",
+				slog.Int("book_id", bookID), slog.String("hash", hash), slog.Any("error", err))
+		}
+	}
+}
+
+// OpenCover öffnet eine Größe eines Covers zum Ausliefern. Nur das aktuelle
+// Cover des Buchs wird geliefert, ein ersetztes ist 404, auch wenn seine
+// Datei noch liegt.
+func (s *DefaultCoverService) OpenCover(ctx context.Context, bookID int, hash, size string) (io.ReadCloser, blob.Info, error) {
+	if !covers.ValidSize(size) {
+		return nil, blob.Info{}, apperr.NotFound("cover_not_found", "unbekannte Cover-Größe %q", size)
+	}
+	current, err := s.repo.CoverHash(ctx, bookID)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		return nil, blob.Info{}, err
+	}
+	if current == "" || current != hash {
+		return nil, blob.Info{}, apperr.NotFound("cover_not_found", "kein Cover %s für Buch mit ID %d", hash, bookID)
+	}
+	r, info, err := s.blobs.Open(ctx, covers.Key(bookID, hash, size))
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, blob.Info{}, apperr.NotFound("cover_not_found", "kein Cover %s für Buch mit ID %d", hash, bookID)
+	}
+	return r, info, err
+}
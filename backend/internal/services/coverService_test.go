@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/blob"
+	"bookbazaar-backend/internal/covers"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository/memory"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func coverPNG(t *testing.T, c color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 450))
+	for y := 0; y < 450; y++ {
+		for x := 0; x < 300; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestSetCover(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (CoverService, *models.Book) {
+		store := memory.NewStore()
+		blobs, err := blob.NewLocal(t.TempDir())
+		require.NoError(t, err)
+		book, err := NewBookService(store.Books(), store.Users(), nil).Create(ctx, &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien"})
+		require.NoError(t, err)
+		return NewCoverService(store.Books(), blobs, 1<<20), book
+	}
+
+	t.Run("Upload setzt URL und Version, ersetzt altes Cover", func(t *testing.T) {
+		service, book := setup(t)
+
+		first, err := service.SetCover(ctx, book.ID, bytes.NewReader(coverPNG(t, color.White)))
+		require.NoError(t, err)
+		assert.Equal(t, 2, first.Version)
+		assert.True(t, strings.HasSuffix(first.CoverURL, "/medium.jpg"), first.CoverURL)
+
+		r, info, err := service.OpenCover(ctx, book.ID, first.CoverHash, "small")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(r)
+		r.Close()
+		assert.Equal(t, info.Size, int64(len(data)))
+
+		second, err := service.SetCover(ctx, book.ID, bytes.NewReader(coverPNG(t, color.Black)))
+		require.NoError(t, err)
+		assert.NotEqual(t, first.CoverURL, second.CoverURL)
+
+		// das alte Cover ist weg
+		_, _, err = service.OpenCover(ctx, book.ID, first.CoverHash, "small")
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	})
+
+	t.Run("zu groß", func(t *testing.T) {
+		service, book := setup(t)
+
+		_, err := service.SetCover(ctx, book.ID, bytes.NewReader(make([]byte, 1<<20+1)))
+
+		assert.True(t, errors.Is(err, apperr.ErrTooLarge))
+	})
+
+	t.Run("kein Bild", func(t *testing.T) {
+		service, book := setup(t)
+
+		_, err := service.SetCover(ctx, book.ID, strings.NewReader("%PDF-1.7 ..."))
+
+		assert.True(t, errors.Is(err, apperr.ErrUnsupportedMedia))
+	})
+
+	t.Run("ersetztes Cover ist weg, auch wenn die Datei noch liegt", func(t *testing.T) {
+		service, book := setup(t)
+		first, err := service.SetCover(ctx, book.ID, bytes.NewReader(coverPNG(t, color.White)))
+		require.NoError(t, err)
+		blobs := service.(*DefaultCoverService).blobs
+		data := coverPNG(t, color.Black)
+
+		_, err = service.SetCover(ctx, book.ID, bytes.NewReader(data))
+		require.NoError(t, err)
+		require.NoError(t, blobs.Put(ctx, covers.Key(book.ID, first.CoverHash, "small"), bytes.NewReader(data)))
+
+		_, _, err = service.OpenCover(ctx, book.ID, first.CoverHash, "small")
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	})
+
+	t.Run("unbekanntes Buch und unbekannte Größe", func(t *testing.T) {
+		service, book := setup(t)
+
+		_, err := service.SetCover(ctx, 999, bytes.NewReader(coverPNG(t, color.White)))
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+
+		_, _, err = service.OpenCover(ctx, book.ID, "0123456789abcdef", "huge")
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	})
+}
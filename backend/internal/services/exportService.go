@@ -0,0 +1,45 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/catalogio"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"io"
+	"log/slog"
+)
+
+type ExportService interface {
+	// Export schreibt alle angebotenen Bücher im Format nach w und liefert
+	// die Anzahl. Nach einem Fehler kann w schon einen Teil enthalten.
+	Export(ctx context.Context, w io.Writer, format string) (int, error)
+}
+
+type DefaultExportService struct {
+	repo repository.CatalogStore
+}
+
+func NewExportService(r repository.CatalogStore) ExportService {
+	return &DefaultExportService{repo: r}
+}
+
+func (s *DefaultExportService) Export(ctx context.Context, w io.Writer, format string) (int, error) {
+	out, err := catalogio.NewWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = s.repo.ExportBooks(ctx, func(book *models.Book) error {
+		count++
+		return out.Write(book)
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "export abgebrochen", slog.String("format", format), slog.Int("books", count), slog.Any("error", err))
+		return count, err
+	}
+	if err := out.Close(); err != nil {
+		return count, err
+	}
+	slog.InfoContext(ctx, "export abgeschlossen", slog.String("format", format), slog.Int("books", count))
+	return count, nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/catalogio"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository/memory"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ohne archivierte Bücher, nach ID sortiert", func(t *testing.T) {
+		store := memory.NewStore()
+		books := NewBookService(store.Books(), store.Users(), nil)
+		for _, name := range []string{"Momo", "Der Hobbit", "Jim Knopf"} {
+			_, err := books.Create(ctx, &models.Book{Name: name, Author: "Michael Ende", Quantity: 2, BorrowPrice: 1})
+			require.NoError(t, err)
+		}
+		require.NoError(t, store.Books().Archive(ctx, 2))
+		var buf bytes.Buffer
+
+		count, err := NewExportService(store.Books()).Export(ctx, &buf, catalogio.FormatCSV)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[0], "isbn,name,author,price,borrowprice,quantity"))
+		assert.Contains(t, lines[1], "Momo")
+		assert.Contains(t, lines[2], "Jim Knopf")
+	})
+}
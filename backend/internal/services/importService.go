@@ -0,0 +1,214 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/catalogio"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// Status einer Importzeile. Im Probelauf steht da, was passieren würde.
+const (
+	ImportCreate  = "create"
+	ImportUpdate  = "update"
+	ImportError   = "error"
+	ImportSkipped = "skipped" // nicht geschrieben, weil ein früherer Block fehlschlug
+)
+
+type ImportOptions struct {
+	DryRun bool
+	// ChunkSize ist die Anzahl Zeilen je Transaktion; 0 schreibt alles in einer.
+	ChunkSize int
+	// UserID wird im Bestandsjournal eingetragen; 0 = ohne User (CLI).
+	UserID int
+}
+
+type ImportRow struct {
+	Line    int                 `json:"line"`
+	Status  string              `json:"status"`
+	BookID  int                 `json:"bookId,omitempty"` // bestehendes bzw. angelegtes Buch
+	Name    string              `json:"name,omitempty"`
+	Code    string              `json:"code,omitempty"`
+	Message string              `json:"message,omitempty"`
+	Fields  []apperr.FieldError `json:"errors,omitempty"`
+}
+
+// ImportReport zählt die Zeilen nach Status. Applied ist false im
+// Probelauf und wenn fehlerhafte Zeilen den Import verhindert haben.
+type ImportReport struct {
+	DryRun  bool        `json:"dryRun"`
+	Applied bool        `json:"applied"`
+	Total   int         `json:"total"`
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Failed  int         `json:"failed"`
+	Skipped int         `json:"skipped"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+type ImportService interface {
+	Import(ctx context.Context, rows []catalogio.Row, opts ImportOptions) (*ImportReport, error)
+}
+
+type DefaultImportService struct {
+	repo repository.CatalogStore
+}
+
+func NewImportService(r repository.CatalogStore) ImportService {
+	return &DefaultImportService{repo: r}
+}
+
+// Import prüft jede Zeile mit denselben Regeln wie das Anlegen eines Buchs.
+// Zeilen zu einem bestehenden Buch (gleiche ISBN, sonst gleicher Titel und
+// Autor) werden zum Update. Geschrieben wird nur, wenn keine Zeile fehlerhaft
+// ist – ein Import soll nicht zur Hälfte im Katalog landen, nur weil eine
+// Zeile einen Tippfehler hat.
+func (s *DefaultImportService) Import(ctx context.Context, rows []catalogio.Row, opts ImportOptions) (*ImportReport, error) {
+	if opts.ChunkSize < 0 {
+		return nil, invalidField("invalid_query", "chunkSize", "chunkSize darf nicht negativ sein")
+	}
+	report := &ImportReport{DryRun: opts.DryRun, Total: len(rows), Rows: make([]ImportRow, len(rows))}
+	books := make([]*models.Book, len(rows)) // nil bei fehlerhaften Zeilen
+	seen := map[string]int{}                 // Schlüssel -> Zeile, für Dubletten in der Datei
+
+	for i, row := range rows {
+		result := &report.Rows[i]
+		result.Line, result.Name = row.Line, row.Book.Name
+		if row.Err != nil {
+			setImportError(result, row.Err)
+			continue
+		}
+
+		book := row.Book
+		book.ID = 0
+		status, err := s.checkRow(ctx, &book)
+		if err != nil {
+			var ae *apperr.Error
+			if !errors.As(err, &ae) {
+				return nil, err
+			}
+			setImportError(result, err)
+			continue
+		}
+
+		key := "isbn:" + book.ISBN13
+		if book.ISBN13 == "" {
+			key = "title:" + strings.ToLower(book.Name) + "|" + strings.ToLower(book.Author)
+		}
+		if line, dup := seen[key]; dup {
+			setImportError(result, apperr.Conflict("duplicate_in_file", "Dasselbe Buch steht schon in Zeile %d", line))
+			continue
+		}
+		seen[key] = row.Line
+
+		result.Status, result.BookID = status, book.ID
+		books[i] = &book
+	}
+	report.count()
+
+	if opts.DryRun || report.Failed > 0 {
+		return report, nil
+	}
+	s.apply(ctx, report, books, opts)
+	return report, nil
+}
+
+// checkRow bereitet ein Buch wie Create vor und sucht das bestehende Buch.
+// Bei einem Update bleiben ISBN und Kategorien des Buchs erhalten, wenn die
+// Zeile keine angibt.
+func (s *DefaultImportService) checkRow(ctx context.Context, book *models.Book) (string, error) {
+	if err := prepareBook(ctx, s.repo, book); err != nil {
+		return "", err
+	}
+	existing, err := s.repo.FindDuplicate(ctx, book)
+	if err != nil {
+		return "", err
+	}
+	if existing == nil {
+		return ImportCreate, nil
+	}
+	if existing.ArchivedAt != nil {
+		return "", apperr.Conflict("book_archived", "Buch existiert archiviert als ID %d; erst wiederherstellen", existing.ID)
+	}
+	book.ID = existing.ID
+	if book.ISBN13 == "" {
+		book.ISBN13, book.ISBN10 = existing.ISBN13, existing.ISBN10
+	}
+	if len(book.Categories) == 0 {
+		book.Categories = existing.Categories
+	}
+	return ImportUpdate, nil
+}
+
+func setImportError(result *ImportRow, err error) {
+	result.Status, result.BookID = ImportError, 0
+	var ae *apperr.Error
+	if errors.As(err, &ae) {
+		result.Code, result.Message, result.Fields = ae.Code, ae.Message, ae.Fields
+		return
+	}
+	result.Code, result.Message = "import_failed", "Zeile konnte nicht geschrieben werden"
+}
+
+func (r *ImportReport) count() {
+	r.Created, r.Updated, r.Failed, r.Skipped = 0, 0, 0, 0
+	for _, row := range r.Rows {
+		switch row.Status {
+		case ImportCreate:
+			r.Created++
+		case ImportUpdate:
+			r.Updated++
+		case ImportError:
+			r.Failed++
+		case ImportSkipped:
+			r.Skipped++
+		}
+	}
+}
+
+// apply schreibt die Zeilen in Blöcken von opts.ChunkSize, jeder Block in einer
+// eigenen Transaktion. Schlägt ein Block fehl, bekommen seine Zeilen den
+// Fehler und alle späteren werden übersprungen; frühere bleiben geschrieben.
+func (s *DefaultImportService) apply(ctx context.Context, report *ImportReport, books []*models.Book, opts ImportOptions) {
+	chunkSize := opts.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = len(books)
+	}
+	var failed error
+	for start := 0; start < len(books); start += chunkSize {
+		end := min(start+chunkSize, len(books))
+		if failed != nil {
+			for i := start; i < end; i++ {
+				report.Rows[i].Status, report.Rows[i].BookID = ImportSkipped, 0
+			}
+			continue
+		}
+
+		chunk := make([]*models.Book, 0, end-start)
+		for _, b := range books[start:end] {
+			if b != nil {
+				chunk = append(chunk, b)
+			}
+		}
+		if err := s.repo.UpsertBooks(ctx, opts.UserID, chunk); err != nil {
+			slog.WarnContext(ctx, "importblock fehlgeschlagen", slog.Int("first_line", report.Rows[start].Line), slog.Any("error", err))
+			failed = err
+			for i := start; i < end; i++ {
+				setImportError(&report.Rows[i], err)
+			}
+			continue
+		}
+		report.Applied = true
+		for i := start; i < end; i++ {
+			report.Rows[i].BookID = books[i].ID
+		}
+	}
+	report.count()
+	slog.InfoContext(ctx, "import abgeschlossen",
+		slog.Int("created", report.Created), slog.Int("updated", report.Updated),
+		slog.Int("failed", report.Failed), slog.Int("skipped", report.Skipped))
+}
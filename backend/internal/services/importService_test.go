@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/catalogio"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"bookbazaar-backend/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingUpserts lässt den n-ten Aufruf von UpsertBooks scheitern.
+type failingUpserts struct {
+	repository.CatalogStore
+	failAt, calls int
+}
+
+func (f *failingUpserts) UpsertBooks(ctx context.Context, userID int, books []*models.Book) error {
+	f.calls++
+	if f.calls == f.failAt {
+		return errors.New("verbindung verloren")
+	}
+	return f.CatalogStore.UpsertBooks(ctx, userID, books)
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	var store *memory.Store
+	setup := func(t *testing.T) (BookService, ImportService) {
+		store = memory.NewStore()
+		books := NewBookService(store.Books(), store.Users(), nil)
+		_, err := books.Create(ctx, &models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", ISBN13: "9783608938289", Quantity: 1})
+		require.NoError(t, err)
+		return books, NewImportService(store.Books())
+	}
+	count := func(t *testing.T) int {
+		page, err := store.Books().ListBooks(ctx, repository.BookQuery{Limit: MaxPageSize})
+		require.NoError(t, err)
+		return page.Total
+	}
+	row := func(line int, book models.Book) catalogio.Row {
+		return catalogio.Row{Line: line, Book: book}
+	}
+
+	t.Run("Probelauf meldet Fehler und Dubletten", func(t *testing.T) {
+		_, importer := setup(t)
+		rows := []catalogio.Row{
+			row(2, models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", Quantity: 5}),
+			row(3, models.Book{Name: "Momo", Author: "Michael Ende", Price: -1}),
+			row(4, models.Book{Name: "Die unendliche Geschichte", Author: "Michael Ende"}),
+			row(5, models.Book{Name: "die unendliche geschichte", Author: "michael ende"}),
+			{Line: 6, Err: apperr.Validation("invalid_row", "Zeile nicht lesbar")},
+		}
+
+		report, err := importer.Import(ctx, rows, ImportOptions{DryRun: true})
+
+		require.NoError(t, err)
+		assert.False(t, report.Applied)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 1, report.Updated)
+		assert.Equal(t, 3, report.Failed)
+		assert.Equal(t, ImportUpdate, report.Rows[0].Status)
+		assert.Equal(t, "invalid_book", report.Rows[1].Code)
+		assert.Equal(t, "duplicate_in_file", report.Rows[3].Code)
+		assert.Equal(t, "invalid_row", report.Rows[4].Code)
+		assert.Equal(t, 1, count(t))
+	})
+
+	t.Run("fehlerhafte Zeile verhindert den Import", func(t *testing.T) {
+		_, importer := setup(t)
+		rows := []catalogio.Row{
+			row(2, models.Book{Name: "Momo", Author: "Michael Ende"}),
+			row(3, models.Book{Name: "X", Author: "Michael Ende"}),
+		}
+
+		report, err := importer.Import(ctx, rows, ImportOptions{})
+
+		require.NoError(t, err)
+		assert.False(t, report.Applied)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, 1, count(t))
+	})
+
+	t.Run("legt an und aktualisiert", func(t *testing.T) {
+		_, importer := setup(t)
+		rows := []catalogio.Row{
+			row(2, models.Book{Name: "Der Hobbit", Author: "J.R.R. Tolkien", Price: 14.9, Quantity: 5}),
+			row(3, models.Book{Name: "Momo", Author: "Michael Ende", Quantity: 2}),
+		}
+
+		report, err := importer.Import(ctx, rows, ImportOptions{ChunkSize: 1})
+
+		require.NoError(t, err)
+		assert.True(t, report.Applied)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 1, report.Updated)
+		hobbit, err := store.Books().GetBookByID(ctx, report.Rows[0].BookID)
+		require.NoError(t, err)
+		assert.Equal(t, 5, hobbit.Quantity)
+		assert.Equal(t, 14.9, hobbit.Price)
+		assert.Equal(t, "9783608938289", hobbit.ISBN13, "ISBN bleibt, wenn die Zeile keine hat")
+		assert.Equal(t, 2, hobbit.Version)
+		assert.NotZero(t, report.Rows[1].BookID)
+	})
+
+	t.Run("fehlgeschlagener Block überspringt den Rest", func(t *testing.T) {
+		setup(t)
+		importer := NewImportService(&failingUpserts{CatalogStore: store.Books(), failAt: 2})
+		rows := []catalogio.Row{
+			row(2, models.Book{Name: "Momo", Author: "Michael Ende"}),
+			row(3, models.Book{Name: "Die unendliche Geschichte", Author: "Michael Ende"}),
+			row(4, models.Book{Name: "Jim Knopf", Author: "Michael Ende"}),
+		}
+
+		report, err := importer.Import(ctx, rows, ImportOptions{ChunkSize: 1})
+
+		require.NoError(t, err)
+		assert.True(t, report.Applied)
+		assert.Equal(t, []string{ImportCreate, ImportError, ImportSkipped}, []string{report.Rows[0].Status, report.Rows[1].Status, report.Rows[2].Status})
+		assert.Equal(t, "import_failed", report.Rows[1].Code)
+		assert.Equal(t, 2, count(t))
+	})
+
+	t.Run("negative Blockgröße", func(t *testing.T) {
+		_, importer := setup(t)
+
+		_, err := importer.Import(ctx, nil, ImportOptions{ChunkSize: -1})
+
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/repository"
+	"context"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Zeitraum für die Verkaufsgeschwindigkeit im Bericht über knappe Bücher.
+const (
+	DefaultVelocityDays = 30
+	MaxVelocityDays     = 365
+)
+
+// MaxAdjustmentReason begrenzt die Begründung einer Bestandskorrektur.
+const MaxAdjustmentReason = 500
+
+type StockService interface {
+	ListMovements(ctx context.Context, q repository.MovementQuery) (repository.MovementPage, error)
+	Reconcile(ctx context.Context) (*StockReport, error)
+	GetStockLevel(ctx context.Context, bookID int) (repository.StockLevel, error)
+	AdjustStock(ctx context.Context, adj repository.StockAdjustment) (repository.StockLevel, error)
+	SetReorderThreshold(ctx context.Context, bookID int, threshold *int) (repository.StockLevel, error)
+	LowStock(ctx context.Context, days int) (*LowStockReport, error)
+}
+
+// StockReport ist das Ergebnis des Bestandsabgleichs; OK, wenn für jedes
+// Buch quantity der Summe seiner Journaleinträge entspricht.
+type StockReport struct {
+	OK bool `json:"ok"`
+	repository.StockReconciliation
+}
+
+// LowStockReport listet Bücher am oder unter ihrem Meldebestand mit den
+// Verkäufen der letzten Days Tage.
+type LowStockReport struct {
+	Days  int                       `json:"days"`
+	Since time.Time                 `json:"since"`
+	Items []repository.LowStockItem `json:"items"`
+}
+
+type DefaultStockService struct {
+	repo repository.StockStore
+}
+
+func NewStockService(r repository.StockStore) StockService {
+	return &DefaultStockService{repo: r}
+}
+
+func (s *DefaultStockService) ListMovements(ctx context.Context, q repository.MovementQuery) (repository.MovementPage, error) {
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+
+	var invalid queryErrors
+	invalid.page(q.Limit, q.Offset)
+	if err := invalid.err(); err != nil {
+		return repository.MovementPage{}, err
+	}
+	return s.repo.ListMovements(ctx, q)
+}
+
+func (s *DefaultStockService) Reconcile(ctx context.Context) (*StockReport, error) {
+	result, err := s.repo.ReconcileStock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Mismatches) > 0 {
+		slog.WarnContext(ctx, "bestand weicht vom journal ab", slog.Int("books", len(result.Mismatches)))
+	}
+	return &StockReport{OK: len(result.Mismatches) == 0, StockReconciliation: result}, nil
+}
+
+func (s *DefaultStockService) GetStockLevel(ctx context.Context, bookID int) (repository.StockLevel, error) {
+	return s.repo.GetStockLevel(ctx, bookID)
+}
+
+// AdjustStock prüft eine Korrektur: genau eines von Quantity und Delta und
+// immer eine Begründung, die im Journal als note steht.
+func (s *DefaultStockService) AdjustStock(ctx context.Context, adj repository.StockAdjustment) (repository.StockLevel, error) {
+	adj.Note = strings.TrimSpace(adj.Note)
+	switch {
+	case (adj.Quantity == nil) == (adj.Delta == nil):
+		return repository.StockLevel{}, invalidField("invalid_adjustment", "quantity", "Genau eines von quantity und delta angeben")
+	case adj.Quantity != nil && *adj.Quantity < 0:
+		return repository.StockLevel{}, invalidField("invalid_adjustment", "quantity", "quantity darf nicht negativ sein")
+	case adj.Delta != nil && *adj.Delta == 0:
+		return repository.StockLevel{}, invalidField("invalid_adjustment", "delta", "delta darf nicht 0 sein")
+	case adj.Note == "":
+		return repository.StockLevel{}, invalidField("invalid_adjustment", "reason", "Begründung fehlt")
+	case utf8.RuneCountInString(adj.Note) > MaxAdjustmentReason:
+		return repository.StockLevel{}, invalidField("invalid_adjustment", "reason", "Begründung darf höchstens %d Zeichen haben", MaxAdjustmentReason)
+	}
+	return s.repo.AdjustStock(ctx, adj)
+}
+
+func (s *DefaultStockService) SetReorderThreshold(ctx context.Context, bookID int, threshold *int) (repository.StockLevel, error) {
+	if threshold != nil && *threshold < 0 {
+		return repository.StockLevel{}, invalidField("invalid_threshold", "threshold", "Meldebestand darf nicht negativ sein")
+	}
+	return s.repo.SetReorderThreshold(ctx, bookID, threshold)
+}
+
+// LowStock ergänzt die Verkäufe der letzten days Tage um Exemplare pro Tag
+// und die Tage, die der Bestand bei diesem Tempo noch reicht.
+func (s *DefaultStockService) LowStock(ctx context.Context, days int) (*LowStockReport, error) {
+	if days == 0 {
+		days = DefaultVelocityDays
+	}
+	if days < 1 || days > MaxVelocityDays {
+		return nil, invalidField("invalid_query", "days", "days muss zwischen 1 und %d liegen", MaxVelocityDays)
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	items, err := s.repo.LowStock(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		item := &items[i]
+		item.PerDay = round2(float64(item.Sold) / float64(days))
+		if item.Sold > 0 {
+			left := round2(float64(item.Quantity) * float64(days) / float64(item.Sold))
+			item.DaysLeft = &left
+		}
+	}
+	return &LowStockReport{Days: days, Since: since, Items: items}, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
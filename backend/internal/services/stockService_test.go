@@ -0,0 +1,249 @@
+package services
+
+import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/catalogio"
+	"bookbazaar-backend/internal/models"
+	"bookbazaar-backend/internal/repository"
+	"bookbazaar-backend/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStock(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (BookService, StockService, *memory.Store, int, int) {
+		store := memory.NewStore()
+		user := &models.User{Name: "Malek", Lastname: "Test", Username: "malek1", Email: "m@example.com", Password: "geheim123"}
+		require.NoError(t, store.Users().AddUser(ctx, user))
+		store.SetBalance(user.ID, 50)
+
+		books := NewBookService(store.Books(), store.Users(), nil)
+		book, err := books.Create(ctx, &models.Book{
+			Name: "Der Hobbit", Author: "J.R.R. Tolkien", Genre: "Fantasy",
+			Description: "Test Beschreibung", Price: 10, BorrowPrice: 2, Quantity: 3,
+		})
+		require.NoError(t, err)
+		return books, NewStockService(store.Stock()), store, user.ID, book.ID
+	}
+	movements := func(t *testing.T, stock StockService, bookID int) []models.StockMovement {
+		page, err := stock.ListMovements(ctx, repository.MovementQuery{BookID: bookID})
+		require.NoError(t, err)
+		return page.Items
+	}
+
+	t.Run("Anlage schreibt Anfangsbestand", func(t *testing.T) {
+		_, stock, _, _, bookID := setup(t)
+
+		items := movements(t, stock, bookID)
+
+		require.Len(t, items, 1)
+		assert.Equal(t, models.MovementAdjustment, items[0].Reason)
+		assert.Equal(t, 3, items[0].Delta)
+		assert.Equal(t, 3, items[0].QuantityAfter)
+		assert.Nil(t, items[0].UserID)
+	})
+
+	t.Run("Kauf, Ausleihe und Rückgabe", func(t *testing.T) {
+		books, stock, _, userID, bookID := setup(t)
+
+		require.NoError(t, books.BuyBooks(ctx, userID, []Purchase{{BookId: bookID, Quantity: 2}}))
+		require.NoError(t, books.BorrowBook(ctx, userID, bookID, 7))
+		require.NoError(t, books.GiveBorrowedBookBack(ctx, userID, bookID))
+
+		items := movements(t, stock, bookID)
+		require.Len(t, items, 4)
+		ret, loan, sale := items[0], items[1], items[2]
+
+		assert.Equal(t, models.MovementSale, sale.Reason)
+		assert.Equal(t, -2, sale.Delta)
+		assert.Equal(t, 1, sale.QuantityAfter)
+		assert.Equal(t, &userID, sale.UserID)
+		assert.NotNil(t, sale.PurchaseID)
+
+		assert.Equal(t, models.MovementLoan, loan.Reason)
+		assert.Equal(t, -1, loan.Delta)
+		assert.Equal(t, 0, loan.QuantityAfter)
+		require.NotNil(t, loan.LoanID)
+
+		assert.Equal(t, models.MovementReturn, ret.Reason)
+		assert.Equal(t, 1, ret.Delta)
+		assert.Equal(t, 1, ret.QuantityAfter)
+		assert.Equal(t, loan.LoanID, ret.LoanID)
+	})
+
+	t.Run("Import schreibt Differenz mit User", func(t *testing.T) {
+		_, stock, store, userID, bookID := setup(t)
+		row := catalogio.Row{Line: 2, Book: models.Book{
+			Name: "Der Hobbit", Author: "J.R.R. Tolkien", Genre: "Fantasy",
+			Description: "Neu", Price: 12, Quantity: 5,
+		}}
+
+		_, err := NewImportService(store.Books()).Import(ctx, []catalogio.Row{row}, ImportOptions{UserID: userID})
+		require.NoError(t, err)
+
+		items := movements(t, stock, bookID)
+		require.Len(t, items, 2)
+		assert.Equal(t, models.MovementImport, items[0].Reason)
+		assert.Equal(t, 2, items[0].Delta)
+		assert.Equal(t, 5, items[0].QuantityAfter)
+		assert.Equal(t, &userID, items[0].UserID)
+	})
+
+	t.Run("Abgleich ohne Abweichung", func(t *testing.T) {
+		books, stock, _, userID, bookID := setup(t)
+		require.NoError(t, books.BuyBook(ctx, userID, bookID))
+
+		report, err := stock.Reconcile(ctx)
+
+		require.NoError(t, err)
+		assert.True(t, report.OK)
+		assert.Equal(t, 1, report.Checked)
+		assert.Empty(t, report.Mismatches)
+	})
+
+	t.Run("Abgleich findet Änderung am Journal vorbei", func(t *testing.T) {
+		_, stock, store, _, bookID := setup(t)
+		store.SetQuantity(bookID, 7)
+
+		report, err := stock.Reconcile(ctx)
+
+		require.NoError(t, err)
+		assert.False(t, report.OK)
+		require.Len(t, report.Mismatches, 1)
+		assert.Equal(t, repository.StockMismatch{BookID: bookID, Name: "Der Hobbit", Quantity: 7, LedgerSum: 3}, report.Mismatches[0])
+	})
+
+	t.Run("unbekanntes Buch", func(t *testing.T) {
+		_, stock, _, _, _ := setup(t)
+
+		_, err := stock.ListMovements(ctx, repository.MovementQuery{BookID: 999})
+
+		assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	})
+
+	t.Run("Korrektur absolut und per Delta", func(t *testing.T) {
+		_, stock, _, userID, bookID := setup(t)
+		ten, minusTwo := 10, -2
+
+		level, err := stock.AdjustStock(ctx, repository.StockAdjustment{BookID: bookID, UserID: userID, Quantity: &ten, Note: " Inventur "})
+		require.NoError(t, err)
+		assert.Equal(t, 10, level.Quantity)
+		require.NotNil(t, level.Movement)
+		assert.Equal(t, 7, level.Movement.Delta)
+		assert.Equal(t, "Inventur", level.Movement.Note)
+		assert.Equal(t, &userID, level.Movement.UserID)
+
+		level, err = stock.AdjustStock(ctx, repository.StockAdjustment{BookID: bookID, UserID: userID, Delta: &minusTwo, Note: "beschädigt"})
+		require.NoError(t, err)
+		assert.Equal(t, 8, level.Quantity)
+		assert.Equal(t, models.MovementAdjustment, level.Movement.Reason)
+
+		report, err := stock.Reconcile(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.OK)
+	})
+
+	t.Run("Korrektur ohne Änderung bucht nichts", func(t *testing.T) {
+		_, stock, _, userID, bookID := setup(t)
+		three := 3
+
+		level, err := stock.AdjustStock(ctx, repository.StockAdjustment{BookID: bookID, UserID: userID, Quantity: &three, Note: "Inventur"})
+
+		require.NoError(t, err)
+		assert.Nil(t, level.Movement)
+		assert.Len(t, movements(t, stock, bookID), 1)
+	})
+
+	t.Run("ungültige Korrekturen", func(t *testing.T) {
+		_, stock, _, userID, bookID := setup(t)
+		one, zero, minusOne := 1, 0, -1
+
+		cases := map[string]repository.StockAdjustment{
+			"beides":          {BookID: bookID, Quantity: &one, Delta: &one, Note: "x"},
+			"keines":          {BookID: bookID, Note: "x"},
+			"negativ":         {BookID: bookID, Quantity: &minusOne, Note: "x"},
+			"Delta 0":         {BookID: bookID, Delta: &zero, Note: "x"},
+			"ohne Begründung": {BookID: bookID, Delta: &one, Note: "  "},
+		}
+		for name, adj := range cases {
+			adj.UserID = userID
+			_, err := stock.AdjustStock(ctx, adj)
+			assert.True(t, errors.Is(err, apperr.ErrValidation), name)
+		}
+	})
+
+	t.Run("Bestand fällt nicht unter 0", func(t *testing.T) {
+		_, stock, _, userID, bookID := setup(t)
+		minusFour := -4
+
+		_, err := stock.AdjustStock(ctx, repository.StockAdjustment{BookID: bookID, UserID: userID, Delta: &minusFour, Note: "Schwund"})
+
+		assert.True(t, errors.Is(err, apperr.ErrOutOfStock))
+		level, err := stock.GetStockLevel(ctx, bookID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, level.Quantity)
+	})
+
+	t.Run("Meldebestand und knappe Bücher", func(t *testing.T) {
+		books, stock, _, userID, bookID := setup(t)
+		two := 2
+		require.NoError(t, books.BuyBooks(ctx, userID, []Purchase{{BookId: bookID, Quantity: 1}}))
+
+		level, err := stock.SetReorderThreshold(ctx, bookID, &two)
+		require.NoError(t, err)
+		assert.Equal(t, &two, level.ReorderThreshold)
+
+		report, err := stock.LowStock(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 10, report.Days)
+		require.Len(t, report.Items, 1)
+		item := report.Items[0]
+		assert.Equal(t, bookID, item.BookID)
+		assert.Equal(t, 2, item.Quantity)
+		assert.Equal(t, 1, item.Sold)
+		assert.Equal(t, 0.1, item.PerDay)
+		require.NotNil(t, item.DaysLeft)
+		assert.Equal(t, 20.0, *item.DaysLeft)
+
+		level, err = stock.SetReorderThreshold(ctx, bookID, nil)
+		require.NoError(t, err)
+		assert.Nil(t, level.ReorderThreshold)
+		report, err = stock.LowStock(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultVelocityDays, report.Days)
+		assert.Empty(t, report.Items)
+	})
+
+	t.Run("Wiederholungskauf zählt zum Datum des Verkaufs", func(t *testing.T) {
+		books, stock, store, userID, bookID := setup(t)
+		one, now := 1, time.Now()
+		_, err := stock.SetReorderThreshold(ctx, bookID, &one)
+		require.NoError(t, err)
+
+		store.SetClock(func() time.Time { return now.AddDate(0, 0, -20) })
+		require.NoError(t, books.BuyBooks(ctx, userID, []Purchase{{BookId: bookID, Quantity: 1}}))
+		store.SetClock(func() time.Time { return now })
+		require.NoError(t, books.BuyBooks(ctx, userID, []Purchase{{BookId: bookID, Quantity: 1}}))
+
+		report, err := stock.LowStock(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, report.Items, 1)
+		assert.Equal(t, 1, report.Items[0].Sold)
+	})
+
+	t.Run("ungültiger Meldebestand und Zeitraum", func(t *testing.T) {
+		_, stock, _, _, bookID := setup(t)
+		minusOne := -1
+
+		_, err := stock.SetReorderThreshold(ctx, bookID, &minusOne)
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+		_, err = stock.LowStock(ctx, MaxVelocityDays+1)
+		assert.True(t, errors.Is(err, apperr.ErrValidation))
+	})
+}
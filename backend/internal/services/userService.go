@@ -1,12 +1,14 @@
 package services
 
 import (
+	"bookbazaar-backend/internal/apperr"
+	"bookbazaar-backend/internal/metrics"
 	"bookbazaar-backend/internal/models"
 	"bookbazaar-backend/internal/repository"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
-	"log"
 	"net/mail"
 	"time"
 
@@ -14,14 +16,14 @@ import (
 )
 
 type UserService interface {
-	GetAllUsers() ([]models.User, error)
-	AddUser(user *models.User) (*models.User, error)
-	ValidateUser(username, password string) (*models.User, error)
-	GetUserByUserId(userId int) (*models.User, error)
-	StoreRefreshToken(userID int, token string) error
-	ValidateRefreshToken(token string) (int, error)
-	RevokeRefreshToken(token string) error
-	ValidateUserCredentials(username, password string) (*models.User, error)
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	AddUser(ctx context.Context, user *models.User) (*models.User, error)
+	ValidateUser(ctx context.Context, username, password string) (*models.User, error)
+	GetUserByUserId(ctx context.Context, userId int) (*models.User, error)
+	StoreRefreshToken(ctx context.Context, userID int, token string) error
+	ValidateRefreshToken(ctx context.Context, token string) (int, error)
+	RevokeRefreshToken(ctx context.Context, token string) error
+	ValidateUserCredentials(ctx context.Context, username, password string) (*models.User, error)
 }
 
 func hashToken(token string) string {
@@ -30,16 +32,17 @@ func hashToken(token string) string {
 }
 
 type DefaultUserService struct {
-	repo *repository.UserRepository
+	repo    repository.UserStorage
+	metrics *metrics.Metrics
 }
 
-func NewUserService(r *repository.UserRepository) UserService {
-	return &DefaultUserService{repo: r}
+// NewUserService baut den Service; m darf nil sein (keine Metriken).
+func NewUserService(r repository.UserStorage, m *metrics.Metrics) UserService {
+	return &DefaultUserService{repo: r, metrics: m}
 }
 
-func (s *DefaultUserService) GetAllUsers() ([]models.User, error) {
-	log.Println("Service.GetAllUsers wurde aufgerufen")
-	return s.repo.GetAllUsers()
+func (s *DefaultUserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	return s.repo.GetAllUsers(ctx)
 }
 
 func isValidEmail(email string) bool {
@@ -52,15 +55,13 @@ func validateUser(User *models.User) error {
 	return validate.Struct(User)
 }
 
-func (s *DefaultUserService) AddUser(user *models.User) (*models.User, error) {
-	log.Println("Service.AddUser wurde aufgerufen")
-
+func (s *DefaultUserService) AddUser(ctx context.Context, user *models.User) (*models.User, error) {
 	if err := validateUser(user); err != nil {
-		return nil, err
+		return nil, apperr.FromValidator("invalid_user", err)
 	}
 
 	if !isValidEmail(user.Email) {
-		return nil, errors.New("email ist nicht gültig")
+		return nil, invalidField("invalid_user", "email", "email ist nicht gültig")
 	}
 
 	if user.Role == "" {
@@ -68,70 +69,74 @@ func (s *DefaultUserService) AddUser(user *models.User) (*models.User, error) {
 	}
 
 	if len(user.Name) < 3 || len(user.Lastname) < 3 {
-		return nil, errors.New("vor- Nachname müssen mindestens 3 Zeichen haben")
+		return nil, invalidField("invalid_user", "name", "vor- Nachname müssen mindestens 3 Zeichen haben")
 	}
 
 	if len(user.Password) < 6 {
-		return nil, errors.New("passwort muss mindestens 6 Zeichen haben")
+		return nil, invalidField("invalid_user", "password", "passwort muss mindestens 6 Zeichen haben")
 	}
 
-	existingUser, err := s.repo.GetUserByUserName(user.Username)
+	existingUser, err := s.repo.GetUserByUserName(ctx, user.Username)
 
 	if err == nil && existingUser != nil {
-		return nil, errors.New("username existiert bereits")
+		return nil, apperr.Conflict("username_taken", "username existiert bereits")
 	}
 
-	if err := s.repo.AddUser(user); err != nil {
+	if err := s.repo.AddUser(ctx, user); err != nil {
 		return nil, err
 	}
 
-	log.Println(user)
 	return user, nil
 }
 
-func (s *DefaultUserService) ValidateUser(username, password string) (*models.User, error) {
-	user, err := s.repo.GetUserByUserName(username)
-	if err != nil || user.Password != password {
-		return nil, errors.New("ungültiger Benutzer oder Passwort")
+func (s *DefaultUserService) ValidateUser(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := s.repo.GetUserByUserName(ctx, username)
+	if err != nil || user == nil || user.Password != password {
+		return nil, apperr.Unauthorized("invalid_credentials", "ungültiger Benutzer oder Passwort")
 	}
 	return user, nil
 }
 
-func (s *DefaultUserService) ValidateUserCredentials(username, password string) (*models.User, error) {
-	return s.repo.ValidateUserCredentials(username, password)
+func (s *DefaultUserService) ValidateUserCredentials(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := s.repo.ValidateUserCredentials(ctx, username, password)
+	if errors.Is(err, apperr.ErrUnauthorized) {
+		s.metrics.LoginFailed()
+	}
+	return user, err
 }
 
-func (s *DefaultUserService) GetUserByUserId(userId int) (*models.User, error) {
-	user, err := s.repo.GetUserByUserId(userId)
+func (s *DefaultUserService) GetUserByUserId(ctx context.Context, userId int) (*models.User, error) {
+	user, err := s.repo.GetUserByUserId(ctx, userId)
 
 	if err != nil {
-		return nil, errors.New("ungüliter Benutzer")
+		return nil, err
 	}
 
 	return user, nil
 }
 
 // StoreRefreshToken speichert einen neu erzeugten refresh token (hashed) in der DB.
-func (s *DefaultUserService) StoreRefreshToken(userID int, token string) error {
+func (s *DefaultUserService) StoreRefreshToken(ctx context.Context, userID int, token string) error {
 	hash := hashToken(token)
 	expires := time.Now().Add(30 * 24 * time.Hour) // 30 Tage
-	return s.repo.CreateRefreshToken(userID, hash, expires)
+	return s.repo.CreateRefreshToken(ctx, userID, hash, expires)
 }
 
 // ValidateRefreshToken validiert den refresh token und gibt die zugehörige user_id zurück.
-func (s *DefaultUserService) ValidateRefreshToken(token string) (int, error) {
+func (s *DefaultUserService) ValidateRefreshToken(ctx context.Context, token string) (int, error) {
 	hash := hashToken(token)
-	userID, err := s.repo.GetUserIDByRefreshToken(hash)
+	userID, err := s.repo.GetUserIDByRefreshToken(ctx, hash)
 	if err != nil {
 		return 0, err
 	}
 	// optional: last_used_at updaten
-	_ = s.repo.UpdateRefreshTokenLastUsed(hash)
+	_ = s.repo.UpdateRefreshTokenLastUsed(ctx, hash)
+	s.metrics.RefreshTokenUsed()
 	return userID, nil
 }
 
 // RevokeRefreshToken markiert einen refresh token als revoked.
-func (s *DefaultUserService) RevokeRefreshToken(token string) error {
+func (s *DefaultUserService) RevokeRefreshToken(ctx context.Context, token string) error {
 	hash := hashToken(token)
-	return s.repo.RevokeRefreshToken(hash)
+	return s.repo.RevokeRefreshToken(ctx, hash)
 }
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"bookbazaar-backend/internal/metrics"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// CartExpirer wird vom BookRepository implementiert.
+type CartExpirer interface {
+	ExpireCartReservations(ctx context.Context) (int64, error)
+}
+
+// CartSweeper markiert abgelaufene Warenkorb-Reservierungen regelmäßig als entfernt.
+type CartSweeper struct {
+	repo     CartExpirer
+	interval time.Duration
+	metrics  *metrics.Metrics
+}
+
+func NewCartSweeper(repo CartExpirer, interval time.Duration, m *metrics.Metrics) *CartSweeper {
+	return &CartSweeper{repo: repo, interval: interval, metrics: m}
+}
+
+func (w *CartSweeper) Name() string {
+	return "cart-sweeper"
+}
+
+func (w *CartSweeper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			expired, err := w.repo.ExpireCartReservations(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "abgelaufene reservierungen konnten nicht freigegeben werden", slog.String("worker", w.Name()), slog.Any("error", err))
+				continue
+			}
+			w.metrics.CartExpired(expired)
+			if expired > 0 {
+				slog.InfoContext(ctx, "abgelaufene reservierungen freigegeben", slog.String("worker", w.Name()), slog.Int64("count", expired))
+			}
+		}
+	}
+}
@@ -15,10 +15,17 @@ import {
 import { Input } from "./ui/input";
 import type { Book } from "@/interface/Book";
 import useFavorites from "@/hooks/useFavorites";
+import { Button } from "./ui/button";
 
 function BookList() {
   const { favorites } = useFavorites();
-  const { data: books } = useBooks();
+  const {
+    data: books,
+    total,
+    hasNextPage,
+    fetchNextPage,
+    isFetchingNextPage,
+  } = useBooks();
   const [genreFilter, setGenreFilter] = useState("all");
   const [search, setSearch] = useState("");
   const [selectedCard, setSelectedCard] = useState<Book>();
@@ -81,6 +88,21 @@ function BookList() {
               isBorrowpage={false}
             />
           ))}
+          <div className="ml-5 flex items-center gap-3 text-sm text-muted-foreground">
+            <span>
+              {books?.length ?? 0} von {total} Büchern geladen
+            </span>
+            {hasNextPage && (
+              <Button
+                variant="outline"
+                size="sm"
+                onClick={() => fetchNextPage()}
+                disabled={isFetchingNextPage}
+              >
+                {isFetchingNextPage ? "Lade..." : "Mehr laden"}
+              </Button>
+            )}
+          </div>
         </div>
         <aside className="flex-1 border border-border bg-card text-foreground p-5 rounded-2xl shadow-lg transition-all duration-500 detail-panel">
           <div
@@ -1,11 +1,33 @@
 import type { Book } from "../interface/Book";
-import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import {
+  useInfiniteQuery,
+  useMutation,
+  useQueryClient,
+} from "@tanstack/react-query";
 import { useAuthStore } from "../States/userAuthState";
 import { fetchWithAuth } from "@/lib/fetchWithAuth";
 import { apiFetch } from "@/lib/api";
 
-async function getBooks(token: string): Promise<Book[]> {
-  const res = await apiFetch("/books", {
+// Bücher je Seite; weitere Seiten lädt die Liste über fetchNextPage nach.
+export const BOOKS_PAGE_SIZE = 50;
+
+// GET /books liefert eine Seite { items, total, limit, offset, nextCursor }.
+interface BookPage {
+  items: Book[];
+  total: number;
+  limit: number;
+  offset: number;
+  nextCursor?: string;
+}
+
+async function getBooks(
+  token: string,
+  cursor: string | undefined,
+  limit: number
+): Promise<BookPage> {
+  const params = new URLSearchParams({ limit: String(limit) });
+  if (cursor) params.set("cursor", cursor);
+  const res = await apiFetch(`/books?${params}`, {
     method: "GET",
     headers: {
       "Content-Type": "application/json",
@@ -28,7 +50,7 @@ async function addBook(book: Omit<Book, "id">, token: string): Promise<Book> {
 
   const data = await res.json();
 
-  if (!res.ok) throw new Error(data.message || "Fehler beim Hinzufügen");
+  if (!res.ok) throw new Error(data.detail || "Fehler beim Hinzufügen");
   return data;
 }
 
@@ -67,7 +89,10 @@ async function buyBooks(purchases: { bookId: number; quantity: number }[]) {
   return res.json();
 }
 
-export default function useBooks() {
+// useBooks lädt den Katalog seitenweise über nextCursor. data enthält die
+// bisher geladenen Bücher, total die Anzahl im Katalog; solange hasNextPage
+// gilt, fehlen noch welche.
+export default function useBooks(limit: number = BOOKS_PAGE_SIZE) {
   const qc = useQueryClient();
   const { token } = useAuthStore();
 
@@ -75,11 +100,15 @@ export default function useBooks() {
     throw new Error("User ist nicht eingeloggt");
   }
 
-  const query = useQuery<Book[], Error>({
-    queryFn: () => getBooks(token!),
-    queryKey: ["books"],
+  const query = useInfiniteQuery({
+    queryFn: ({ pageParam }) => getBooks(token!, pageParam, limit),
+    queryKey: ["books", limit],
+    initialPageParam: undefined as string | undefined,
+    getNextPageParam: (last: BookPage) => last.nextCursor || undefined,
     enabled: !!token,
   });
+  const books = query.data?.pages.flatMap((page) => page.items);
+  const total = query.data?.pages[0]?.total ?? 0;
 
   const addNewBook = useMutation({
     mutationFn: (book: Omit<Book, "id">) => addBook(book, token),
@@ -118,6 +147,8 @@ export default function useBooks() {
 
   return {
     ...query,
+    data: books,
+    total,
     addNewBook,
     deleteBookFrontEnd,
     buyBookMutation,
@@ -75,7 +75,7 @@ export default function UserLogin() {
 
       if (!res.ok) {
         const errorData = await res.json();
-        setLoginError({ username: errorData.error, password: "" });
+        setLoginError({ username: errorData.detail, password: "" });
         return;
       }
 